@@ -0,0 +1,557 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/admira-project/backend/internal/alerts"
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/backfill"
+	"github.com/admira-project/backend/internal/catalog"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/events"
+	"github.com/admira-project/backend/internal/gql"
+	"github.com/admira-project/backend/internal/health"
+	"github.com/admira-project/backend/internal/lake"
+	"github.com/admira-project/backend/internal/landing"
+	"github.com/admira-project/backend/internal/lock"
+	"github.com/admira-project/backend/internal/messaging"
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/openapi"
+	"github.com/admira-project/backend/internal/queue"
+	"github.com/admira-project/backend/internal/retention"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/admira-project/backend/internal/webhooks"
+	"github.com/sirupsen/logrus"
+)
+
+type app struct {
+	logger      *logrus.Logger
+	httpClient  *utils.RetryableHTTPClient
+	sourceGuard *utils.GuardedHTTPClient
+	storage     storage.Storage
+	campaigns   *catalog.Catalog
+	pipeline    *etl.Pipeline
+	backfill    *backfill.Manager
+	readiness   *health.Readiness
+	limiter     *api.ClientLimiter
+	quota       *api.DailyQuota
+	validator   *api.RequestValidator
+	graphql     *gql.Executor
+	events      *events.Broker
+	webhooks    *webhooks.Dispatcher
+	relay       *messaging.Relay
+	retention   *retention.Manager
+	queue       queue.Queue
+}
+
+// appConfig reúne las opciones que los subcomandos pueden cambiar con sus flags. El resto de
+// la configuración se lee del entorno dentro de newApp.
+type appConfig struct {
+	LandingDir          string
+	BackfillStateDir    string
+	BackfillConcurrency int
+	// IngestMode es inline o queue; replay y backfill usan siempre inline y no tocan la cola.
+	IngestMode string
+	// QueueDir guarda la cola en disco; es el directorio que comparten la API y los workers.
+	QueueDir string
+	// StorageDir comparte las métricas entre procesos; sin él cada proceso tiene las suyas en memoria.
+	StorageDir string
+}
+
+func configFromEnv() appConfig {
+	return appConfig{
+		LandingDir:          os.Getenv("LANDING_DIR"),
+		BackfillStateDir:    os.Getenv("BACKFILL_STATE_DIR"),
+		BackfillConcurrency: getEnvAsInt("BACKFILL_CONCURRENCY", 2),
+		IngestMode:          getEnv("INGEST_MODE", "inline"),
+		QueueDir:            os.Getenv("INGEST_QUEUE_DIR"),
+		StorageDir:          os.Getenv("STORAGE_DIR"),
+	}
+}
+
+func newApp(logger *logrus.Logger, config appConfig) *app {
+	retryPolicy := utils.DefaultRetryPolicy(
+		getEnvAsInt("MAX_RETRIES", 3),
+		getEnvAsInt("RETRY_BACKOFF_MS", 1000),
+	)
+	retryPolicy.MaxBackoff = time.Duration(getEnvAsInt("RETRY_MAX_BACKOFF_MS", 30000)) * time.Millisecond
+	retryPolicy.MaxElapsedTime = time.Duration(getEnvAsInt("RETRY_MAX_ELAPSED_MS", 120000)) * time.Millisecond
+	retryPolicy.FullJitter = getEnvAsBool("RETRY_JITTER", true)
+
+	sourceGuard := utils.NewGuardedHTTPClient(
+		&http.Client{Timeout: 30 * time.Second},
+		utils.BreakerConfig{
+			FailureThreshold:    getEnvAsInt("BREAKER_FAILURE_THRESHOLD", 5),
+			OpenTimeout:         time.Duration(getEnvAsInt("BREAKER_OPEN_TIMEOUT_MS", 30000)) * time.Millisecond,
+			HalfOpenMaxRequests: getEnvAsInt("BREAKER_HALF_OPEN_MAX_REQUESTS", 1),
+		},
+		utils.RateLimit{
+			RequestsPerSecond: getEnvAsFloat("RATE_LIMIT_RPS", 0),
+			Burst:             getEnvAsInt("RATE_LIMIT_BURST", 1),
+		},
+	)
+	for _, override := range getEnvAsList("RATE_LIMIT_HOSTS") {
+		host, limit, err := parseRateLimit(override)
+		if err != nil {
+			logger.Fatalf("Invalid RATE_LIMIT_HOSTS: %v", err)
+		}
+		sourceGuard.SetHostLimit(host, limit)
+	}
+
+	if err := monitoring.RegisterSourceStates(sourceStates(sourceGuard)); err != nil {
+		logger.Warnf("Failed to register source metrics: %v", err)
+	}
+
+	httpClient := utils.NewRetryableHTTPClientWithPolicy(logger, sourceGuard, retryPolicy)
+
+	adsSource, err := loadSourceConfig("ADS", httpClient)
+	if err != nil {
+		logger.Fatalf("Failed to configure ads source: %v", err)
+	}
+
+	crmSource, err := loadSourceConfig("CRM", httpClient)
+	if err != nil {
+		logger.Fatalf("Failed to configure CRM source: %v", err)
+	}
+
+	extractor := etl.NewExtractorWithSources(httpClient, adsSource, crmSource, logger)
+	if err := loadTenants(extractor, httpClient); err != nil {
+		logger.Fatalf("Failed to configure tenants: %v", err)
+	}
+	if cacheDir := os.Getenv("SOURCE_CACHE_DIR"); cacheDir != "" {
+		cache, err := utils.NewResponseCache(cacheDir)
+		if err != nil {
+			logger.Fatalf("Failed to configure source cache: %v", err)
+		}
+		extractor.SetCache(cache)
+	}
+
+	campaigns := catalog.NewCatalog()
+	if catalogFile := os.Getenv("CAMPAIGN_CATALOG_FILE"); catalogFile != "" {
+		count, err := campaigns.LoadCSVFile(tenant.Default, catalogFile)
+		if err != nil {
+			logger.Fatalf("Failed to load campaign catalog: %v", err)
+		}
+		logger.Infof("Loaded %d campaigns from catalog", count)
+	}
+
+	transformer := etl.NewTransformer(logger)
+	transformer.SetCatalog(campaigns)
+	storage, err := newStorage(config.StorageDir)
+	if err != nil {
+		logger.Fatalf("Failed to configure storage: %v", err)
+	}
+
+	pipeline := etl.NewPipeline(extractor, transformer, storage, logger)
+	if config.LandingDir != "" {
+		store, err := landing.NewStore(config.LandingDir)
+		if err != nil {
+			logger.Fatalf("Failed to configure landing zone: %v", err)
+		}
+		pipeline.SetLanding(store)
+	}
+
+	locker, err := newIngestLocker(storage)
+	if err != nil {
+		logger.Fatalf("Failed to configure ingestion lock: %v", err)
+	}
+	if locker != nil {
+		pipeline.SetLocker(locker, time.Duration(getEnvAsInt("INGEST_LOCK_TTL_MS", 600000))*time.Millisecond)
+	}
+
+	if rulesFile := os.Getenv("ALERT_RULES_FILE"); rulesFile != "" {
+		rules, err := alerts.LoadRules(rulesFile)
+		if err != nil {
+			logger.Fatalf("Failed to load alert rules: %v", err)
+		}
+
+		notifier := alerts.NewWebhookNotifier(httpClient, getEnvAsList("ALERT_WEBHOOK_URLS"))
+		engine, err := alerts.NewEngine(rules, storage, notifier, logger)
+		if err != nil {
+			logger.Fatalf("Failed to configure alert engine: %v", err)
+		}
+
+		pipeline.AddHook(engine)
+		logger.Infof("Loaded %d alert rules", len(rules))
+	}
+
+	broker := events.NewBroker(getEnvAsInt("EVENTS_HISTORY", 256))
+	observer := events.NewPipelineEvents(broker, logger)
+	pipeline.AddObserver(observer)
+	pipeline.AddHook(observer)
+
+	// El export se registra antes que los webhooks para que el aviso llegue con los ficheros ya escritos
+	if exportPath := os.Getenv("EXPORT_PATH"); exportPath != "" {
+		store, err := newExportStore(exportPath)
+		if err != nil {
+			logger.Fatalf("Failed to configure data lake export: %v", err)
+		}
+		sink := lake.NewSink(store, logger)
+		if err := sink.Recover(context.Background()); err != nil {
+			logger.Errorf("Failed to recover interrupted data lake exports: %v", err)
+		}
+		pipeline.AddObserver(sink)
+		logger.Infof("Exporting ingestion runs to %s", store.Location())
+	}
+
+	var relay *messaging.Relay
+	if brokers := getEnvAsList("MESSAGING_KAFKA_BROKERS"); len(brokers) > 0 {
+		emitter, outboxRelay, err := newMessaging(brokers, logger)
+		if err != nil {
+			logger.Fatalf("Failed to configure message publishing: %v", err)
+		}
+		pipeline.AddObserver(emitter)
+		relay = outboxRelay
+		relay.Start()
+		logger.Infof("Publishing ingestion events to Kafka at %s", strings.Join(brokers, ","))
+	}
+
+	webhookPolicy := utils.DefaultRetryPolicy(
+		getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		getEnvAsInt("WEBHOOK_RETRY_BACKOFF_MS", 1000),
+	)
+	webhookPolicy.MaxBackoff = time.Duration(getEnvAsInt("WEBHOOK_MAX_BACKOFF_MS", 300000)) * time.Millisecond
+	dispatcher, err := webhooks.NewDispatcher(
+		webhooks.NewHTTPClient(
+			time.Duration(getEnvAsInt("WEBHOOK_TIMEOUT_MS", 10000))*time.Millisecond,
+			getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+		),
+		webhookPolicy,
+		os.Getenv("WEBHOOK_STATE_DIR"),
+		getEnvAsInt("WEBHOOK_DELIVERY_LOG_SIZE", 1000),
+		logger,
+	)
+	if err != nil {
+		logger.Fatalf("Failed to configure webhooks: %v", err)
+	}
+	pipeline.AddObserver(dispatcher)
+
+	backfillManager, err := backfill.NewManager(
+		pipeline,
+		config.BackfillStateDir,
+		config.BackfillConcurrency,
+		logger,
+	)
+	if err != nil {
+		logger.Fatalf("Failed to configure backfill: %v", err)
+	}
+
+	ingestQueue, err := newIngestQueue(config.IngestMode, config.QueueDir)
+	if err != nil {
+		logger.Fatalf("Failed to configure ingestion queue: %v", err)
+	}
+
+	retentionManager, err := newRetention(storage, logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure retention: %v", err)
+	}
+	if retentionManager != nil {
+		retentionManager.Start(time.Duration(getEnvAsInt("RETENTION_INTERVAL_MS", 3600000)) * time.Millisecond)
+	}
+
+	readiness := health.NewReadiness(time.Duration(getEnvAsInt("READY_CHECK_TIMEOUT_MS", 2000)) * time.Millisecond)
+	readiness.Add("storage", health.StorageCheck(storage))
+
+	probeClient := &http.Client{Timeout: 5 * time.Second}
+	probeTTL := time.Duration(getEnvAsInt("READY_PROBE_TTL_MS", 30000)) * time.Millisecond
+	for source, sourceURL := range extractor.SourceURLs() {
+		readiness.Add("source_"+source, health.HTTPProbe(probeClient, sourceURL, probeTTL))
+	}
+
+	if getEnvAsBool("READY_REQUIRE_INGESTION", true) {
+		maxStaleness := time.Duration(getEnvAsInt("READY_MAX_STALENESS_MINUTES", 1440)) * time.Minute
+		readiness.Add("ingestion", health.FreshnessCheck(pipeline.LastSuccess, maxStaleness))
+	}
+
+	trustProxy := getEnvAsBool("API_RATE_LIMIT_TRUST_PROXY", false)
+	limiter := api.NewClientLimiter(utils.RateLimit{
+		RequestsPerSecond: getEnvAsFloat("API_RATE_LIMIT_RPS", 0),
+		Burst:             getEnvAsInt("API_RATE_LIMIT_BURST", 10),
+	}, trustProxy)
+	for _, override := range getEnvAsList("API_RATE_LIMIT_ROUTES") {
+		route, limit, err := parseRateLimit(override)
+		if err != nil {
+			logger.Fatalf("Invalid API_RATE_LIMIT_ROUTES: %v", err)
+		}
+		limiter.SetRouteLimit(route, limit)
+	}
+
+	spec, err := openapi.Load()
+	if err != nil {
+		logger.Fatalf("Failed to load OpenAPI specification: %v", err)
+	}
+	spec.SetMaxBodyBytes(int64(getEnvAsInt("API_MAX_BODY_BYTES", openapi.DefaultMaxBodyBytes)))
+
+	executor, err := gql.NewExecutor(storage, gql.Limits{
+		MaxDepth:      getEnvAsInt("GRAPHQL_MAX_DEPTH", 15),
+		MaxComplexity: getEnvAsInt("GRAPHQL_MAX_COMPLEXITY", 20000),
+	})
+	if err != nil {
+		logger.Fatalf("Failed to configure GraphQL: %v", err)
+	}
+
+	return &app{
+		logger:      logger,
+		httpClient:  httpClient,
+		sourceGuard: sourceGuard,
+		storage:     storage,
+		campaigns:   campaigns,
+		pipeline:    pipeline,
+		backfill:    backfillManager,
+		readiness:   readiness,
+		limiter:     limiter,
+		quota:       api.NewDailyQuota(getEnvAsInt("INGEST_DAILY_QUOTA", 0), trustProxy),
+		validator:   api.NewRequestValidator(spec),
+		graphql:     executor,
+		events:      broker,
+		webhooks:    dispatcher,
+		relay:       relay,
+		retention:   retentionManager,
+		queue:       ingestQueue,
+	}
+}
+
+// newIngestLocker elige con INGEST_LOCK cómo se evita que dos ingestas del mismo tenant se
+// solapen: memory sólo dentro del proceso, file con flock en INGEST_LOCK_DIR para procesos
+// en la misma máquina y storage con un lease en el almacenamiento compartido.
+func newIngestLocker(store storage.Storage) (lock.Locker, error) {
+	switch mode := getEnv("INGEST_LOCK", "memory"); mode {
+	case "none":
+		return nil, nil
+	case "memory":
+		return lock.NewMemoryLocker(), nil
+	case "file":
+		dir := os.Getenv("INGEST_LOCK_DIR")
+		if dir == "" {
+			return nil, fmt.Errorf("INGEST_LOCK=file requires INGEST_LOCK_DIR")
+		}
+		return lock.NewFileLocker(dir)
+	case "storage":
+		leases, ok := store.(storage.LeaseStore)
+		if !ok {
+			return nil, fmt.Errorf("storage does not support leases")
+		}
+		return lock.NewStoreLocker(leases), nil
+	default:
+		return nil, fmt.Errorf("unknown INGEST_LOCK %q (expected memory, file, storage or none)", mode)
+	}
+}
+
+// newRetention devuelve nil con RETENTION_DAYS=0 (por defecto), en el que se conserva todo
+// el detalle diario. RETENTION_ROLLUPS elige en qué agregados se guarda lo que se borra.
+func newRetention(store storage.Storage, logger *logrus.Logger) (*retention.Manager, error) {
+	days := getEnvAsInt("RETENTION_DAYS", 0)
+	if days <= 0 {
+		return nil, nil
+	}
+
+	rollups, ok := store.(storage.RollupStore)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support rollups")
+	}
+
+	granularities := getEnvAsList("RETENTION_ROLLUPS")
+	if len(granularities) == 0 {
+		granularities = []string{storage.GranularityWeek, storage.GranularityMonth}
+	}
+	for _, granularity := range granularities {
+		if granularity != storage.GranularityWeek && granularity != storage.GranularityMonth {
+			return nil, fmt.Errorf("unknown RETENTION_ROLLUPS granularity %q (expected week or month)", granularity)
+		}
+	}
+
+	logger.Infof("Keeping %d days of daily metrics, older ones rolled up by %s", days, strings.Join(granularities, ","))
+	return retention.NewManager(rollups, days, granularities, logger), nil
+}
+
+// newIngestQueue devuelve nil con INGEST_MODE=inline, en el que la API ejecuta la ingesta
+// dentro de la petición. Con INGEST_MODE=queue la API encola; queueDir guarda la cola en
+// disco y permite compartirla con workers en otros procesos.
+func newIngestQueue(mode, queueDir string) (queue.Queue, error) {
+	switch mode {
+	case "inline":
+		return nil, nil
+	case "queue":
+	default:
+		return nil, fmt.Errorf("unknown INGEST_MODE %q (expected inline or queue)", mode)
+	}
+
+	retention := time.Duration(getEnvAsInt("INGEST_QUEUE_RETENTION_MS", 86400000)) * time.Millisecond
+	if queueDir == "" {
+		memoryQueue := queue.NewMemoryQueue()
+		memoryQueue.SetRetention(retention)
+		return memoryQueue, nil
+	}
+
+	fileQueue, err := queue.NewFileQueue(
+		queueDir,
+		time.Duration(getEnvAsInt("INGEST_QUEUE_LEASE_MS", 300000))*time.Millisecond,
+		getEnvAsInt("INGEST_QUEUE_MAX_ATTEMPTS", 3),
+	)
+	if err != nil {
+		return nil, err
+	}
+	fileQueue.SetRetention(retention)
+	return fileQueue, nil
+}
+
+// newStorage guarda en memoria o, con dir, en un snapshot que comparten los procesos que
+// usan el mismo directorio: así la API lee lo que guardan los workers.
+func newStorage(dir string) (storage.Storage, error) {
+	if dir == "" {
+		return storage.NewMemoryStorage(), nil
+	}
+	return storage.NewFileStorage(dir)
+}
+
+// newMessaging prepara el outbox y su relay hacia Kafka. MESSAGING_EVENTS elige qué
+// eventos se publican: "metric" (uno por métrica) y/o "run" (uno por ejecución).
+func newMessaging(brokers []string, logger *logrus.Logger) (*messaging.Emitter, *messaging.Relay, error) {
+	var codec messaging.Codec = messaging.JSONCodec{}
+	switch format := getEnv("MESSAGING_FORMAT", "json"); format {
+	case "json":
+	case "avro":
+		avro, err := messaging.NewAvroCodec()
+		if err != nil {
+			return nil, nil, err
+		}
+		codec = avro
+	default:
+		return nil, nil, fmt.Errorf("unknown MESSAGING_FORMAT %q (expected json or avro)", format)
+	}
+
+	var topics messaging.Topics
+	events := getEnvAsList("MESSAGING_EVENTS")
+	if len(events) == 0 {
+		events = []string{"metric", "run"}
+	}
+	for _, event := range events {
+		switch event {
+		case "metric":
+			topics.Metrics = getEnv("MESSAGING_METRICS_TOPIC", "admira.metrics")
+		case "run":
+			topics.Runs = getEnv("MESSAGING_RUNS_TOPIC", "admira.ingestion-runs")
+		default:
+			return nil, nil, fmt.Errorf("unknown MESSAGING_EVENTS value %q (expected metric or run)", event)
+		}
+	}
+
+	outbox, err := messaging.NewOutbox(os.Getenv("MESSAGING_OUTBOX_DIR"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publisher, err := messaging.NewKafkaPublisher(messaging.KafkaConfig{
+		Brokers:  brokers,
+		ClientID: getEnv("MESSAGING_CLIENT_ID", "admira-backend"),
+		Timeout:  time.Duration(getEnvAsInt("MESSAGING_TIMEOUT_MS", 10000)) * time.Millisecond,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policy := utils.DefaultRetryPolicy(0, getEnvAsInt("MESSAGING_RETRY_BACKOFF_MS", 1000))
+	policy.MaxBackoff = time.Duration(getEnvAsInt("MESSAGING_MAX_BACKOFF_MS", 60000)) * time.Millisecond
+	relay := messaging.NewRelay(
+		outbox,
+		publisher,
+		policy,
+		getEnvAsInt("MESSAGING_BATCH_SIZE", 500),
+		time.Duration(getEnvAsInt("MESSAGING_POLL_INTERVAL_MS", 5000))*time.Millisecond,
+		logger,
+	)
+
+	if pending := outbox.Len(); pending > 0 {
+		logger.Infof("Found %d unpublished messages in the outbox", pending)
+	}
+	return messaging.NewEmitter(outbox, relay, codec, topics, logger), relay, nil
+}
+
+// newExportStore elige el destino del export: s3://bucket/prefijo o un directorio local.
+func newExportStore(exportPath string) (lake.ObjectStore, error) {
+	if !strings.HasPrefix(exportPath, "s3://") {
+		return lake.NewLocalStore(exportPath)
+	}
+
+	bucket, prefix, err := lake.ParseS3URL(exportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return lake.NewS3Store(lake.S3Config{
+		Endpoint:  getEnv("EXPORT_S3_ENDPOINT", "s3.amazonaws.com"),
+		Bucket:    bucket,
+		Prefix:    prefix,
+		Region:    os.Getenv("EXPORT_S3_REGION"),
+		AccessKey: getEnv("EXPORT_S3_ACCESS_KEY", os.Getenv("AWS_ACCESS_KEY_ID")),
+		SecretKey: getEnv("EXPORT_S3_SECRET_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY")),
+		UseSSL:    getEnvAsBool("EXPORT_S3_USE_SSL", true),
+	})
+}
+
+// parseRateLimit interpreta "clave=rps" o "clave=rps:burst", donde la clave es un host o una ruta.
+func parseRateLimit(value string) (string, utils.RateLimit, error) {
+	key, spec, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return "", utils.RateLimit{}, fmt.Errorf("expected key=rps[:burst], got %q", value)
+	}
+
+	rpsStr, burstStr, hasBurst := strings.Cut(spec, ":")
+	rps, err := strconv.ParseFloat(rpsStr, 64)
+	if err != nil {
+		return "", utils.RateLimit{}, fmt.Errorf("invalid rate for %s: %v", key, err)
+	}
+
+	limit := utils.RateLimit{RequestsPerSecond: rps, Burst: 1}
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burstStr); err != nil {
+			return "", utils.RateLimit{}, fmt.Errorf("invalid burst for %s: %v", key, err)
+		}
+	}
+
+	return key, limit, nil
+}
+
+func sourceStates(guard *utils.GuardedHTTPClient) func() []monitoring.SourceState {
+	return func() []monitoring.SourceState {
+		hosts := guard.States()
+		states := make([]monitoring.SourceState, 0, len(hosts))
+		for _, host := range hosts {
+			state := monitoring.SourceState{Host: host.Host, State: host.State}
+			if host.Tokens != nil {
+				state.Tokens = *host.Tokens
+				state.HasLimiter = true
+			}
+			states = append(states, state)
+		}
+		return states
+	}
+}
+
+// startWorkers arranca count workers sobre la cola de la aplicación; terminan al cancelar ctx.
+func startWorkers(ctx context.Context, app *app, count int) *sync.WaitGroup {
+	var wg sync.WaitGroup
+
+	hostname, _ := os.Hostname()
+	lease := time.Duration(getEnvAsInt("INGEST_QUEUE_LEASE_MS", 300000)) * time.Millisecond
+	for i := 1; i <= count; i++ {
+		worker := queue.NewWorker(app.queue, app.pipeline, fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), i), app.logger)
+		worker.SetHeartbeat(lease / 3)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.Run(ctx)
+		}()
+	}
+	return &wg
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+// newAuthenticator configura las API keys (API_KEYS o API_KEYS_FILE) y la validación de JWT
+// (AUTH_JWKS_FILE). Sin ninguna de las dos no arranca, salvo con AUTH_DISABLED=true.
+func newAuthenticator(logger *logrus.Logger) *auth.Authenticator {
+	authenticator := auth.NewAuthenticator()
+
+	if getEnvAsBool("AUTH_DISABLED", false) {
+		if os.Getenv("API_KEYS") != "" || os.Getenv("API_KEYS_FILE") != "" || os.Getenv("AUTH_JWKS_FILE") != "" {
+			logger.Fatal("AUTH_DISABLED=true cannot be combined with API_KEYS or AUTH_JWKS_FILE")
+		}
+		logger.Warn("AUTH_DISABLED=true, API authentication is disabled")
+		authenticator.Disable()
+		return authenticator
+	}
+
+	if os.Getenv("API_KEYS") != "" || os.Getenv("API_KEYS_FILE") != "" {
+		keys, err := readSecret("API_KEYS")
+		if err != nil {
+			logger.Fatalf("Failed to read API keys: %v", err)
+		}
+
+		for _, entry := range strings.FieldsFunc(keys, func(r rune) bool { return r == ',' || r == '\n' }) {
+			key, subject, tenantID, roles, err := parseAPIKey(strings.TrimSpace(entry))
+			if err != nil {
+				logger.Fatalf("Invalid API_KEYS: %v", err)
+			}
+			authenticator.AddTenantAPIKey(key, subject, tenantID, roles)
+		}
+	}
+
+	if jwksFile := os.Getenv("AUTH_JWKS_FILE"); jwksFile != "" {
+		keys, err := auth.LoadJWKS(jwksFile)
+		if err != nil {
+			logger.Fatalf("Failed to load JWKS: %v", err)
+		}
+
+		authenticator.SetJWT(auth.JWTConfig{
+			Keys:        keys,
+			Issuer:      os.Getenv("AUTH_JWT_ISSUER"),
+			Audience:    os.Getenv("AUTH_JWT_AUDIENCE"),
+			RolesClaim:  getEnv("AUTH_JWT_ROLES_CLAIM", "roles"),
+			TenantClaim: getEnv("AUTH_JWT_TENANT_CLAIM", "tenant_id"),
+		})
+	}
+
+	if !authenticator.Enabled() {
+		logger.Fatal("No API keys or JWKS configured; set AUTH_DISABLED=true to run without authentication")
+	}
+	return authenticator
+}
+
+// parseAPIKey interpreta "key:role[:subject[:tenant]]"; varios roles se separan con "|".
+// Sin tenant, la clave se limita a "default"; con "*" tiene acceso a todos.
+func parseAPIKey(entry string) (string, string, string, []string, error) {
+	parts := strings.SplitN(entry, ":", 4)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", nil, fmt.Errorf("expected key:role[:subject[:tenant]]")
+	}
+
+	roles := strings.Split(parts[1], "|")
+	for _, role := range roles {
+		if role != auth.RoleViewer && role != auth.RoleOperator {
+			return "", "", "", nil, fmt.Errorf("unknown role %q", role)
+		}
+	}
+
+	subject := "api-key"
+	if len(parts) >= 3 && parts[2] != "" {
+		subject = parts[2]
+	}
+
+	tenantID := tenant.Default
+	if len(parts) == 4 && parts[3] != "" {
+		tenantID = parts[3]
+		if tenantID != auth.AllTenants && !tenant.Valid(tenantID) {
+			return "", "", "", nil, fmt.Errorf("invalid tenant %q", tenantID)
+		}
+	}
+	return parts[0], subject, tenantID, roles, nil
+}
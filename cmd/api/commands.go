@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/admira-project/backend/internal/backfill"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+func runCommand(logger *logrus.Logger, name string, args []string) error {
+	switch name {
+	case "replay":
+		return runReplay(logger, args)
+	case "backfill":
+		return runBackfill(logger, args)
+	case "worker":
+		return runWorker(logger, args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// runReplay re-transforma una ejecución guardada en LANDING_DIR e imprime las métricas resultantes.
+func runReplay(logger *logrus.Logger, args []string) error {
+	config := configFromEnv()
+	config.IngestMode = "inline"
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.StringVar(&config.LandingDir, "landing-dir", config.LandingDir, "landing zone directory")
+	list := flags.Bool("list", false, "list stored runs instead of replaying one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if config.LandingDir == "" {
+		return fmt.Errorf("landing zone is not configured, set LANDING_DIR or -landing-dir")
+	}
+
+	app := newApp(logger, config)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if *list {
+		manifests, err := app.pipeline.Landing().ListManifests()
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(manifests)
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: admira replay [-landing-dir dir] [-list] <run-id>")
+	}
+
+	result, err := app.pipeline.Replay(context.Background(), flags.Arg(0), false)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Replayed run %s into %d metrics", result.RunID, result.Count)
+	return encoder.Encode(result.Metrics)
+}
+
+// runBackfill ingesta un rango histórico por tramos. Si se interrumpe (Ctrl+C), el estado queda
+// en BACKFILL_STATE_DIR y se reanuda con -resume <job-id>.
+func runBackfill(logger *logrus.Logger, args []string) error {
+	config := configFromEnv()
+	config.IngestMode = "inline"
+	if config.BackfillStateDir == "" {
+		// A diferencia del servidor, el CLI guarda siempre el estado para poder reanudar
+		config.BackfillStateDir = ".backfill"
+	}
+
+	flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	fromStr := flags.String("from", "", "first day to ingest (YYYY-MM-DD)")
+	toStr := flags.String("to", "", "last day to ingest (YYYY-MM-DD)")
+	chunkDays := flags.Int("chunk-days", 7, "days per chunk")
+	flags.IntVar(&config.BackfillConcurrency, "concurrency", config.BackfillConcurrency, "chunks ingested in parallel")
+	flags.StringVar(&config.BackfillStateDir, "state-dir", config.BackfillStateDir, "directory for resumable job state")
+	resume := flags.String("resume", "", "job id to resume")
+	tenantID := flags.String("tenant", tenant.Default, "tenant to ingest")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	app := newApp(logger, config)
+
+	jobID := *resume
+	if jobID == "" {
+		from, err := time.Parse("2006-01-02", *fromStr)
+		if err != nil {
+			return fmt.Errorf("invalid -from: %v", err)
+		}
+		to, err := time.Parse("2006-01-02", *toStr)
+		if err != nil {
+			return fmt.Errorf("invalid -to: %v", err)
+		}
+
+		job, err := app.backfill.CreateForTenant(*tenantID, from, to, *chunkDays)
+		if err != nil {
+			return err
+		}
+		jobID = job.ID
+		logger.Infof("Created backfill job %s with %d chunks", job.ID, len(job.Chunks))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	job, err := app.backfill.Run(ctx, jobID)
+	if job != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(job)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted, resume with: admira backfill -resume %s", jobID)
+	}
+	if err != nil {
+		return err
+	}
+	if job.Status != backfill.StatusCompleted {
+		return fmt.Errorf("backfill %s finished with status %s", job.ID, job.Status)
+	}
+	return nil
+}
+
+// runWorker consume la cola compartida en INGEST_QUEUE_DIR hasta recibir SIGTERM y guarda en
+// el almacenamiento compartido de STORAGE_DIR, donde la API lee lo ingestado. Un trabajo
+// interrumpido vuelve a la cola y lo retoma otro worker.
+func runWorker(logger *logrus.Logger, args []string) error {
+	config := configFromEnv()
+	config.IngestMode = "queue"
+
+	flags := flag.NewFlagSet("worker", flag.ContinueOnError)
+	flags.StringVar(&config.QueueDir, "queue-dir", config.QueueDir, "shared ingestion queue directory")
+	flags.StringVar(&config.StorageDir, "storage-dir", config.StorageDir, "shared metrics storage directory")
+	concurrency := flags.Int("concurrency", getEnvAsInt("INGEST_WORKERS", 1), "jobs processed in parallel")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if config.QueueDir == "" {
+		return fmt.Errorf("ingestion queue is not configured, set INGEST_QUEUE_DIR or -queue-dir")
+	}
+	// Sin almacenamiento compartido la API nunca vería lo que guarda el worker
+	if config.StorageDir == "" {
+		return fmt.Errorf("shared storage is not configured, set STORAGE_DIR or -storage-dir")
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("-concurrency must be at least 1")
+	}
+
+	app := newApp(logger, config)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Infof("Worker consuming %s with %d workers", config.QueueDir, *concurrency)
+	startWorkers(ctx, app, *concurrency).Wait()
+
+	app.webhooks.Close()
+	if app.relay != nil {
+		app.relay.Close()
+	}
+	if app.retention != nil {
+		app.retention.Close()
+	}
+	return nil
+}
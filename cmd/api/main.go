@@ -2,52 +2,117 @@ package main
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/admira-project/backend/internal/api"
-	"github.com/admira-project/backend/internal/etl"
-	"github.com/admira-project/backend/internal/storage"
-	"github.com/admira-project/backend/internal/utils"
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/grpcapi"
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/openapi"
+	"github.com/admira-project/backend/internal/tracing"
 	"github.com/gorilla/mux"
 
 	// "github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 )
 
 func main() {
 	logger := configureLogger()
 
-	httpClient := utils.NewRetryableHTTPClient(
-		logger,
-		getEnvAsInt("MAX_RETRIES", 3),
-		getEnvAsInt("RETRY_BACKOFF_MS", 1000),
-	)
+	if len(os.Args) > 1 {
+		if err := runCommand(logger, os.Args[1], os.Args[2:]); err != nil {
+			logger.Fatalf("%s failed: %v", os.Args[1], err)
+		}
+		return
+	}
 
-	extractor := etl.NewExtractor(
-		httpClient,
-		os.Getenv("ADS_API_URL"),
-		os.Getenv("CRM_API_URL"),
-		logger,
-	)
+	shutdownTracing := setupTracing(logger)
 
-	transformer := etl.NewTransformer(logger)
-	storage := storage.NewMemoryStorage()
+	config := configFromEnv()
+	app := newApp(logger, config)
+	// Sólo el servidor exige autenticación; los subcomandos no la necesitan
+	authn := newAuthenticator(logger)
+	handler := api.NewHandler(app.pipeline, app.storage, logger)
+	campaignHandler := api.NewCampaignHandler(app.campaigns, logger)
+	backfillHandler := api.NewBackfillHandler(app.backfill, logger)
+	graphqlHandler := api.NewGraphQLHandler(app.graphql, logger)
+	eventsHandler := api.NewEventsHandler(app.events, logger)
+	webhookHandler := api.NewWebhookHandler(app.webhooks, logger)
+	handler.SetSourceGuard(app.sourceGuard)
+	handler.SetReadiness(app.readiness)
 
-	handler := api.NewHandler(extractor, transformer, storage, logger)
+	// En modo cola la API encola; con INGEST_WORKERS=0 sólo encola y la ejecución queda para
+	// procesos "admira worker" que comparten la cola y el almacenamiento
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	workers := &sync.WaitGroup{}
+	if app.queue != nil {
+		count := getEnvAsInt("INGEST_WORKERS", 1)
+		if count < 1 && (config.QueueDir == "" || config.StorageDir == "") {
+			logger.Fatal("INGEST_WORKERS=0 requires INGEST_QUEUE_DIR and STORAGE_DIR shared with admira worker processes")
+		}
+		handler.SetQueue(app.queue)
+		workers = startWorkers(workerCtx, app, count)
+	}
 
 	router := mux.NewRouter()
-	router.Use(loggingMiddleware(logger))
+	router.Use(api.RequestIDMiddleware, api.TracingMiddleware, loggingMiddleware(logger))
+
+	viewer := func(h http.HandlerFunc) http.Handler {
+		return authn.Require(auth.RoleViewer, app.limiter.Limit(app.validator.Validate(h)))
+	}
+	operator := func(h http.HandlerFunc) http.Handler {
+		return authn.Require(auth.RoleOperator, app.limiter.Limit(app.validator.Validate(h)))
+	}
 
-	router.HandleFunc("/ingest/run", handler.IngestHandler).Methods("POST")
-	router.HandleFunc("/metrics/channel", handler.MetricsChannelHandler).Methods("GET")
-	router.HandleFunc("/metrics/funnel", handler.MetricsFunnelHandler).Methods("GET")
 	router.HandleFunc("/healthz", handler.HealthHandler).Methods("GET")
-	router.HandleFunc("/readyz", handler.ReadyHandler).Methods("GET")
+	router.Handle("/readyz", authn.Identify(handler.ReadyHandler)).Methods("GET")
+	router.Handle("/internal/metrics", monitoring.Handler()).Methods("GET")
+	router.Handle("/openapi.json", openapi.Handler()).Methods("GET")
+	router.Handle("/graphql", viewer(graphqlHandler.QueryHandler)).Methods("GET", "POST")
+	router.Handle("/events", viewer(eventsHandler.StreamHandler)).Methods("GET")
+
+	// Las rutas sin prefijo se mantienen por compatibilidad, marcadas como obsoletas
+	v1 := router.PathPrefix(api.VersionPrefix).Subrouter()
+	legacy := router.NewRoute().Subrouter()
+	legacy.Use(api.DeprecatedMiddleware)
+
+	for _, routes := range []*mux.Router{v1, legacy} {
+		routes.Handle("/ingest/run", operator(app.quota.Enforce(handler.IngestHandler))).Methods("POST")
+		routes.Handle("/ingest/runs", viewer(handler.ListRunsHandler)).Methods("GET")
+		routes.Handle("/ingest/jobs/{id}", viewer(handler.JobHandler)).Methods("GET")
+		routes.Handle("/ingest/runs/{id}/replay", operator(app.quota.Enforce(handler.ReplayHandler))).Methods("POST")
+		routes.Handle("/backfill", operator(app.quota.Enforce(backfillHandler.CreateHandler))).Methods("POST")
+		routes.Handle("/backfill", viewer(backfillHandler.ListHandler)).Methods("GET")
+		routes.Handle("/backfill/{id}", viewer(backfillHandler.GetHandler)).Methods("GET")
+		routes.Handle("/backfill/{id}/resume", operator(app.quota.Enforce(backfillHandler.ResumeHandler))).Methods("POST")
+		routes.Handle("/metrics/channel", viewer(handler.MetricsChannelHandler)).Methods("GET")
+		routes.Handle("/metrics/funnel", viewer(handler.MetricsFunnelHandler)).Methods("GET")
+		routes.Handle("/metrics/campaigns", viewer(handler.MetricsCampaignsHandler)).Methods("GET")
+		routes.Handle("/campaigns", viewer(campaignHandler.ListHandler)).Methods("GET")
+		routes.Handle("/campaigns/import", operator(campaignHandler.ImportHandler)).Methods("POST")
+		routes.Handle("/campaigns/{id}", viewer(campaignHandler.GetHandler)).Methods("GET")
+		routes.Handle("/campaigns/{id}", operator(campaignHandler.UpsertHandler)).Methods("PUT")
+		routes.Handle("/campaigns/{id}", operator(campaignHandler.DeleteHandler)).Methods("DELETE")
+	}
+
+	// Los webhooks sólo existen bajo /v1
+	v1.Handle("/webhooks", operator(webhookHandler.CreateHandler)).Methods("POST")
+	v1.Handle("/webhooks", operator(webhookHandler.ListHandler)).Methods("GET")
+	v1.Handle("/webhooks/{id}", operator(webhookHandler.GetHandler)).Methods("GET")
+	v1.Handle("/webhooks/{id}", operator(webhookHandler.UpdateHandler)).Methods("PATCH")
+	v1.Handle("/webhooks/{id}", operator(webhookHandler.DeleteHandler)).Methods("DELETE")
+	v1.Handle("/webhooks/{id}/deliveries", operator(webhookHandler.DeliveriesHandler)).Methods("GET")
+	v1.Handle("/webhooks/{id}/deliveries/replay", operator(webhookHandler.ReplayFailedHandler)).Methods("POST")
+	v1.Handle("/webhooks/{id}/deliveries/{delivery}/replay", operator(webhookHandler.ReplayHandler)).Methods("POST")
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -61,6 +126,7 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	srv.RegisterOnShutdown(app.events.Close)
 
 	go func() {
 		logger.Infof("Server starting on port %s", port)
@@ -69,6 +135,27 @@ func main() {
 		}
 	}()
 
+	// gRPC solo se sirve si se pide con GRPC_PORT
+	var grpcServer *grpc.Server
+	if grpcPort := os.Getenv("GRPC_PORT"); grpcPort != "" {
+		grpcServer = grpcapi.NewServer(app.storage, app.pipeline, app.backfill, app.queue, authn, logger,
+			grpcapi.RateLimitInterceptor(app.limiter),
+			grpcapi.QuotaInterceptor(app.quota),
+		)
+
+		go func() {
+			listener, err := net.Listen("tcp", ":"+grpcPort)
+			if err != nil {
+				logger.Fatalf("gRPC server failed to listen: %v", err)
+			}
+
+			logger.Infof("gRPC server starting on port %s", grpcPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
@@ -81,9 +168,56 @@ func main() {
 		logger.Errorf("Error during server shutdown: %v", err)
 	}
 
+	// GracefulStop espera a las llamadas en curso; si no acaban a tiempo, se cortan
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+	}
+
+	// Los trabajos en curso se interrumpen y vuelven a la cola
+	stopWorkers()
+	workers.Wait()
+
+	// Corta los reintentos pendientes; las entregas interrumpidas quedan como fallidas
+	app.webhooks.Close()
+	// Lo que no haya confirmado el broker sigue en el outbox para el siguiente arranque
+	if app.relay != nil {
+		app.relay.Close()
+	}
+	if app.retention != nil {
+		app.retention.Close()
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Errorf("Error flushing traces: %v", err)
+	}
+
 	logger.Info("Server stopped")
 }
 
+// setupTracing activa la exportación OTLP sólo si hay un collector configurado.
+func setupTracing(logger *logrus.Logger) func(context.Context) error {
+	if getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "") == "" && getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "") == "" {
+		return func(context.Context) error { return nil }
+	}
+
+	shutdown, err := tracing.Setup(context.Background(), getEnv("OTEL_SERVICE_NAME", "admira-backend"))
+	if err != nil {
+		logger.Fatalf("Failed to configure tracing: %v", err)
+	}
+
+	logger.Info("OpenTelemetry tracing enabled")
+	return shutdown
+}
+
 func configureLogger() *logrus.Logger {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
@@ -113,6 +247,39 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func loggingMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -124,7 +291,15 @@ func loggingMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
 
 			duration := time.Since(start)
 
-			logger.WithFields(logrus.Fields{
+			route := "unmatched"
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+			monitoring.HTTPRequestDuration.WithLabelValues(r.Method, route, strconv.Itoa(wrapped.status)).Observe(duration.Seconds())
+
+			tracing.Logger(r.Context(), logger).WithFields(logrus.Fields{
 				"method":   r.Method,
 				"path":     r.URL.Path,
 				"status":   wrapped.status,
@@ -144,3 +319,8 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.status = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap permite a http.ResponseController llegar al writer original (Flush, deadlines).
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/utils"
+)
+
+// loadSourceConfig lee la configuración de una fuente a partir de variables con el prefijo dado
+// (ADS o CRM). Los secretos pueden venir de <VAR> o de un fichero indicado en <VAR>_FILE.
+func loadSourceConfig(prefix string, client utils.HTTPClient) (etl.SourceConfig, error) {
+	source := etl.SourceConfig{
+		URL:       os.Getenv(prefix + "_API_URL"),
+		AccountID: os.Getenv(prefix + "_ACCOUNT_ID"),
+	}
+
+	if rawQuery := os.Getenv(prefix + "_QUERY_PARAMS"); rawQuery != "" {
+		// Se parsea a mano para no escapar las llaves de las plantillas
+		source.Query = make(map[string]string)
+		for _, pair := range strings.Split(rawQuery, "&") {
+			key, value, _ := strings.Cut(pair, "=")
+			if key == "" {
+				return source, fmt.Errorf("invalid %s_QUERY_PARAMS: %q", prefix, rawQuery)
+			}
+			source.Query[key] = value
+		}
+	}
+
+	if rawHeaders := os.Getenv(prefix + "_HEADERS"); rawHeaders != "" {
+		source.Headers = make(http.Header)
+		for _, pair := range strings.Split(rawHeaders, ",") {
+			key, value, ok := strings.Cut(pair, ":")
+			if !ok {
+				return source, fmt.Errorf("invalid %s_HEADERS: %q", prefix, rawHeaders)
+			}
+			source.Headers.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+		}
+	}
+
+	authType := os.Getenv(prefix + "_AUTH_TYPE")
+	switch authType {
+	case "", "none":
+	case "bearer":
+		token, err := readSecret(prefix + "_AUTH_TOKEN")
+		if err != nil {
+			return source, err
+		}
+		source.Auth = utils.BearerAuth{Token: token}
+	case "api_key":
+		key, err := readSecret(prefix + "_API_KEY")
+		if err != nil {
+			return source, err
+		}
+		source.Auth = utils.APIKeyAuth{Header: os.Getenv(prefix + "_API_KEY_HEADER"), Key: key}
+	case "basic":
+		password, err := readSecret(prefix + "_BASIC_PASSWORD")
+		if err != nil {
+			return source, err
+		}
+		source.Auth = utils.BasicAuth{Username: os.Getenv(prefix + "_BASIC_USER"), Password: password}
+	case "oauth2":
+		secret, err := readSecret(prefix + "_OAUTH_CLIENT_SECRET")
+		if err != nil {
+			return source, err
+		}
+		tokenURL := os.Getenv(prefix + "_OAUTH_TOKEN_URL")
+		if _, err := url.ParseRequestURI(tokenURL); err != nil {
+			return source, fmt.Errorf("invalid %s_OAUTH_TOKEN_URL: %q", prefix, tokenURL)
+		}
+		source.Auth = utils.NewOAuth2ClientCredentials(
+			client,
+			tokenURL,
+			os.Getenv(prefix+"_OAUTH_CLIENT_ID"),
+			secret,
+			strings.Fields(os.Getenv(prefix+"_OAUTH_SCOPES")),
+		)
+	default:
+		return source, fmt.Errorf("unsupported %s_AUTH_TYPE: %q", prefix, authType)
+	}
+
+	return source, nil
+}
+
+func readSecret(key string) (string, error) {
+	if value := os.Getenv(key); value != "" {
+		return value, nil
+	}
+
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return "", fmt.Errorf("%s or %s_FILE is required", key, key)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %v", key, err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// loadTenants registra las fuentes de cada tenant de TENANTS con las variables
+// TENANT_<ID>_ADS_* y TENANT_<ID>_CRM_* (ID en mayúsculas y con "-" como "_").
+func loadTenants(extractor *etl.Extractor, client utils.HTTPClient) error {
+	for _, id := range getEnvAsList("TENANTS") {
+		if !tenant.Valid(id) {
+			return fmt.Errorf("invalid tenant id %q", id)
+		}
+
+		prefix := "TENANT_" + strings.ToUpper(strings.ReplaceAll(id, "-", "_"))
+		ads, err := loadSourceConfig(prefix+"_ADS", client)
+		if err != nil {
+			return fmt.Errorf("tenant %s: %v", id, err)
+		}
+		crm, err := loadSourceConfig(prefix+"_CRM", client)
+		if err != nil {
+			return fmt.Errorf("tenant %s: %v", id, err)
+		}
+
+		extractor.AddTenant(id, ads, crm)
+	}
+	return nil
+}
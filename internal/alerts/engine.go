@@ -0,0 +1,242 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+type Alert struct {
+	Rule        string        `json:"rule"`
+	Kind        string        `json:"kind"`
+	Field       string        `json:"field"`
+	Value       float64       `json:"value"`
+	Threshold   float64       `json:"threshold"`
+	Expected    float64       `json:"expected,omitempty"`
+	Score       float64       `json:"score,omitempty"`
+	Message     string        `json:"message"`
+	Metric      models.Metric `json:"metric"`
+	TriggeredAt time.Time     `json:"triggered_at"`
+}
+
+type Engine struct {
+	rules    []Rule
+	storage  storage.Storage
+	notifier Notifier
+	logger   *logrus.Logger
+}
+
+func NewEngine(rules []Rule, storage storage.Storage, notifier Notifier, logger *logrus.Logger) (*Engine, error) {
+	for i := range rules {
+		if err := rules[i].normalize(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Engine{
+		rules:    rules,
+		storage:  storage,
+		notifier: notifier,
+		logger:   logger,
+	}, nil
+}
+
+// AfterIngest evalúa las reglas sobre el lote recién guardado y notifica las alertas disparadas.
+func (e *Engine) AfterIngest(ctx context.Context, metrics []models.Metric) {
+	alerts, err := e.Evaluate(metrics)
+	if err != nil {
+		e.logger.Errorf("Failed to evaluate alert rules: %v", err)
+		return
+	}
+
+	if len(alerts) == 0 {
+		return
+	}
+
+	e.logger.Warnf("%d alerts triggered", len(alerts))
+
+	if e.notifier == nil {
+		return
+	}
+
+	for _, rule := range e.rules {
+		var triggered []Alert
+		for _, alert := range alerts {
+			if alert.Rule == rule.Name {
+				triggered = append(triggered, alert)
+			}
+		}
+
+		if len(triggered) == 0 {
+			continue
+		}
+
+		if err := e.notifier.Notify(ctx, rule, triggered); err != nil {
+			e.logger.Errorf("Failed to notify alerts for rule %s: %v", rule.Name, err)
+		}
+	}
+}
+
+func (e *Engine) Evaluate(metrics []models.Metric) ([]Alert, error) {
+	var alerts []Alert
+
+	for _, rule := range e.rules {
+		for _, metric := range metrics {
+			if !rule.matches(metric) {
+				continue
+			}
+
+			var (
+				alert *Alert
+				err   error
+			)
+			if rule.Anomaly != nil {
+				alert, err = e.evaluateAnomaly(rule, metric)
+			} else {
+				alert = evaluateThreshold(rule, metric)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+			if alert != nil {
+				alerts = append(alerts, *alert)
+			}
+		}
+	}
+
+	return alerts, nil
+}
+
+func evaluateThreshold(rule Rule, metric models.Metric) *Alert {
+	value, _ := numericField(metric, rule.Field)
+	if !comparators[rule.Operator](value, rule.Value) {
+		return nil
+	}
+
+	return &Alert{
+		Rule:        rule.Name,
+		Kind:        "threshold",
+		Field:       rule.Field,
+		Value:       value,
+		Threshold:   rule.Value,
+		Message:     fmt.Sprintf("%s %s %g (value %g)", rule.Field, rule.Operator, rule.Value, value),
+		Metric:      metric,
+		TriggeredAt: time.Now().UTC(),
+	}
+}
+
+func (e *Engine) evaluateAnomaly(rule Rule, metric models.Metric) (*Alert, error) {
+	date, err := time.Parse("2006-01-02", metric.Date)
+	if err != nil {
+		return nil, nil
+	}
+
+	from := date.AddDate(0, 0, -rule.Anomaly.TrailingDays).Format("2006-01-02")
+	to := date.AddDate(0, 0, -1).Format("2006-01-02")
+
+	history, err := e.storage.GetMetricsInRange(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alert history: %v", err)
+	}
+
+	series := trailingSeries(history, metric, rule.Field)
+	if len(series) < rule.Anomaly.MinPoints {
+		return nil, nil
+	}
+
+	value, _ := numericField(metric, rule.Field)
+
+	var expected, deviation float64
+	switch rule.Anomaly.Method {
+	case MethodEWMA:
+		expected, deviation = ewma(series, rule.Anomaly.Alpha)
+	default:
+		expected, deviation = meanStdDev(series)
+	}
+
+	if deviation == 0 {
+		return nil, nil
+	}
+
+	score := (value - expected) / deviation
+	if math.Abs(score) < rule.Anomaly.Threshold {
+		return nil, nil
+	}
+
+	return &Alert{
+		Rule:        rule.Name,
+		Kind:        rule.Anomaly.Method,
+		Field:       rule.Field,
+		Value:       value,
+		Threshold:   rule.Anomaly.Threshold,
+		Expected:    expected,
+		Score:       score,
+		Message:     fmt.Sprintf("%s deviates %.2f sigma from expected %g (value %g)", rule.Field, score, expected, value),
+		Metric:      metric,
+		TriggeredAt: time.Now().UTC(),
+	}, nil
+}
+
+// trailingSeries devuelve un valor por día para la misma serie (canal, campaña y UTMs) ordenado por fecha.
+func trailingSeries(history []models.Metric, metric models.Metric, field string) []float64 {
+	byDate := make(map[string]float64)
+
+	for _, past := range history {
+		if past.TenantID != metric.TenantID ||
+			past.Channel != metric.Channel || past.CampaignID != metric.CampaignID ||
+			past.UtmCampaign != metric.UtmCampaign || past.UtmSource != metric.UtmSource ||
+			past.UtmMedium != metric.UtmMedium {
+			continue
+		}
+		value, _ := numericField(past, field)
+		byDate[past.Date] = value
+	}
+
+	dates := make([]string, 0, len(byDate))
+	for date := range byDate {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	series := make([]float64, len(dates))
+	for i, date := range dates {
+		series[i] = byDate[date]
+	}
+	return series
+}
+
+func meanStdDev(series []float64) (float64, float64) {
+	var sum float64
+	for _, v := range series {
+		sum += v
+	}
+	mean := sum / float64(len(series))
+
+	var variance float64
+	for _, v := range series {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(series))
+
+	return mean, math.Sqrt(variance)
+}
+
+func ewma(series []float64, alpha float64) (float64, float64) {
+	average := series[0]
+	var variance float64
+
+	for _, v := range series[1:] {
+		diff := v - average
+		average += alpha * diff
+		variance = (1 - alpha) * (variance + alpha*diff*diff)
+	}
+
+	return average, math.Sqrt(variance)
+}
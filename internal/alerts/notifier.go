@@ -0,0 +1,74 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/admira-project/backend/internal/utils"
+)
+
+type Notifier interface {
+	Notify(ctx context.Context, rule Rule, alerts []Alert) error
+}
+
+type WebhookNotifier struct {
+	client      utils.HTTPClient
+	defaultURLs []string
+}
+
+func NewWebhookNotifier(client utils.HTTPClient, defaultURLs []string) *WebhookNotifier {
+	return &WebhookNotifier{
+		client:      client,
+		defaultURLs: defaultURLs,
+	}
+}
+
+type webhookPayload struct {
+	Rule   string  `json:"rule"`
+	Expr   string  `json:"expr,omitempty"`
+	Alerts []Alert `json:"alerts"`
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, rule Rule, alerts []Alert) error {
+	urls := rule.Webhooks
+	if len(urls) == 0 {
+		urls = n.defaultURLs
+	}
+
+	body, err := json.Marshal(webhookPayload{Rule: rule.Name, Expr: rule.Expr, Alerts: alerts})
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, url := range urls {
+		if err := n.post(ctx, url, body); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver alert to %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook %s returned status code: %d", url, resp.StatusCode)
+	}
+
+	return nil
+}
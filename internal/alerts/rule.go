@@ -0,0 +1,210 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/admira-project/backend/internal/models"
+)
+
+const (
+	MethodZScore = "zscore"
+	MethodEWMA   = "ewma"
+)
+
+type Rule struct {
+	Name     string            `json:"name"`
+	Expr     string            `json:"expr,omitempty"`
+	Field    string            `json:"field"`
+	Operator string            `json:"operator,omitempty"`
+	Value    float64           `json:"value,omitempty"`
+	Filters  map[string]string `json:"filters,omitempty"`
+	Anomaly  *AnomalyConfig    `json:"anomaly,omitempty"`
+	Webhooks []string          `json:"webhooks,omitempty"`
+}
+
+type AnomalyConfig struct {
+	Method       string  `json:"method"`
+	TrailingDays int     `json:"trailing_days"`
+	Threshold    float64 `json:"threshold"`
+	Alpha        float64 `json:"alpha"`
+	MinPoints    int     `json:"min_points"`
+}
+
+type rulesFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules: %v", err)
+	}
+
+	var file rulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal alert rules: %v", err)
+	}
+
+	for i := range file.Rules {
+		if err := file.Rules[i].normalize(); err != nil {
+			return nil, err
+		}
+	}
+
+	return file.Rules, nil
+}
+
+// ParseExpression interpreta reglas del tipo "roas < 1.5 for channel=google_ads".
+func ParseExpression(expr string) (Rule, error) {
+	rule := Rule{Expr: expr}
+
+	condition, scope, _ := strings.Cut(expr, " for ")
+	parts := strings.Fields(condition)
+	if len(parts) != 3 {
+		return rule, fmt.Errorf("invalid alert expression: %q", expr)
+	}
+
+	value, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return rule, fmt.Errorf("invalid threshold in alert expression %q: %v", expr, err)
+	}
+
+	rule.Field = parts[0]
+	rule.Operator = parts[1]
+	rule.Value = value
+
+	for _, pair := range strings.FieldsFunc(scope, func(r rune) bool { return r == ',' || r == ' ' }) {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return rule, fmt.Errorf("invalid filter %q in alert expression %q", pair, expr)
+		}
+		if rule.Filters == nil {
+			rule.Filters = make(map[string]string)
+		}
+		rule.Filters[key] = val
+	}
+
+	return rule, nil
+}
+
+func (r *Rule) normalize() error {
+	if r.Expr != "" {
+		parsed, err := ParseExpression(r.Expr)
+		if err != nil {
+			return err
+		}
+		r.Field = parsed.Field
+		r.Operator = parsed.Operator
+		r.Value = parsed.Value
+		for key, val := range parsed.Filters {
+			if r.Filters == nil {
+				r.Filters = make(map[string]string)
+			}
+			r.Filters[key] = val
+		}
+	}
+
+	if r.Name == "" {
+		r.Name = r.Field
+		if r.Expr != "" {
+			r.Name = r.Expr
+		}
+	}
+
+	if _, ok := numericField(models.Metric{}, r.Field); !ok {
+		return fmt.Errorf("alert rule %q: unknown numeric field %q", r.Name, r.Field)
+	}
+
+	for key := range r.Filters {
+		if _, ok := stringField(models.Metric{}, key); !ok {
+			return fmt.Errorf("alert rule %q: unknown filter field %q", r.Name, key)
+		}
+	}
+
+	if r.Anomaly == nil {
+		if _, ok := comparators[r.Operator]; !ok {
+			return fmt.Errorf("alert rule %q: unsupported operator %q", r.Name, r.Operator)
+		}
+		return nil
+	}
+
+	if r.Anomaly.Method == "" {
+		r.Anomaly.Method = MethodZScore
+	}
+	if r.Anomaly.Method != MethodZScore && r.Anomaly.Method != MethodEWMA {
+		return fmt.Errorf("alert rule %q: unsupported anomaly method %q", r.Name, r.Anomaly.Method)
+	}
+	if r.Anomaly.TrailingDays <= 0 {
+		r.Anomaly.TrailingDays = 14
+	}
+	if r.Anomaly.Threshold <= 0 {
+		r.Anomaly.Threshold = 3
+	}
+	if r.Anomaly.Alpha <= 0 || r.Anomaly.Alpha > 1 {
+		r.Anomaly.Alpha = 0.3
+	}
+	if r.Anomaly.MinPoints <= 0 {
+		r.Anomaly.MinPoints = 3
+	}
+
+	return nil
+}
+
+func (r Rule) matches(metric models.Metric) bool {
+	for key, want := range r.Filters {
+		if got, _ := stringField(metric, key); got != want {
+			return false
+		}
+	}
+	return true
+}
+
+var comparators = map[string]func(a, b float64) bool{
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+func numericField(metric models.Metric, name string) (float64, bool) {
+	field, ok := fieldByTag(metric, name)
+	if !ok {
+		return 0, false
+	}
+
+	switch field.Kind() {
+	case reflect.Int:
+		return float64(field.Int()), true
+	case reflect.Float64:
+		return field.Float(), true
+	}
+	return 0, false
+}
+
+func stringField(metric models.Metric, name string) (string, bool) {
+	field, ok := fieldByTag(metric, name)
+	if !ok || field.Kind() != reflect.String {
+		return "", false
+	}
+	return field.String(), true
+}
+
+func fieldByTag(metric models.Metric, name string) (reflect.Value, bool) {
+	value := reflect.ValueOf(metric)
+	typ := value.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		tag, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return value.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/admira-project/backend/internal/backfill"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type BackfillHandler struct {
+	manager *backfill.Manager
+	logger  *logrus.Logger
+}
+
+func NewBackfillHandler(manager *backfill.Manager, logger *logrus.Logger) *BackfillHandler {
+	return &BackfillHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+func (h *BackfillHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	params := r.URL.Query()
+
+	from, err := time.Parse("2006-01-02", params.Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from parameter format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	to, err := time.Parse("2006-01-02", params.Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to parameter format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	var chunkDays int
+	if chunkStr := params.Get("chunk_days"); chunkStr != "" {
+		if chunkDays, err = strconv.Atoi(chunkStr); err != nil {
+			http.Error(w, "Invalid chunk_days parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	job, err := h.manager.CreateForTenant(tenant.FromContext(r.Context()), from, to, chunkDays)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// El job sobrevive a la petición, por eso no se usa r.Context()
+	if err := h.manager.Start(context.Background(), job.ID); err != nil {
+		tracing.Logger(r.Context(), h.logger).Errorf("Failed to start backfill %s: %v", job.ID, err)
+		http.Error(w, "Failed to start backfill", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJob(w, r, job.ID, http.StatusAccepted)
+}
+
+func (h *BackfillHandler) ResumeHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	jobID := mux.Vars(r)["id"]
+
+	job, err := h.manager.Get(jobID)
+	if err == nil && !ownsJob(r, job) {
+		err = backfill.ErrJobNotFound
+	}
+	if err == nil {
+		err = h.manager.Start(context.Background(), jobID)
+	}
+
+	switch {
+	case errors.Is(err, backfill.ErrJobNotFound):
+		http.Error(w, "Backfill job not found", http.StatusNotFound)
+		return
+	case errors.Is(err, backfill.ErrJobAlreadyActive):
+		http.Error(w, "Backfill job is already running", http.StatusConflict)
+		return
+	case err != nil:
+		tracing.Logger(r.Context(), h.logger).Errorf("Failed to resume backfill %s: %v", jobID, err)
+		http.Error(w, "Failed to resume backfill", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJob(w, r, jobID, http.StatusAccepted)
+}
+
+func (h *BackfillHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeJob(w, r, mux.Vars(r)["id"], http.StatusOK)
+}
+
+func (h *BackfillHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	jobs, err := h.manager.List()
+	if err != nil {
+		tracing.Logger(r.Context(), h.logger).Errorf("Failed to list backfill jobs: %v", err)
+		http.Error(w, "Failed to list backfill jobs", http.StatusInternalServerError)
+		return
+	}
+
+	scoped := make([]backfill.Job, 0, len(jobs))
+	for i := range jobs {
+		if ownsJob(r, &jobs[i]) {
+			scoped = append(scoped, jobs[i])
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scoped)
+}
+
+func (h *BackfillHandler) writeJob(w http.ResponseWriter, r *http.Request, jobID string, status int) {
+	job, err := h.manager.Get(jobID)
+	if errors.Is(err, backfill.ErrJobNotFound) || (err == nil && !ownsJob(r, job)) {
+		http.Error(w, "Backfill job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		tracing.Logger(r.Context(), h.logger).Errorf("Failed to load backfill %s: %v", jobID, err)
+		http.Error(w, "Failed to load backfill job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(job)
+}
+
+// ownsJob oculta los jobs de otros tenants como si no existieran.
+func ownsJob(r *http.Request, job *backfill.Job) bool {
+	jobTenant := job.Tenant
+	if jobTenant == "" {
+		jobTenant = tenant.Default
+	}
+	return jobTenant == tenant.FromContext(r.Context())
+}
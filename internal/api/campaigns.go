@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/admira-project/backend/internal/catalog"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type CampaignHandler struct {
+	catalog *catalog.Catalog
+	logger  *logrus.Logger
+}
+
+func NewCampaignHandler(catalog *catalog.Catalog, logger *logrus.Logger) *CampaignHandler {
+	return &CampaignHandler{
+		catalog: catalog,
+		logger:  logger,
+	}
+}
+
+// Todas las rutas trabajan sobre el catálogo del tenant de la petición.
+func (h *CampaignHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.catalog.List(tenant.FromContext(r.Context())))
+}
+
+func (h *CampaignHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	campaign, ok := h.catalog.Lookup(tenant.FromContext(r.Context()), mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(campaign)
+}
+
+func (h *CampaignHandler) UpsertHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	var campaign models.Campaign
+	if err := json.NewDecoder(r.Body).Decode(&campaign); err != nil {
+		http.Error(w, "Invalid campaign payload", http.StatusBadRequest)
+		return
+	}
+	campaign.CampaignID = mux.Vars(r)["id"]
+
+	if err := h.catalog.Upsert(tenant.FromContext(r.Context()), campaign); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(campaign)
+}
+
+func (h *CampaignHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.catalog.Delete(tenant.FromContext(r.Context()), mux.Vars(r)["id"]) {
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CampaignHandler) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := h.catalog.LoadCSV(tenant.FromContext(r.Context()), r.Body)
+	if err != nil {
+		tracing.Logger(r.Context(), h.logger).Warnf("Failed to import campaign catalog: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Campaign catalog imported successfully",
+		"count":   count,
+	})
+}
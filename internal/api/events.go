@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/admira-project/backend/internal/events"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	eventsHeartbeat = 15 * time.Second
+	eventsRetryMs   = 3000
+)
+
+type EventsHandler struct {
+	broker    *events.Broker
+	logger    *logrus.Logger
+	heartbeat time.Duration
+}
+
+func NewEventsHandler(broker *events.Broker, logger *logrus.Logger) *EventsHandler {
+	return &EventsHandler{
+		broker:    broker,
+		logger:    logger,
+		heartbeat: eventsHeartbeat,
+	}
+}
+
+// SetHeartbeat cambia cada cuánto se envía un comentario para mantener viva la conexión.
+func (h *EventsHandler) SetHeartbeat(interval time.Duration) {
+	h.heartbeat = interval
+}
+
+// StreamHandler abre un stream SSE con los eventos del tenant. Acepta ?types= (tipos exactos
+// o prefijos como "ingestion.") y reenvía lo perdido a partir de la cabecera Last-Event-ID.
+func (h *EventsHandler) StreamHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	lastEventID, err := parseLastEventID(r)
+	if err != nil {
+		http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+		return
+	}
+	filter := parseEventTypes(r.URL.Query().Get("types"))
+
+	controller := http.NewResponseController(w)
+	// El WriteTimeout del servidor cortaría el stream; aquí no aplica
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		tracing.Logger(r.Context(), h.logger).Warnf("Failed to clear write deadline: %v", err)
+	}
+
+	stream, backlog, cancel := h.broker.Subscribe(tenant.FromContext(r.Context()), lastEventID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventsRetryMs)
+	for _, event := range backlog {
+		if filter.match(event.Type) {
+			writeEvent(w, event)
+		}
+	}
+	if err := controller.Flush(); err != nil {
+		tracing.Logger(r.Context(), h.logger).Errorf("Streaming is not supported: %v", err)
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-stream:
+			if !open {
+				// El broker cierra el canal al apagarse o si el cliente no consume a tiempo
+				tracing.Logger(r.Context(), h.logger).Info("Event stream closed by broker")
+				return
+			}
+			if !filter.match(event.Type) {
+				continue
+			}
+			writeEvent(w, event)
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event events.Event) {
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.IDString(), event.Type, event.Data)
+}
+
+func parseLastEventID(r *http.Request) (uint64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+type eventFilter []string
+
+func parseEventTypes(value string) eventFilter {
+	var filter eventFilter
+	for _, eventType := range strings.Split(value, ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			filter = append(filter, eventType)
+		}
+	}
+	return filter
+}
+
+func (f eventFilter) match(eventType string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, allowed := range f {
+		if eventType == allowed || (strings.HasSuffix(allowed, ".") && strings.HasPrefix(eventType, allowed)) {
+			return true
+		}
+	}
+	return false
+}
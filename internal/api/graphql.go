@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/admira-project/backend/internal/gql"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+type GraphQLHandler struct {
+	executor *gql.Executor
+	logger   *logrus.Logger
+}
+
+func NewGraphQLHandler(executor *gql.Executor, logger *logrus.Logger) *GraphQLHandler {
+	return &GraphQLHandler{
+		executor: executor,
+		logger:   logger,
+	}
+}
+
+// QueryHandler acepta la consulta por POST con cuerpo JSON o por GET con query, operationName y variables.
+// El tenant se elige igual que en el resto de la API, con ?tenant= o el de la credencial.
+func (h *GraphQLHandler) QueryHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	var request gql.Request
+	if r.Method == http.MethodGet {
+		params := r.URL.Query()
+		request.Query = params.Get("query")
+		request.OperationName = params.Get("operationName")
+		if variables := params.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeGraphQLError(w, http.StatusBadRequest, "Invalid GraphQL request payload")
+		return
+	}
+
+	if request.Query == "" {
+		writeGraphQLError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	result, err := h.executor.Execute(r.Context(), request)
+	if err != nil {
+		tracing.Logger(r.Context(), h.logger).Warnf("Rejected GraphQL query: %v", err)
+		writeGraphQLError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeGraphQLError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{"message": message}},
+	})
+}
@@ -2,33 +2,65 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/admira-project/backend/internal/auth"
 	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/health"
+	"github.com/admira-project/backend/internal/landing"
+	"github.com/admira-project/backend/internal/lock"
 	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/queue"
 	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
 type Handler struct {
-	extractor   *etl.Extractor
-	transformer *etl.Transformer
+	pipeline    *etl.Pipeline
 	storage     storage.Storage
 	logger      *logrus.Logger
+	sourceGuard *utils.GuardedHTTPClient
+	readiness   *health.Readiness
+	queue       queue.Queue
 }
 
-func NewHandler(extractor *etl.Extractor, transformer *etl.Transformer, storage storage.Storage, logger *logrus.Logger) *Handler {
+func NewHandler(pipeline *etl.Pipeline, storage storage.Storage, logger *logrus.Logger) *Handler {
 	return &Handler{
-		extractor:   extractor,
-		transformer: transformer,
-		storage:     storage,
-		logger:      logger,
+		pipeline: pipeline,
+		storage:  storage,
+		logger:   logger,
 	}
 }
 
+func (h *Handler) SetSourceGuard(guard *utils.GuardedHTTPClient) {
+	h.sourceGuard = guard
+}
+
+func (h *Handler) SetReadiness(readiness *health.Readiness) {
+	h.readiness = readiness
+}
+
+// SetQueue hace que IngestHandler encole la ingesta en lugar de ejecutarla.
+func (h *Handler) SetQueue(q queue.Queue) {
+	h.queue = q
+}
+
 func (h *Handler) IngestHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
 	ctx := r.Context()
 
 	sinceParam := r.URL.Query().Get("since")
@@ -38,57 +70,202 @@ func (h *Handler) IngestHandler(w http.ResponseWriter, r *http.Request) {
 	if sinceParam != "" {
 		since, err = time.Parse("2006-01-02", sinceParam)
 		if err != nil {
-			h.logger.Warnf("Invalid since parameter: %s", sinceParam)
+			tracing.Logger(r.Context(), h.logger).Warnf("Invalid since parameter: %s", sinceParam)
 			http.Error(w, "Invalid since parameter format. Use YYYY-MM-DD", http.StatusBadRequest)
 			return
 		}
 	}
 
-	adsData, err := h.extractor.ExtractAdsData(ctx)
-	if err != nil {
-		h.logger.Errorf("Failed to extract ads data: %v", err)
-		http.Error(w, "Failed to extract ads data", http.StatusInternalServerError)
+	if h.queue != nil {
+		h.enqueueIngestion(w, r, sinceParam)
 		return
 	}
 
-	crmData, err := h.extractor.ExtractCrmData(ctx)
+	var result *etl.RunResult
+	if r.URL.Query().Get("force") == "true" {
+		result, err = h.pipeline.ForceRunRange(ctx, etl.DateRange{From: since})
+	} else {
+		result, err = h.pipeline.Run(ctx, since)
+	}
 	if err != nil {
-		h.logger.Errorf("Failed to extract CRM data: %v", err)
-		http.Error(w, "Failed to extract CRM data", http.StatusInternalServerError)
+		h.writePipelineError(w, r, err)
 		return
 	}
 
-	metrics, err := h.transformer.Transform(adsData, crmData, since)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"message":   "Data ingestion completed successfully",
+		"run_id":    result.RunID,
+		"tenant_id": result.Tenant,
+		"count":     result.Count,
+	}
+	if result.Unchanged {
+		response["message"] = "Sources unchanged since last ingestion"
+		response["unchanged"] = true
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handler) enqueueIngestion(w http.ResponseWriter, r *http.Request, since string) {
+	job := &queue.Job{
+		Tenant: tenant.FromContext(r.Context()),
+		Since:  since,
+		Force:  r.URL.Query().Get("force") == "true",
+	}
+	if err := h.queue.Enqueue(job); err != nil {
+		tracing.Logger(r.Context(), h.logger).Errorf("Failed to enqueue ingestion: %v", err)
+		http.Error(w, "Failed to enqueue ingestion", http.StatusInternalServerError)
+		return
+	}
+	monitoring.IngestJobs.WithLabelValues("enqueued").Inc()
+	tracing.Logger(r.Context(), h.logger).Infof("Enqueued ingestion job %s", job.ID)
+
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/run")+"/jobs/"+job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *Handler) JobHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	if h.queue == nil {
+		http.Error(w, "Ingestion queue is not configured", http.StatusNotFound)
+		return
+	}
+
+	job, err := h.queue.Get(mux.Vars(r)["id"])
+	// Un trabajo de otro tenant se trata como inexistente
+	if errors.Is(err, queue.ErrJobNotFound) || (err == nil && job.Tenant != tenant.FromContext(r.Context())) {
+		http.Error(w, "Ingestion job not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		h.logger.Errorf("Failed to transform data: %v", err)
-		http.Error(w, "Failed to transform data", http.StatusInternalServerError)
+		tracing.Logger(r.Context(), h.logger).Errorf("Failed to get ingestion job: %v", err)
+		http.Error(w, "Failed to get ingestion job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *Handler) ListRunsHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	store := h.pipeline.Landing()
+	if store == nil {
+		http.Error(w, "Landing zone is not configured", http.StatusNotFound)
 		return
 	}
 
-	if err := h.storage.SaveMetrics(metrics); err != nil {
-		h.logger.Errorf("Failed to save metrics: %v", err)
-		http.Error(w, "Failed to save metrics", http.StatusInternalServerError)
+	manifests, err := store.ListManifests()
+	if err != nil {
+		tracing.Logger(r.Context(), h.logger).Errorf("Failed to list ingestion runs: %v", err)
+		http.Error(w, "Failed to list ingestion runs", http.StatusInternalServerError)
 		return
 	}
 
+	tenantID := tenant.FromContext(r.Context())
+	runs := make([]landing.Manifest, 0, len(manifests))
+	for _, manifest := range manifests {
+		if manifest.Tenant == tenantID || (manifest.Tenant == "" && tenantID == tenant.Default) {
+			runs = append(runs, manifest)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(runs)
+}
+
+func (h *Handler) ReplayHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.pipeline.Replay(r.Context(), mux.Vars(r)["id"], true)
+	if err != nil {
+		h.writePipelineError(w, r, err)
+		return
+	}
 
 	response := map[string]interface{}{
-		"message": "Data ingestion completed successfully",
-		"count":   len(metrics),
+		"message": "Replay completed successfully",
+		"run_id":  result.RunID,
+		"count":   result.Count,
+	}
+	if result.Unchanged {
+		response["message"] = "Replay produced the same metrics"
+		response["unchanged"] = true
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *Handler) writePipelineError(w http.ResponseWriter, r *http.Request, err error) {
+	// Otra ejecución del mismo tenant tiene el lock: se devuelve su run_id para seguirla
+	var held *lock.HeldError
+	if errors.As(err, &held) {
+		tracing.Logger(r.Context(), h.logger).Warnf("Ingestion rejected: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":  "Ingestion already in progress",
+			"run_id": held.Holder,
+		})
+		return
+	}
+
+	tracing.Logger(r.Context(), h.logger).Errorf("Ingestion failed: %v", err)
+
+	if errors.Is(err, landing.ErrRunNotFound) {
+		http.Error(w, "Ingestion run not found", http.StatusNotFound)
+		return
+	}
+
+	if errors.Is(err, etl.ErrUnknownTenant) {
+		http.Error(w, "Unknown tenant", http.StatusNotFound)
+		return
+	}
+
+	var pipelineErr *etl.PipelineError
+	if errors.As(err, &pipelineErr) {
+		http.Error(w, "Failed to "+pipelineErr.Stage, http.StatusInternalServerError)
+		return
+	}
+
+	http.Error(w, "Ingestion failed", http.StatusInternalServerError)
+}
+
 func (h *Handler) MetricsChannelHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
 	params := r.URL.Query()
 	request := models.MetricsRequest{
-		From:    params.Get("from"),
-		To:      params.Get("to"),
-		Channel: params.Get("channel"),
+		From:        params.Get("from"),
+		To:          params.Get("to"),
+		Channel:     params.Get("channel"),
+		Granularity: params.Get("granularity"),
 	}
+	parseCampaignFilters(&request, params)
+	request.TenantID = tenant.FromContext(r.Context())
 
 	if limitStr := params.Get("limit"); limitStr != "" {
 		if limit, err := strconv.Atoi(limitStr); err == nil {
@@ -102,9 +279,14 @@ func (h *Handler) MetricsChannelHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	if !storage.ValidGranularity(request.Granularity) {
+		http.Error(w, "Invalid granularity", http.StatusBadRequest)
+		return
+	}
+
 	metrics, err := h.storage.GetMetricsByChannel(request)
 	if err != nil {
-		h.logger.Errorf("Failed to get metrics by channel: %v", err)
+		tracing.Logger(r.Context(), h.logger).Errorf("Failed to get metrics by channel: %v", err)
 		http.Error(w, "Failed to get metrics", http.StatusInternalServerError)
 		return
 	}
@@ -115,12 +297,20 @@ func (h *Handler) MetricsChannelHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *Handler) MetricsFunnelHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
 	params := r.URL.Query()
 	request := models.MetricsRequest{
 		From:        params.Get("from"),
 		To:          params.Get("to"),
 		UtmCampaign: params.Get("utm_campaign"),
+		Granularity: params.Get("granularity"),
 	}
+	parseCampaignFilters(&request, params)
+	request.TenantID = tenant.FromContext(r.Context())
 
 	if limitStr := params.Get("limit"); limitStr != "" {
 		if limit, err := strconv.Atoi(limitStr); err == nil {
@@ -134,9 +324,14 @@ func (h *Handler) MetricsFunnelHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if !storage.ValidGranularity(request.Granularity) {
+		http.Error(w, "Invalid granularity", http.StatusBadRequest)
+		return
+	}
+
 	metrics, err := h.storage.GetMetricsByFunnel(request)
 	if err != nil {
-		h.logger.Errorf("Failed to get metrics by funnel: %v", err)
+		tracing.Logger(r.Context(), h.logger).Errorf("Failed to get metrics by funnel: %v", err)
 		http.Error(w, "Failed to get metrics", http.StatusInternalServerError)
 		return
 	}
@@ -146,14 +341,103 @@ func (h *Handler) MetricsFunnelHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(metrics)
 }
 
+func (h *Handler) MetricsCampaignsHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	params := r.URL.Query()
+	request := models.MetricsRequest{
+		From:        params.Get("from"),
+		To:          params.Get("to"),
+		Channel:     params.Get("channel"),
+		UtmCampaign: params.Get("utm_campaign"),
+		GroupBy:     params.Get("group_by"),
+		Granularity: params.Get("granularity"),
+	}
+	parseCampaignFilters(&request, params)
+	request.TenantID = tenant.FromContext(r.Context())
+
+	if limitStr := params.Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			request.Limit = limit
+		}
+	}
+
+	if offsetStr := params.Get("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil {
+			request.Offset = offset
+		}
+	}
+
+	if !storage.ValidGranularity(request.Granularity) {
+		http.Error(w, "Invalid granularity", http.StatusBadRequest)
+		return
+	}
+	if !storage.ValidGroupBy(request.GroupBy) {
+		http.Error(w, "Invalid group_by", http.StatusBadRequest)
+		return
+	}
+
+	groups, err := h.storage.GetMetricsGrouped(request)
+	if err != nil {
+		tracing.Logger(r.Context(), h.logger).Errorf("Failed to group metrics: %v", err)
+		http.Error(w, "Failed to get metrics", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(groups)
+}
+
+func parseCampaignFilters(request *models.MetricsRequest, params url.Values) {
+	request.CampaignName = params.Get("campaign_name")
+	request.Owner = params.Get("owner")
+	request.BusinessUnit = params.Get("business_unit")
+	request.Tag = params.Get("tag")
+}
+
 func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
+// ReadyHandler es público: los errores, los detalles de cada check y el estado de los
+// circuit breakers sólo se muestran a quien se identifica con rol viewer.
 func (h *Handler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{"status": "ready"}
+	status := http.StatusOK
+	principal, ok := auth.PrincipalFrom(r.Context())
+	detailed := ok && principal.HasRole(auth.RoleViewer)
+
+	if h.readiness != nil {
+		report := h.readiness.Run(r.Context())
+		response["checks"] = report.Public()
+		if detailed {
+			response["checks"] = report.Checks
+		}
+		if !report.Ready {
+			response["status"] = "not_ready"
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	if h.sourceGuard != nil {
+		states := h.sourceGuard.States()
+		for _, state := range states {
+			if state.State == utils.StateOpen && status == http.StatusOK {
+				response["status"] = "degraded"
+			}
+		}
+		if detailed {
+			response["sources"] = states
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
 }
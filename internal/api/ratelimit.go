@@ -0,0 +1,220 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// Buckets sin uso durante este tiempo se descartan para no crecer sin límite.
+const idleBucketTTL = 10 * time.Minute
+
+type clientBucket struct {
+	bucket   *utils.TokenBucket
+	lastSeen time.Time
+}
+
+// ClientLimiter limita las peticiones por cliente (API key, sujeto del token o IP) y ruta.
+type ClientLimiter struct {
+	defaultLimit utils.RateLimit
+	routeLimits  map[string]utils.RateLimit
+	trustProxy   bool
+
+	mu        sync.Mutex
+	buckets   map[string]*clientBucket
+	lastSweep time.Time
+}
+
+func NewClientLimiter(defaultLimit utils.RateLimit, trustProxy bool) *ClientLimiter {
+	return &ClientLimiter{
+		defaultLimit: defaultLimit,
+		routeLimits:  make(map[string]utils.RateLimit),
+		trustProxy:   trustProxy,
+		buckets:      make(map[string]*clientBucket),
+		lastSweep:    time.Now(),
+	}
+}
+
+// SetRouteLimit fija el límite de una ruta, identificada por su plantilla sin versión (p. ej. /backfill/{id}).
+func (l *ClientLimiter) SetRouteLimit(route string, limit utils.RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.routeLimits[route] = limit
+}
+
+func (l *ClientLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route := operationPath(r)
+		bucket := l.bucketFor(clientKey(r, l.trustProxy), route)
+		if bucket == nil {
+			next(w, r)
+			return
+		}
+
+		wait, ok := bucket.Take()
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(bucket.Burst()))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(math.Floor(bucket.Tokens()))))
+		if !ok {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(retryAfter))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// Allow consume un token de client en route fuera de HTTP, por ejemplo desde gRPC. Si no
+// quedan, devuelve cuánto hay que esperar.
+func (l *ClientLimiter) Allow(client, route string) (time.Duration, bool) {
+	bucket := l.bucketFor(client, route)
+	if bucket == nil {
+		return 0, true
+	}
+	return bucket.Take()
+}
+
+func (l *ClientLimiter) bucketFor(client, route string) *utils.TokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, ok := l.routeLimits[route]
+	if !ok {
+		limit = l.defaultLimit
+	}
+	if limit.RequestsPerSecond <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > idleBucketTTL {
+		for key, entry := range l.buckets {
+			if now.Sub(entry.lastSeen) > idleBucketTTL {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	key := client + "|" + route
+	entry, ok := l.buckets[key]
+	if !ok {
+		entry = &clientBucket{bucket: utils.NewTokenBucket(limit.RequestsPerSecond, limit.Burst)}
+		l.buckets[key] = entry
+	}
+	entry.lastSeen = now
+	return entry.bucket
+}
+
+// DailyQuota limita cuántas veces al día (UTC) puede un cliente lanzar operaciones caras.
+type DailyQuota struct {
+	limit      int
+	trustProxy bool
+
+	mu     sync.Mutex
+	day    string
+	counts map[string]int
+}
+
+func NewDailyQuota(limit int, trustProxy bool) *DailyQuota {
+	return &DailyQuota{limit: limit, trustProxy: trustProxy, counts: make(map[string]int)}
+}
+
+func (q *DailyQuota) Enforce(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if q.limit <= 0 {
+			next(w, r)
+			return
+		}
+
+		used, ok := q.consume(clientKey(r, q.trustProxy))
+		w.Header().Set("X-Quota-Limit", strconv.Itoa(q.limit))
+		w.Header().Set("X-Quota-Remaining", strconv.Itoa(q.limit-used))
+		if !ok {
+			now := time.Now().UTC()
+			midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(midnight.Sub(now).Seconds()))))
+			http.Error(w, fmt.Sprintf("Daily quota of %d requests exceeded", q.limit), http.StatusTooManyRequests)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// Allow cuenta una operación de client fuera de HTTP y devuelve false si ya agotó la cuota del día.
+func (q *DailyQuota) Allow(client string) bool {
+	if q.limit <= 0 {
+		return true
+	}
+	_, ok := q.consume(client)
+	return ok
+}
+
+func (q *DailyQuota) consume(client string) (int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	today := time.Now().UTC().Format("2006-01-02")
+	if q.day != today {
+		q.day = today
+		q.counts = make(map[string]int)
+	}
+
+	if q.counts[client] >= q.limit {
+		return q.limit, false
+	}
+	q.counts[client]++
+	return q.counts[client], true
+}
+
+// clientKey identifica al cliente por la identidad que dejó en el contexto la autenticación
+// o, sin ella, por su IP. Las cabeceras de credenciales no se miran aquí: una API key sin
+// validar permitiría estrenar un bucket en cada petición.
+func clientKey(r *http.Request, trustProxy bool) string {
+	principal, _ := auth.PrincipalFrom(r.Context())
+
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			client, _, _ := strings.Cut(forwarded, ",")
+			return ClientKey(principal, strings.TrimSpace(client))
+		}
+	}
+
+	return ClientKey(principal, r.RemoteAddr)
+}
+
+// ClientKey identifica al cliente por su identidad autenticada o, sin ella, por su IP.
+// addr puede llevar puerto. Lo usan también los transportes que no son HTTP, como gRPC.
+func ClientKey(principal *auth.Principal, addr string) string {
+	if principal != nil && principal.Subject != "" {
+		return "sub:" + principal.Method + ":" + principal.Tenant + "/" + principal.Subject
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return "ip:" + host
+}
+
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
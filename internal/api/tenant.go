@@ -0,0 +1,30 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/tenant"
+)
+
+// scopeTenant resuelve el tenant de la petición y lo deja en su contexto. Una credencial
+// limitada a un tenant sólo puede operar sobre él; el resto elige con ?tenant= (por defecto, "default").
+func scopeTenant(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	var bound string
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		bound = principal.BoundTenant()
+	}
+
+	tenantID, err := tenant.Resolve(r.URL.Query().Get("tenant"), bound)
+	if errors.Is(err, tenant.ErrInvalidTenant) {
+		http.Error(w, "Invalid tenant parameter", http.StatusBadRequest)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	return r.WithContext(tenant.WithID(r.Context(), tenantID)), true
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/admira-project/backend/internal/openapi"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/gorilla/mux"
+)
+
+// VersionPrefix es el prefijo de las rutas versionadas; la especificación OpenAPI lo declara como server.
+const VersionPrefix = "/v1"
+
+const problemInvalidRequest = "urn:admira:problem:invalid-request"
+
+// Problem sigue el formato de RFC 7807, con la lista de errores de validación como extensión.
+type Problem struct {
+	Type      string               `json:"type"`
+	Title     string               `json:"title"`
+	Status    int                  `json:"status"`
+	Detail    string               `json:"detail,omitempty"`
+	Instance  string               `json:"instance,omitempty"`
+	RequestID string               `json:"request_id,omitempty"`
+	Errors    []openapi.FieldError `json:"errors,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	problem.Instance = r.URL.Path
+	problem.RequestID = tracing.RequestID(r.Context())
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// RequestValidator rechaza las peticiones que no cumplen la operación de la especificación.
+type RequestValidator struct {
+	doc *openapi.Document
+}
+
+func NewRequestValidator(doc *openapi.Document) *RequestValidator {
+	return &RequestValidator{doc: doc}
+}
+
+func (v *RequestValidator) Validate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		operation, ok := v.doc.Operation(r.Method, operationPath(r))
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		if errs := v.doc.ValidateRequest(operation, r, mux.Vars(r)); len(errs) > 0 {
+			status := http.StatusBadRequest
+			for _, err := range errs {
+				if err.In == "body" && strings.HasPrefix(err.Reason, openapi.ReasonBodyTooLarge) {
+					status = http.StatusRequestEntityTooLarge
+				}
+			}
+
+			writeProblem(w, r, Problem{
+				Type:   problemInvalidRequest,
+				Title:  "Invalid request",
+				Status: status,
+				Detail: fmt.Sprintf("%d validation error(s) for %s", len(errs), operation.OperationID),
+				Errors: errs,
+			})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// DeprecatedMiddleware marca las rutas sin versión e indica su equivalente en /v1.
+func DeprecatedMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, VersionPrefix, r.URL.Path))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// operationPath es la plantilla de la ruta sin el prefijo de versión, tal y como aparece en la especificación.
+func operationPath(r *http.Request) string {
+	return strings.TrimPrefix(routeTemplate(r), VersionPrefix)
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/admira-project/backend/internal/webhooks"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type WebhookHandler struct {
+	dispatcher *webhooks.Dispatcher
+	logger     *logrus.Logger
+}
+
+func NewWebhookHandler(dispatcher *webhooks.Dispatcher, logger *logrus.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+type webhookRequest struct {
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Secret      string `json:"secret"`
+	Active      *bool  `json:"active"`
+}
+
+// CreateHandler registra una suscripción. Si no se indica secret se genera uno;
+// la respuesta es la única que lo incluye.
+func (h *WebhookHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	var request webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid webhook payload", http.StatusBadRequest)
+		return
+	}
+
+	active := request.Active == nil || *request.Active
+	subscription, err := h.dispatcher.Create(tenant.FromContext(r.Context()), webhooks.Subscription{
+		URL:         request.URL,
+		Description: request.Description,
+		Secret:      request.Secret,
+		Active:      active,
+	})
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, subscription)
+}
+
+func (h *WebhookHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.dispatcher.List(tenant.FromContext(r.Context())))
+}
+
+func (h *WebhookHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.dispatcher.Get(tenant.FromContext(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+func (h *WebhookHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	var update webhooks.SubscriptionUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid webhook payload", http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.dispatcher.Update(tenant.FromContext(r.Context()), mux.Vars(r)["id"], update)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+func (h *WebhookHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.dispatcher.Delete(tenant.FromContext(r.Context()), mux.Vars(r)["id"]); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeliveriesHandler devuelve el registro de entregas de la suscripción; ?status= filtra por estado.
+func (h *WebhookHandler) DeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.dispatcher.Deliveries(tenant.FromContext(r.Context()), mux.Vars(r)["id"], r.URL.Query().Get("status"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// ReplayHandler reenvía una entrega concreta en segundo plano.
+func (h *WebhookHandler) ReplayHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	tenantID := tenant.FromContext(r.Context())
+
+	// La entrega tiene que pertenecer a la suscripción de la ruta
+	delivery, err := h.dispatcher.GetDelivery(tenantID, vars["delivery"])
+	if err == nil && delivery.SubscriptionID != vars["id"] {
+		err = webhooks.ErrDeliveryNotFound
+	}
+	if err == nil {
+		delivery, err = h.dispatcher.Replay(tenantID, vars["delivery"])
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+// ReplayFailedHandler reenvía todas las entregas fallidas de la suscripción.
+func (h *WebhookHandler) ReplayFailedHandler(w http.ResponseWriter, r *http.Request) {
+	r, ok := scopeTenant(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.dispatcher.ReplayFailed(tenant.FromContext(r.Context()), mux.Vars(r)["id"])
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, deliveries)
+}
+
+func (h *WebhookHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, webhooks.ErrSubscriptionNotFound):
+		http.Error(w, "Webhook subscription not found", http.StatusNotFound)
+	case errors.Is(err, webhooks.ErrDeliveryNotFound):
+		http.Error(w, "Webhook delivery not found", http.StatusNotFound)
+	case errors.Is(err, webhooks.ErrDeliveryActive):
+		http.Error(w, "Webhook delivery is in progress", http.StatusConflict)
+	case errors.Is(err, webhooks.ErrInvalidSubscription):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		tracing.Logger(r.Context(), h.logger).Errorf("Webhook request failed: %v", err)
+		http.Error(w, "Failed to process webhook request", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
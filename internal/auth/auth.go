@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+)
+
+// AllTenants es el valor explícito de tenant que da acceso a todos los tenants.
+const AllTenants = "*"
+
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// roleLevel ordena los roles: un operator puede hacer todo lo que puede un viewer.
+var roleLevel = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+}
+
+// Principal es la identidad autenticada. Tenant es AllTenants si tiene acceso a todos.
+type Principal struct {
+	Subject string   `json:"subject"`
+	Tenant  string   `json:"tenant,omitempty"`
+	Roles   []string `json:"roles"`
+	Method  string   `json:"method"`
+}
+
+// BoundTenant devuelve el tenant al que está limitada la credencial, o "" si tiene
+// acceso a todos. Un Tenant vacío se limita a tenant.Default.
+func (p *Principal) BoundTenant() string {
+	switch p.Tenant {
+	case AllTenants:
+		return ""
+	case "":
+		return tenant.Default
+	}
+	return p.Tenant
+}
+
+func (p *Principal) HasRole(role string) bool {
+	required, ok := roleLevel[role]
+	if !ok {
+		return false
+	}
+
+	for _, granted := range p.Roles {
+		if roleLevel[granted] >= required {
+			return true
+		}
+	}
+	return false
+}
+
+type apiKey struct {
+	hash    [32]byte
+	subject string
+	tenant  string
+	roles   []string
+}
+
+type JWTConfig struct {
+	Keys        *KeySet
+	Issuer      string
+	Audience    string
+	RolesClaim  string
+	TenantClaim string
+}
+
+type Authenticator struct {
+	apiKeys  []apiKey
+	jwt      *JWTConfig
+	disabled bool
+}
+
+func NewAuthenticator() *Authenticator {
+	return &Authenticator{}
+}
+
+// AddAPIKey registra una clave limitada al tenant por defecto.
+func (a *Authenticator) AddAPIKey(key, subject string, roles []string) {
+	a.AddTenantAPIKey(key, subject, tenant.Default, roles)
+}
+
+// AddTenantAPIKey registra una clave limitada a los datos de un tenant; con AllTenants
+// puede acceder a todos.
+func (a *Authenticator) AddTenantAPIKey(key, subject, tenantID string, roles []string) {
+	if tenantID == "" {
+		tenantID = tenant.Default
+	}
+	a.apiKeys = append(a.apiKeys, apiKey{hash: sha256.Sum256([]byte(key)), subject: subject, tenant: tenantID, roles: roles})
+}
+
+func (a *Authenticator) SetJWT(config JWTConfig) {
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+	if config.TenantClaim == "" {
+		config.TenantClaim = "tenant_id"
+	}
+	a.jwt = &config
+}
+
+// Enabled indica si hay algún mecanismo configurado. Sin ninguno se rechaza toda petición,
+// salvo que la autenticación se haya desactivado con Disable.
+func (a *Authenticator) Enabled() bool {
+	return len(a.apiKeys) > 0 || a.jwt != nil
+}
+
+// Disable deja pasar todas las peticiones sin credenciales. Sólo debe usarse cuando se pide
+// de forma explícita, por ejemplo en desarrollo.
+func (a *Authenticator) Disable() {
+	a.disabled = true
+}
+
+func (a *Authenticator) Disabled() bool {
+	return a.disabled
+}
+
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	return a.AuthenticateCredentials(r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+}
+
+// AuthenticateCredentials valida una API key o, si no hay, el valor de Authorization.
+// Permite autenticar transportes que no son HTTP, como los metadatos de gRPC.
+func (a *Authenticator) AuthenticateCredentials(apiKey, authorization string) (*Principal, error) {
+	if apiKey != "" {
+		return a.authenticateAPIKey(apiKey)
+	}
+
+	if authorization == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, fmt.Errorf("%w: unsupported authorization scheme", ErrInvalidCredentials)
+	}
+	return a.authenticateJWT(strings.TrimSpace(token))
+}
+
+func (a *Authenticator) authenticateAPIKey(key string) (*Principal, error) {
+	hash := sha256.Sum256([]byte(key))
+
+	// Se recorren todas las claves para no filtrar por tiempos cuál coincide
+	var match *apiKey
+	for i := range a.apiKeys {
+		if subtle.ConstantTimeCompare(hash[:], a.apiKeys[i].hash[:]) == 1 {
+			match = &a.apiKeys[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%w: unknown API key", ErrInvalidCredentials)
+	}
+
+	return &Principal{Subject: match.subject, Tenant: match.tenant, Roles: match.roles, Method: MethodAPIKey}, nil
+}
+
+func (a *Authenticator) authenticateJWT(token string) (*Principal, error) {
+	if a.jwt == nil {
+		return nil, fmt.Errorf("%w: bearer tokens are not accepted", ErrInvalidCredentials)
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+	}
+	if a.jwt.Issuer != "" {
+		options = append(options, jwt.WithIssuer(a.jwt.Issuer))
+	}
+	if a.jwt.Audience != "" {
+		options = append(options, jwt.WithAudience(a.jwt.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := a.jwt.Keys.Key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return key, nil
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	subject, _ := claims.GetSubject()
+	// Sin el claim de tenant el token no se acepta; el acceso a todos se pide con AllTenants
+	tenantID, _ := claims[a.jwt.TenantClaim].(string)
+	if tenantID == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidCredentials, a.jwt.TenantClaim)
+	}
+	if tenantID != AllTenants && !tenant.Valid(tenantID) {
+		return nil, fmt.Errorf("%w: invalid %s claim", ErrInvalidCredentials, a.jwt.TenantClaim)
+	}
+	return &Principal{Subject: subject, Tenant: tenantID, Roles: claimRoles(claims[a.jwt.RolesClaim]), Method: MethodJWT}, nil
+}
+
+// claimRoles acepta el claim como lista o como cadena separada por espacios.
+func claimRoles(value interface{}) []string {
+	switch roles := value.(type) {
+	case string:
+		return strings.Fields(roles)
+	case []interface{}:
+		var result []string
+		for _, role := range roles {
+			if name, ok := role.(string); ok {
+				result = append(result, name)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
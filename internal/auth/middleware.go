@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// Require exige credenciales válidas con al menos el rol indicado.
+func (a *Authenticator) Require(role string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Disabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admira"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if !principal.HasRole(role) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// Identify añade la identidad al contexto si la petición trae credenciales válidas, pero
+// no las exige. Sirve para rutas públicas que muestran más información a quien se identifica.
+func (a *Authenticator) Identify(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, err := a.Authenticate(r); err == nil {
+			r = r.WithContext(WithPrincipal(r.Context(), principal))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
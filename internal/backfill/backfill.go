@@ -0,0 +1,447 @@
+package backfill
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/lock"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// lockRetryInterval es la espera antes de reintentar el lock del tenant cuando otra ingesta
+// lo tiene.
+const lockRetryInterval = 200 * time.Millisecond
+
+var (
+	ErrJobNotFound      = errors.New("backfill job not found")
+	ErrJobAlreadyActive = errors.New("backfill job is already running")
+)
+
+type Chunk struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Status string `json:"status"`
+	RunID  string `json:"run_id,omitempty"`
+	Count  int    `json:"count"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Job struct {
+	ID        string    `json:"id"`
+	Tenant    string    `json:"tenant_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	ChunkDays int       `json:"chunk_days"`
+	Status    string    `json:"status"`
+	Chunks    []Chunk   `json:"chunks"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Runner interface {
+	RunRange(ctx context.Context, window etl.DateRange) (*etl.RunResult, error)
+}
+
+// TenantLocker lo implementan los runners con lock de ingesta por tenant. El job lo toma una
+// vez y sus tramos corren bajo él con el contexto devuelto, sin competir entre ellos.
+type TenantLocker interface {
+	LockTenant(ctx context.Context, holder string) (context.Context, func(), error)
+}
+
+// Manager divide un rango de fechas en tramos y los ingesta con concurrencia acotada.
+// Si stateDir no está vacío, el estado de cada job se persiste tras cada tramo para poder reanudarlo.
+type Manager struct {
+	runner      Runner
+	stateDir    string
+	concurrency int
+	logger      *logrus.Logger
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	active map[string]bool
+}
+
+func NewManager(runner Runner, stateDir string, concurrency int, logger *logrus.Logger) (*Manager, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	if stateDir != "" {
+		if err := os.MkdirAll(stateDir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create backfill state directory: %v", err)
+		}
+	}
+
+	return &Manager{
+		runner:      runner,
+		stateDir:    stateDir,
+		concurrency: concurrency,
+		logger:      logger,
+		jobs:        make(map[string]*Job),
+		active:      make(map[string]bool),
+	}, nil
+}
+
+func (m *Manager) Create(from, to time.Time, chunkDays int) (*Job, error) {
+	return m.CreateForTenant(tenant.Default, from, to, chunkDays)
+}
+
+func (m *Manager) CreateForTenant(tenantID string, from, to time.Time, chunkDays int) (*Job, error) {
+	if from.IsZero() || to.IsZero() {
+		return nil, fmt.Errorf("from and to are required")
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("to must not be before from")
+	}
+	if chunkDays <= 0 {
+		chunkDays = 7
+	}
+
+	now := time.Now().UTC()
+	job := &Job{
+		ID:        newJobID(),
+		Tenant:    tenantID,
+		From:      from.Format("2006-01-02"),
+		To:        to.Format("2006-01-02"),
+		ChunkDays: chunkDays,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	for start := from; !start.After(to); start = start.AddDate(0, 0, chunkDays) {
+		end := start.AddDate(0, 0, chunkDays-1)
+		if end.After(to) {
+			end = to
+		}
+		job.Chunks = append(job.Chunks, Chunk{
+			From:   start.Format("2006-01-02"),
+			To:     end.Format("2006-01-02"),
+			Status: StatusPending,
+		})
+	}
+
+	m.mu.Lock()
+	m.jobs[job.ID] = job
+	err := m.persist(job)
+	m.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Run procesa los tramos pendientes o fallidos del job. Los tramos completados se saltan,
+// por lo que llamar de nuevo a Run sobre un job interrumpido lo reanuda.
+func (m *Manager) Run(ctx context.Context, jobID string) (*Job, error) {
+	job, err := m.acquire(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.execute(ctx, job)
+}
+
+// Start es como Run pero procesa el job en segundo plano.
+func (m *Manager) Start(ctx context.Context, jobID string) error {
+	job, err := m.acquire(jobID)
+	if err != nil {
+		return err
+	}
+
+	go m.execute(ctx, job)
+	return nil
+}
+
+func (m *Manager) acquire(jobID string) (*Job, error) {
+	job, err := m.load(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active[jobID] {
+		return nil, ErrJobAlreadyActive
+	}
+	previous := job.Status
+	job.Status = StatusRunning
+	// Si no se puede guardar el estado no se arranca: el job quedaría sin poder reanudarse
+	if err := m.persist(job); err != nil {
+		job.Status = previous
+		return nil, err
+	}
+	m.active[jobID] = true
+
+	return job, nil
+}
+
+func (m *Manager) execute(ctx context.Context, job *Job) (*Job, error) {
+	defer func() {
+		m.mu.Lock()
+		delete(m.active, job.ID)
+		m.mu.Unlock()
+	}()
+
+	tenantID := job.Tenant
+	if tenantID == "" {
+		tenantID = tenant.Default
+	}
+	ctx = tenant.WithID(ctx, tenantID)
+
+	if locker, ok := m.runner.(TenantLocker); ok {
+		locked, unlock, err := m.lockTenant(ctx, locker, lockHolder(job.ID))
+		if err != nil {
+			m.logger.Errorf("Backfill %s: failed to take the ingestion lock: %v", job.ID, err)
+			return m.finish(job, err)
+		}
+		defer unlock()
+		ctx = locked
+	}
+
+	sem := make(chan struct{}, m.concurrency)
+	var wg sync.WaitGroup
+
+	for i := range job.Chunks {
+		if job.Chunks[i].Status == StatusCompleted {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			m.runChunk(ctx, job, i)
+		}(i)
+	}
+
+	wg.Wait()
+
+	return m.finish(job, ctx.Err())
+}
+
+// finish calcula el estado del job a partir de sus tramos, lo guarda y devuelve una copia.
+func (m *Manager) finish(job *Job, runErr error) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job.Status = StatusCompleted
+	for _, chunk := range job.Chunks {
+		if chunk.Status == StatusFailed {
+			job.Status = StatusFailed
+			break
+		}
+		if chunk.Status != StatusCompleted {
+			job.Status = StatusPending
+		}
+	}
+
+	if err := m.persist(job); err != nil {
+		return nil, err
+	}
+
+	snapshot := *job
+	snapshot.Chunks = append([]Chunk(nil), job.Chunks...)
+	return &snapshot, runErr
+}
+
+func (m *Manager) runChunk(ctx context.Context, job *Job, i int) {
+	m.mu.Lock()
+	chunk := job.Chunks[i]
+	job.Chunks[i].Status = StatusRunning
+	job.Chunks[i].Error = ""
+	m.persist(job)
+	m.mu.Unlock()
+
+	from, _ := time.Parse("2006-01-02", chunk.From)
+	to, _ := time.Parse("2006-01-02", chunk.To)
+
+	m.logger.Infof("Backfill %s: ingesting %s to %s", job.ID, chunk.From, chunk.To)
+	result, err := m.runner.RunRange(ctx, etl.DateRange{From: from, To: to})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case err == nil:
+		job.Chunks[i].Status = StatusCompleted
+		job.Chunks[i].RunID = result.RunID
+		job.Chunks[i].Count = result.Count
+	case ctx.Err() != nil:
+		// Interrumpido: se deja pendiente para reanudar
+		job.Chunks[i].Status = StatusPending
+	default:
+		m.logger.Errorf("Backfill %s: chunk %s to %s failed: %v", job.ID, chunk.From, chunk.To, err)
+		job.Chunks[i].Status = StatusFailed
+		job.Chunks[i].Error = err.Error()
+	}
+
+	if err := m.persist(job); err != nil {
+		m.logger.Errorf("Failed to persist backfill state: %v", err)
+	}
+}
+
+// lockTenant espera mientras otra ingesta del tenant tenga el lock en lugar de dar el job
+// por fallido.
+func (m *Manager) lockTenant(ctx context.Context, locker TenantLocker, holder string) (context.Context, func(), error) {
+	for {
+		locked, unlock, err := locker.LockTenant(ctx, holder)
+		if !errors.Is(err, lock.ErrHeld) {
+			return locked, unlock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+func (m *Manager) Get(jobID string) (*Job, error) {
+	job, err := m.load(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := *job
+	snapshot.Chunks = append([]Chunk(nil), job.Chunks...)
+	return &snapshot, nil
+}
+
+func (m *Manager) List() ([]Job, error) {
+	if m.stateDir != "" {
+		entries, err := os.ReadDir(m.stateDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list backfill jobs: %v", err)
+		}
+		for _, entry := range entries {
+			if id, ok := strings.CutSuffix(entry.Name(), ".json"); ok {
+				if _, err := m.load(id); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		snapshot := *job
+		snapshot.Chunks = append([]Chunk(nil), job.Chunks...)
+		jobs = append(jobs, snapshot)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+func (m *Manager) load(jobID string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job, ok := m.jobs[jobID]; ok {
+		return job, nil
+	}
+
+	if m.stateDir == "" || jobID == "" || strings.ContainsAny(jobID, `/\`) {
+		return nil, ErrJobNotFound
+	}
+
+	data, err := os.ReadFile(filepath.Join(m.stateDir, jobID+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backfill job %s: %v", jobID, err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal backfill job %s: %v", jobID, err)
+	}
+
+	// Un tramo que quedó "running" en disco pertenece a un proceso interrumpido
+	for i := range job.Chunks {
+		if job.Chunks[i].Status == StatusRunning {
+			job.Chunks[i].Status = StatusPending
+		}
+	}
+
+	m.jobs[job.ID] = &job
+	return &job, nil
+}
+
+// persist debe llamarse con m.mu tomado.
+func (m *Manager) persist(job *Job) error {
+	job.UpdatedAt = time.Now().UTC()
+
+	if m.stateDir == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(m.stateDir, job.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write backfill state: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write backfill state: %v", err)
+	}
+	return nil
+}
+
+// lockHolder identifica cada ejecución del job ante el lock, de modo que reanudarlo nunca
+// reutiliza el lock de una ejecución anterior.
+func lockHolder(jobID string) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return jobID + "-" + hex.EncodeToString(suffix)
+}
+
+func newJobID() string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return "bf-" + time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,169 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/tenant"
+)
+
+// Catalog guarda las campañas de cada tenant por separado: un tenant no ve ni modifica
+// las de otro, y sus métricas sólo se enriquecen con las suyas. Un tenantID vacío es el
+// tenant por defecto.
+type Catalog struct {
+	mu        sync.RWMutex
+	campaigns map[string]map[string]models.Campaign
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{
+		campaigns: make(map[string]map[string]models.Campaign),
+	}
+}
+
+func (c *Catalog) Lookup(tenantID, campaignID string) (models.Campaign, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	campaign, ok := c.campaigns[tenantKey(tenantID)][campaignID]
+	return campaign, ok
+}
+
+func (c *Catalog) List(tenantID string) []models.Campaign {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	byID := c.campaigns[tenantKey(tenantID)]
+	campaigns := make([]models.Campaign, 0, len(byID))
+	for _, campaign := range byID {
+		campaigns = append(campaigns, campaign)
+	}
+
+	sort.Slice(campaigns, func(i, j int) bool {
+		return campaigns[i].CampaignID < campaigns[j].CampaignID
+	})
+	return campaigns
+}
+
+func (c *Catalog) Upsert(tenantID string, campaign models.Campaign) error {
+	if campaign.CampaignID == "" {
+		return fmt.Errorf("campaign_id is required")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tenantCampaigns(tenantID)[campaign.CampaignID] = campaign
+	return nil
+}
+
+func (c *Catalog) Delete(tenantID, campaignID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	byID := c.campaigns[tenantKey(tenantID)]
+	_, ok := byID[campaignID]
+	delete(byID, campaignID)
+	return ok
+}
+
+// tenantCampaigns devuelve, creándolo si hace falta, el mapa de un tenant; requiere c.mu.
+func (c *Catalog) tenantCampaigns(tenantID string) map[string]models.Campaign {
+	key := tenantKey(tenantID)
+	byID, ok := c.campaigns[key]
+	if !ok {
+		byID = make(map[string]models.Campaign)
+		c.campaigns[key] = byID
+	}
+	return byID
+}
+
+func tenantKey(tenantID string) string {
+	if tenantID == "" {
+		return tenant.Default
+	}
+	return tenantID
+}
+
+func (c *Catalog) LoadCSVFile(tenantID, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open campaign catalog: %v", err)
+	}
+	defer file.Close()
+
+	return c.LoadCSV(tenantID, file)
+}
+
+// LoadCSV espera una cabecera con campaign_id y, opcionalmente, name, owner,
+// objective, business_unit y tags (separados por ";").
+func (c *Catalog) LoadCSV(tenantID string, r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read campaign catalog header: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["campaign_id"]; !ok {
+		return 0, fmt.Errorf("campaign catalog is missing campaign_id column")
+	}
+
+	column := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var campaigns []models.Campaign
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read campaign catalog: %v", err)
+		}
+
+		campaign := models.Campaign{
+			CampaignID:   column(record, "campaign_id"),
+			Name:         column(record, "name"),
+			Owner:        column(record, "owner"),
+			Objective:    column(record, "objective"),
+			BusinessUnit: column(record, "business_unit"),
+		}
+		for _, tag := range strings.Split(column(record, "tags"), ";") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				campaign.Tags = append(campaign.Tags, tag)
+			}
+		}
+
+		if campaign.CampaignID == "" {
+			return 0, fmt.Errorf("campaign catalog row %d is missing campaign_id", len(campaigns)+2)
+		}
+		campaigns = append(campaigns, campaign)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	byID := c.tenantCampaigns(tenantID)
+	for _, campaign := range campaigns {
+		byID[campaign.CampaignID] = campaign
+	}
+
+	return len(campaigns), nil
+}
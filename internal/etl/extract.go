@@ -4,72 +4,160 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
 	"github.com/admira-project/backend/internal/utils"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// DateRange acota una extracción; los extremos a cero no se envían a la fuente.
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
 type Extractor struct {
 	httpClient utils.HTTPClient
-	adsAPIURL  string
-	crmAPIURL  string
+	ads        SourceConfig
+	crm        SourceConfig
+	tenants    map[string]tenantSources
+	cache      *utils.ResponseCache
 	logger     *logrus.Logger
 }
 
 func NewExtractor(httpClient utils.HTTPClient, adsAPIURL, crmAPIURL string, logger *logrus.Logger) *Extractor {
-	if adsAPIURL == "" {
-		adsAPIURL = "http://mock-ads:3001"
+	return NewExtractorWithSources(httpClient, SourceConfig{URL: adsAPIURL}, SourceConfig{URL: crmAPIURL}, logger)
+}
+
+func NewExtractorWithSources(httpClient utils.HTTPClient, ads, crm SourceConfig, logger *logrus.Logger) *Extractor {
+	if ads.URL == "" {
+		ads.URL = "http://mock-ads:3001"
 		logger.Warn("ADS_API_URL is empty, using default: http://mock-ads:3001")
 	}
-	if crmAPIURL == "" {
-		crmAPIURL = "http://mock-crm:3002"
+	if crm.URL == "" {
+		crm.URL = "http://mock-crm:3002"
 		logger.Warn("CRM_API_URL is empty, using default: http://mock-crm:3002")
 	}
 
-	logger.Infof("ADS_API_URL: %s", adsAPIURL)
-	logger.Infof("CRM_API_URL: %s", crmAPIURL)
+	logger.Infof("ADS_API_URL: %s", ads.URL)
+	logger.Infof("CRM_API_URL: %s", crm.URL)
 
 	return &Extractor{
 		httpClient: httpClient,
-		adsAPIURL:  adsAPIURL,
-		crmAPIURL:  crmAPIURL,
+		ads:        ads,
+		crm:        crm,
+		tenants:    make(map[string]tenantSources),
 		logger:     logger,
 	}
 }
 
+func (e *Extractor) SetCache(cache *utils.ResponseCache) {
+	e.cache = cache
+}
+
+// SourceURLs devuelve la URL base configurada de cada fuente.
+func (e *Extractor) SourceURLs() map[string]string {
+	return map[string]string{SourceAds: e.ads.URL, SourceCrm: e.crm.URL}
+}
+
 func (e *Extractor) ExtractAdsData(ctx context.Context) (*models.AdsData, error) {
-	e.logger.Info("Extracting Ads data")
-	e.logger.Infof("Fetching from: %s", e.adsAPIURL)
+	result, err := e.FetchAdsPayload(ctx, DateRange{})
+	if err != nil {
+		return nil, err
+	}
+
+	return e.ParseAdsData(ctx, result.Body)
+}
+
+func (e *Extractor) FetchAdsPayload(ctx context.Context, window DateRange) (result *utils.FetchResult, err error) {
+	source, err := e.source(ctx, SourceAds)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceURL := source.RequestURL(window)
+	ctx, span := tracing.Start(ctx, "extract ads",
+		attribute.String("etl.source", SourceAds),
+		attribute.String("etl.tenant", tenant.FromContext(ctx)),
+		attribute.String("http.url", sourceURL),
+	)
+	defer func() { tracing.End(span, err) }()
 
-	body, err := utils.FetchData(ctx, e.httpClient, e.adsAPIURL)
+	log := tracing.Logger(ctx, e.logger)
+	log.Info("Extracting Ads data")
+	log.Infof("Fetching from: %s", sourceURL)
+
+	result, err = utils.Fetch(ctx, e.httpClient, sourceURL, e.requestOptions(ctx, source, sourceURL))
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch ads data: %v", err)
 	}
 
+	span.SetAttributes(attribute.Bool("http.not_modified", result.NotModified))
+	if result.NotModified {
+		log.Info("Ads source not modified, using cached response")
+	}
+	return result, nil
+}
+
+func (e *Extractor) ParseAdsData(ctx context.Context, body []byte) (*models.AdsData, error) {
 	var adsData models.AdsData
 	if err := json.Unmarshal(body, &adsData); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal ads data: %v", err)
 	}
 
-	e.logger.Infof("Extracted %d ads performance records", len(adsData.External.Ads.Performance))
+	tracing.Logger(ctx, e.logger).Infof("Extracted %d ads performance records", len(adsData.External.Ads.Performance))
 	return &adsData, nil
 }
 
 func (e *Extractor) ExtractCrmData(ctx context.Context) (*models.CrmData, error) {
-	e.logger.Info("Extracting CRM data")
-	e.logger.Infof("Fetching from: %s", e.crmAPIURL)
+	result, err := e.FetchCrmPayload(ctx, DateRange{})
+	if err != nil {
+		return nil, err
+	}
+
+	return e.ParseCrmData(ctx, result.Body)
+}
 
-	body, err := utils.FetchData(ctx, e.httpClient, e.crmAPIURL)
+func (e *Extractor) FetchCrmPayload(ctx context.Context, window DateRange) (result *utils.FetchResult, err error) {
+	source, err := e.source(ctx, SourceCrm)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceURL := source.RequestURL(window)
+	ctx, span := tracing.Start(ctx, "extract crm",
+		attribute.String("etl.source", SourceCrm),
+		attribute.String("etl.tenant", tenant.FromContext(ctx)),
+		attribute.String("http.url", sourceURL),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	log := tracing.Logger(ctx, e.logger)
+	log.Info("Extracting CRM data")
+	log.Infof("Fetching from: %s", sourceURL)
+
+	result, err = utils.Fetch(ctx, e.httpClient, sourceURL, e.requestOptions(ctx, source, sourceURL))
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch crm data: %v", err)
 	}
 
+	span.SetAttributes(attribute.Bool("http.not_modified", result.NotModified))
+	if result.NotModified {
+		log.Info("CRM source not modified, using cached response")
+	}
+	return result, nil
+}
+
+func (e *Extractor) ParseCrmData(ctx context.Context, body []byte) (*models.CrmData, error) {
 	var crmData models.CrmData
 	if err := json.Unmarshal(body, &crmData); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal crm data: %v", err)
 	}
 
-	e.logger.Infof("Extracted %d CRM opportunities", len(crmData.External.Crm.Opportunities))
+	tracing.Logger(ctx, e.logger).Infof("Extracted %d CRM opportunities", len(crmData.External.Crm.Opportunities))
 	return &crmData, nil
 }
@@ -0,0 +1,528 @@
+package etl
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/admira-project/backend/internal/landing"
+	"github.com/admira-project/backend/internal/lock"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	SourceAds = "ads"
+	SourceCrm = "crm"
+)
+
+// IngestHook se ejecuta tras cada ingesta guardada correctamente.
+type IngestHook interface {
+	AfterIngest(ctx context.Context, metrics []models.Metric)
+}
+
+// RunObserver recibe el ciclo de vida de cada ejecución, incluidos los replays.
+type RunObserver interface {
+	RunStarted(ctx context.Context, runID string)
+	StageCompleted(ctx context.Context, runID, stage string, duration time.Duration)
+	RunFinished(ctx context.Context, result *RunResult)
+	RunFailed(ctx context.Context, runID string, err error)
+}
+
+type PipelineError struct {
+	Stage string
+	Err   error
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("failed to %s: %v", e.Stage, e.Err)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+type RunResult struct {
+	RunID   string          `json:"run_id"`
+	Tenant  string          `json:"tenant_id"`
+	Since   string          `json:"since,omitempty"`
+	Until   string          `json:"until,omitempty"`
+	Count   int             `json:"count"`
+	Metrics []models.Metric `json:"-"`
+	// Unchanged indica que ambas fuentes respondieron 304 con el mismo contenido ya procesado
+	Unchanged bool `json:"unchanged,omitempty"`
+}
+
+type Pipeline struct {
+	extractor   *Extractor
+	transformer *Transformer
+	storage     storage.Storage
+	landing     *landing.Store
+	hooks       []IngestHook
+	observers   []RunObserver
+	logger      *logrus.Logger
+
+	// locker impide que dos ejecuciones del mismo tenant se solapen, sea cual sea su ventana
+	locker  lock.Locker
+	lockTTL time.Duration
+
+	// saveMu serializa las escrituras cuando varias ejecuciones corren en paralelo (backfill)
+	saveMu sync.Mutex
+
+	// processed guarda la huella de los payloads ya ingeridos por ventana de fechas
+	processedMu sync.Mutex
+	processed   map[string]string
+	lastSuccess time.Time
+}
+
+func NewPipeline(extractor *Extractor, transformer *Transformer, storage storage.Storage, logger *logrus.Logger) *Pipeline {
+	return &Pipeline{
+		extractor:   extractor,
+		transformer: transformer,
+		storage:     storage,
+		logger:      logger,
+		processed:   make(map[string]string),
+	}
+}
+
+func (p *Pipeline) SetLanding(store *landing.Store) {
+	p.landing = store
+}
+
+func (p *Pipeline) Landing() *landing.Store {
+	return p.landing
+}
+
+// SetLocker activa el lock de ingesta por tenant. Mientras dura la ejecución
+// se renueva cada ttl/3; si se pierde, la ejecución se cancela.
+func (p *Pipeline) SetLocker(locker lock.Locker, ttl time.Duration) {
+	p.locker = locker
+	p.lockTTL = ttl
+}
+
+func (p *Pipeline) AddHook(hook IngestHook) {
+	p.hooks = append(p.hooks, hook)
+}
+
+func (p *Pipeline) AddObserver(observer RunObserver) {
+	p.observers = append(p.observers, observer)
+}
+
+func (p *Pipeline) Run(ctx context.Context, since time.Time) (*RunResult, error) {
+	return p.RunRange(ctx, DateRange{From: since})
+}
+
+func (p *Pipeline) RunRange(ctx context.Context, window DateRange) (*RunResult, error) {
+	return p.run(ctx, window, false)
+}
+
+// ForceRunRange ingesta aunque las fuentes no hayan cambiado desde la última ejecución.
+func (p *Pipeline) ForceRunRange(ctx context.Context, window DateRange) (*RunResult, error) {
+	return p.run(ctx, window, true)
+}
+
+func (p *Pipeline) run(ctx context.Context, window DateRange, force bool) (*RunResult, error) {
+	runID := newRunID()
+
+	ctx, unlock, err := p.lock(ctx, runID)
+	if err != nil {
+		monitoring.ETLRuns.WithLabelValues("conflict").Inc()
+		return nil, err
+	}
+	defer unlock()
+
+	ctx, span := tracing.Start(ctx, "pipeline run", attribute.Bool("etl.force", force), attribute.String("etl.run_id", runID))
+	p.notifyStarted(ctx, runID)
+	result, err := p.runOnce(ctx, runID, window, force)
+	if result != nil {
+		span.SetAttributes(attribute.Bool("etl.unchanged", result.Unchanged))
+	}
+	tracing.End(span, err)
+	p.notifyFinished(ctx, runID, result, err)
+
+	switch {
+	case err != nil:
+		monitoring.ETLRuns.WithLabelValues("failure").Inc()
+	case result.Unchanged:
+		monitoring.ETLRuns.WithLabelValues("unchanged").Inc()
+	default:
+		monitoring.ETLRuns.WithLabelValues("success").Inc()
+		monitoring.LastSuccessfulIngestion.SetToCurrentTime()
+	}
+
+	if err == nil {
+		p.processedMu.Lock()
+		p.lastSuccess = time.Now()
+		p.processedMu.Unlock()
+	}
+
+	return result, err
+}
+
+func (p *Pipeline) runOnce(ctx context.Context, runID string, window DateRange, force bool) (*RunResult, error) {
+	stage := p.startStage(ctx, runID, "extract_ads")
+	ads, err := p.extractor.FetchAdsPayload(ctx, window)
+	stage.done()
+	if err != nil {
+		return nil, &PipelineError{Stage: "extract ads data", Err: err}
+	}
+
+	stage = p.startStage(ctx, runID, "extract_crm")
+	crm, err := p.extractor.FetchCrmPayload(ctx, window)
+	stage.done()
+	if err != nil {
+		return nil, &PipelineError{Stage: "extract CRM data", Err: err}
+	}
+
+	windowKey := rangeKey(tenant.FromContext(ctx), window)
+	fingerprint := payloadFingerprint(ads.Body, crm.Body)
+
+	if !force && ads.NotModified && crm.NotModified && p.lastFingerprint(windowKey) == fingerprint {
+		tracing.Logger(ctx, p.logger).Info("Sources unchanged since last ingestion, skipping")
+		return &RunResult{RunID: runID, Tenant: tenant.FromContext(ctx), Unchanged: true}, nil
+	}
+
+	if p.landing != nil {
+		stage := p.startStage(ctx, runID, "land")
+		err := p.land(ctx, runID, window, ads.Body, crm.Body)
+		stage.done()
+		if err != nil {
+			return nil, &PipelineError{Stage: "store raw payloads", Err: err}
+		}
+	}
+
+	result, err := p.process(ctx, runID, window, ads.Body, crm.Body, true)
+	if err != nil {
+		return nil, err
+	}
+
+	p.processedMu.Lock()
+	p.processed[windowKey] = fingerprint
+	p.processedMu.Unlock()
+
+	return result, nil
+}
+
+// LastSuccess devuelve el instante de la última ingesta correcta (o sin cambios) de este proceso.
+func (p *Pipeline) LastSuccess() time.Time {
+	p.processedMu.Lock()
+	defer p.processedMu.Unlock()
+	return p.lastSuccess
+}
+
+func (p *Pipeline) lastFingerprint(windowKey string) string {
+	p.processedMu.Lock()
+	defer p.processedMu.Unlock()
+	return p.processed[windowKey]
+}
+
+func payloadFingerprint(adsBody, crmBody []byte) string {
+	hash := sha256.New()
+	hash.Write(adsBody)
+	hash.Write([]byte{0})
+	hash.Write(crmBody)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Replay vuelve a transformar los payloads guardados de una ejecución sin llamar a las fuentes.
+// Con save=false sólo devuelve las métricas resultantes. Con save=true sustituye las métricas
+// que guardó la ejecución original; si no cambian, el resultado sale como Unchanged.
+func (p *Pipeline) Replay(ctx context.Context, runID string, save bool) (*RunResult, error) {
+	if p.landing == nil {
+		return nil, &PipelineError{Stage: "load raw payloads", Err: fmt.Errorf("landing zone is not configured")}
+	}
+	if _, ok := p.storage.(storage.RunStore); save && !ok {
+		return nil, &PipelineError{Stage: "save metrics", Err: fmt.Errorf("storage cannot replace the metrics of a run")}
+	}
+
+	manifest, err := p.landing.LoadManifest(runID)
+	if err != nil {
+		return nil, &PipelineError{Stage: "load raw payloads", Err: err}
+	}
+
+	// Una ejecución de otro tenant se trata como inexistente
+	manifestTenant := manifest.Tenant
+	if manifestTenant == "" {
+		manifestTenant = tenant.Default
+	}
+	if scoped, ok := tenant.Scoped(ctx); ok && scoped != manifestTenant {
+		return nil, &PipelineError{Stage: "load raw payloads", Err: fmt.Errorf("%w: %s", landing.ErrRunNotFound, runID)}
+	}
+	ctx = tenant.WithID(ctx, manifestTenant)
+
+	var window DateRange
+	if manifest.Since != "" {
+		window.From, err = time.Parse("2006-01-02", manifest.Since)
+		if err != nil {
+			return nil, &PipelineError{Stage: "load raw payloads", Err: err}
+		}
+	}
+	if manifest.Until != "" {
+		window.To, err = time.Parse("2006-01-02", manifest.Until)
+		if err != nil {
+			return nil, &PipelineError{Stage: "load raw payloads", Err: err}
+		}
+	}
+
+	adsBody, err := p.landing.GetBlob(manifest.Sources[SourceAds].SHA256)
+	if err != nil {
+		return nil, &PipelineError{Stage: "load raw payloads", Err: err}
+	}
+
+	crmBody, err := p.landing.GetBlob(manifest.Sources[SourceCrm].SHA256)
+	if err != nil {
+		return nil, &PipelineError{Stage: "load raw payloads", Err: err}
+	}
+
+	// Un replay que guarda escribe como una ejecución más del tenant. Toma el lock con un
+	// holder nuevo: con el run_id original podría heredar el de esa ejecución si sigue en curso.
+	if save {
+		var unlock func()
+		ctx, unlock, err = p.lock(ctx, newRunID())
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	ctx, span := tracing.Start(ctx, "pipeline replay", attribute.String("etl.run_id", runID))
+	tracing.Logger(ctx, p.logger).Infof("Replaying run %s", runID)
+	if save {
+		p.notifyStarted(ctx, runID)
+	}
+	result, err := p.process(ctx, runID, window, adsBody, crmBody, save)
+	tracing.End(span, err)
+	if save {
+		p.notifyFinished(ctx, runID, result, err)
+	}
+	return result, err
+}
+
+func (p *Pipeline) process(ctx context.Context, runID string, window DateRange, adsBody, crmBody []byte, save bool) (*RunResult, error) {
+	adsData, err := p.extractor.ParseAdsData(ctx, adsBody)
+	if err != nil {
+		return nil, &PipelineError{Stage: "extract ads data", Err: err}
+	}
+
+	crmData, err := p.extractor.ParseCrmData(ctx, crmBody)
+	if err != nil {
+		return nil, &PipelineError{Stage: "extract CRM data", Err: err}
+	}
+
+	monitoring.ETLRecords.WithLabelValues(SourceAds, "extracted").Add(float64(len(adsData.External.Ads.Performance)))
+	monitoring.ETLRecords.WithLabelValues(SourceCrm, "extracted").Add(float64(len(crmData.External.Crm.Opportunities)))
+
+	transformCtx, span := tracing.Start(ctx, "transform")
+	stage := p.startStage(ctx, runID, "transform")
+	metrics, err := p.transformer.TransformRange(transformCtx, adsData, crmData, window.From, window.To)
+	stage.done()
+	span.SetAttributes(attribute.Int("etl.records", len(metrics)))
+	tracing.End(span, err)
+	if err != nil {
+		return nil, &PipelineError{Stage: "transform data", Err: err}
+	}
+
+	for i := range metrics {
+		metrics[i].RunID = runID
+	}
+
+	result := &RunResult{RunID: runID, Tenant: tenant.FromContext(ctx), Count: len(metrics), Metrics: metrics}
+	if !window.From.IsZero() {
+		result.Since = window.From.Format("2006-01-02")
+	}
+	if !window.To.IsZero() {
+		result.Until = window.To.Format("2006-01-02")
+	}
+
+	if !save {
+		return result, nil
+	}
+
+	_, span = tracing.Start(ctx, "save", attribute.Int("etl.records", len(metrics)))
+	stage = p.startStage(ctx, runID, "save")
+	p.saveMu.Lock()
+	replaced, err := p.save(ctx, runID, metrics)
+	p.saveMu.Unlock()
+	stage.done()
+	tracing.End(span, err)
+	if err != nil {
+		return nil, &PipelineError{Stage: "save metrics", Err: err}
+	}
+
+	monitoring.ETLRecords.WithLabelValues("metrics", "saved").Add(float64(len(metrics)))
+
+	// Un replay que reproduce las mismas métricas no tiene datos nuevos que anunciar
+	if len(replaced) > 0 && reflect.DeepEqual(replaced, metrics) {
+		result.Unchanged = true
+		return result, nil
+	}
+
+	for _, hook := range p.hooks {
+		hook.AfterIngest(ctx, metrics)
+	}
+
+	return result, nil
+}
+
+// save guarda las métricas de runID sustituyendo las que ya tuviera, si el almacenamiento
+// lo permite, y devuelve las sustituidas.
+func (p *Pipeline) save(ctx context.Context, runID string, metrics []models.Metric) ([]models.Metric, error) {
+	if runs, ok := p.storage.(storage.RunStore); ok {
+		return runs.ReplaceRun(tenant.FromContext(ctx), runID, metrics)
+	}
+	return nil, p.storage.SaveMetrics(metrics)
+}
+
+func (p *Pipeline) land(ctx context.Context, runID string, window DateRange, adsBody, crmBody []byte) error {
+	manifest := landing.Manifest{
+		RunID:     runID,
+		Tenant:    tenant.FromContext(ctx),
+		CreatedAt: time.Now().UTC(),
+		Sources:   make(map[string]landing.Payload),
+	}
+	if !window.From.IsZero() {
+		manifest.Since = window.From.Format("2006-01-02")
+	}
+	if !window.To.IsZero() {
+		manifest.Until = window.To.Format("2006-01-02")
+	}
+
+	for source, body := range map[string][]byte{SourceAds: adsBody, SourceCrm: crmBody} {
+		payload, err := p.landing.PutBlob(body)
+		if err != nil {
+			return err
+		}
+		manifest.Sources[source] = payload
+	}
+
+	if err := p.landing.SaveManifest(manifest); err != nil {
+		return err
+	}
+
+	tracing.Logger(ctx, p.logger).Infof("Stored raw payloads for run %s", runID)
+	return nil
+}
+
+func (p *Pipeline) notifyStarted(ctx context.Context, runID string) {
+	for _, observer := range p.observers {
+		observer.RunStarted(ctx, runID)
+	}
+}
+
+func (p *Pipeline) notifyFinished(ctx context.Context, runID string, result *RunResult, err error) {
+	for _, observer := range p.observers {
+		if err != nil {
+			observer.RunFailed(ctx, runID, err)
+		} else {
+			observer.RunFinished(ctx, result)
+		}
+	}
+}
+
+type stageTimer struct {
+	pipeline *Pipeline
+	ctx      context.Context
+	runID    string
+	stage    string
+	start    time.Time
+}
+
+func (p *Pipeline) startStage(ctx context.Context, runID, stage string) stageTimer {
+	return stageTimer{pipeline: p, ctx: ctx, runID: runID, stage: stage, start: time.Now()}
+}
+
+// done registra la duración de la etapa y avisa a los observers, haya fallado o no.
+func (t stageTimer) done() {
+	elapsed := time.Since(t.start)
+	monitoring.ETLStageDuration.WithLabelValues(t.stage).Observe(elapsed.Seconds())
+
+	for _, observer := range t.pipeline.observers {
+		observer.StageCompleted(t.ctx, t.runID, t.stage, elapsed)
+	}
+}
+
+func rangeKey(tenantID string, window DateRange) string {
+	return tenantID + "|" + window.From.Format("2006-01-02") + "|" + window.To.Format("2006-01-02")
+}
+
+// heldLockKey marca en el contexto la clave del lock que ya tiene quien llama.
+type heldLockKey struct{}
+
+// LockTenant toma el lock de ingesta del tenant de ctx para holder. Las ejecuciones que
+// reciben el contexto devuelto corren bajo ese lock sin volver a tomarlo: así un backfill lo
+// toma una vez y sus tramos se ejecutan en paralelo. Hay que llamar a la función devuelta
+// para soltarlo.
+func (p *Pipeline) LockTenant(ctx context.Context, holder string) (context.Context, func(), error) {
+	return p.lock(ctx, holder)
+}
+
+// lock toma el lock de ingesta del tenant para runID y devuelve un contexto que se cancela
+// si el lock se pierde. Cubre las dos fuentes y cualquier ventana: dos ingestas del mismo
+// tenant escriben sobre las mismas métricas aunque pidan rangos distintos. Si otra
+// ejecución lo tiene, devuelve un *lock.HeldError con su run_id.
+func (p *Pipeline) lock(ctx context.Context, runID string) (context.Context, func(), error) {
+	if p.locker == nil {
+		return ctx, func() {}, nil
+	}
+
+	key := "ingest|" + tenant.FromContext(ctx)
+	if held, _ := ctx.Value(heldLockKey{}).(string); held == key {
+		return ctx, func() {}, nil
+	}
+	if err := p.locker.Acquire(key, runID, p.lockTTL); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, heldLockKey{}, key))
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		p.renewLock(ctx, cancel, key, runID)
+	}()
+
+	return ctx, func() {
+		cancel()
+		<-stopped
+		if err := p.locker.Release(key, runID); err != nil {
+			tracing.Logger(ctx, p.logger).Warnf("Failed to release ingestion lock %s: %v", key, err)
+		}
+	}, nil
+}
+
+func (p *Pipeline) renewLock(ctx context.Context, cancel context.CancelFunc, key, runID string) {
+	interval := p.lockTTL / 3
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.locker.Renew(key, runID, p.lockTTL); err != nil {
+				tracing.Logger(ctx, p.logger).Errorf("Lost ingestion lock %s, cancelling run: %v", key, err)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func newRunID() string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
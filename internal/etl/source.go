@@ -0,0 +1,129 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/utils"
+)
+
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// DefaultQuery es la plantilla de parámetros usada cuando una fuente no define la suya.
+var DefaultQuery = map[string]string{
+	"from": "{{from}}",
+	"to":   "{{to}}",
+}
+
+// SourceConfig describe cómo pedir datos a una fuente. Los valores de Query admiten
+// las variables {{from}}, {{to}} y {{account_id}}; los parámetros que quedan vacíos no se envían.
+type SourceConfig struct {
+	URL       string
+	AccountID string
+	Query     map[string]string
+	Headers   http.Header
+	Auth      utils.Authenticator
+}
+
+func (s SourceConfig) RequestURL(window DateRange) string {
+	parsed, err := url.Parse(s.URL)
+	if err != nil {
+		return s.URL
+	}
+
+	template := s.Query
+	if template == nil {
+		template = DefaultQuery
+	}
+
+	vars := map[string]string{
+		"{{account_id}}": s.AccountID,
+		"{{from}}":       "",
+		"{{to}}":         "",
+	}
+	if !window.From.IsZero() {
+		vars["{{from}}"] = window.From.Format("2006-01-02")
+	}
+	if !window.To.IsZero() {
+		vars["{{to}}"] = window.To.Format("2006-01-02")
+	}
+
+	query := parsed.Query()
+	for key, value := range template {
+		for name, replacement := range vars {
+			value = strings.ReplaceAll(value, name, replacement)
+		}
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+func (e *Extractor) requestOptions(ctx context.Context, source SourceConfig, sourceURL string) utils.RequestOptions {
+	options := utils.RequestOptions{
+		Headers: source.Headers,
+		Auth:    source.Auth,
+		Cache:   e.cache,
+	}
+
+	// Dos tenants pueden compartir URL con credenciales distintas
+	if tenantID := tenant.FromContext(ctx); tenantID != tenant.Default {
+		options.CacheKey = tenantID + "|" + sourceURL
+	}
+	return options
+}
+
+type tenantSources struct {
+	ads SourceConfig
+	crm SourceConfig
+}
+
+// AddTenant registra las fuentes de un tenant. Una URL vacía reutiliza la de las fuentes
+// por defecto, para APIs compartidas que sólo cambian de cuenta o credenciales.
+func (e *Extractor) AddTenant(id string, ads, crm SourceConfig) {
+	if ads.URL == "" {
+		ads.URL = e.ads.URL
+	}
+	if crm.URL == "" {
+		crm.URL = e.crm.URL
+	}
+	e.tenants[id] = tenantSources{ads: ads, crm: crm}
+}
+
+// Tenants devuelve los tenants configurados, incluido el de por defecto.
+func (e *Extractor) Tenants() []string {
+	ids := []string{tenant.Default}
+	for id := range e.tenants {
+		if id != tenant.Default {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids[1:])
+	return ids
+}
+
+func (e *Extractor) source(ctx context.Context, name string) (SourceConfig, error) {
+	tenantID := tenant.FromContext(ctx)
+
+	sources, ok := e.tenants[tenantID]
+	if !ok {
+		if tenantID != tenant.Default {
+			return SourceConfig{}, fmt.Errorf("%w: %s", ErrUnknownTenant, tenantID)
+		}
+		sources = tenantSources{ads: e.ads, crm: e.crm}
+	}
+
+	if name == SourceAds {
+		return sources.ads, nil
+	}
+	return sources.crm, nil
+}
@@ -1,44 +1,71 @@
 package etl
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
 	"github.com/sirupsen/logrus"
 )
 
+// CampaignLookup busca una campaña en el catálogo del tenant indicado.
+type CampaignLookup interface {
+	Lookup(tenantID, campaignID string) (models.Campaign, bool)
+}
+
 type Transformer struct {
-	logger *logrus.Logger
+	logger  *logrus.Logger
+	catalog CampaignLookup
 }
 
 func NewTransformer(logger *logrus.Logger) *Transformer {
 	return &Transformer{logger: logger}
 }
 
+func (t *Transformer) SetCatalog(catalog CampaignLookup) {
+	t.catalog = catalog
+}
+
 func (t *Transformer) Transform(adsData *models.AdsData, crmData *models.CrmData, since time.Time) ([]models.Metric, error) {
-	t.logger.Info("Transforming data")
+	return t.TransformRange(context.Background(), adsData, crmData, since, time.Time{})
+}
+
+// TransformRange descarta además los registros posteriores a until (inclusive hasta ese día).
+func (t *Transformer) TransformRange(ctx context.Context, adsData *models.AdsData, crmData *models.CrmData, since, until time.Time) ([]models.Metric, error) {
+	log := tracing.Logger(ctx, t.logger)
+	log.Info("Transforming data")
 
-	cleanedAds := t.cleanAdsData(adsData, since)
-	cleanedCrm := t.cleanCrmData(crmData, since)
+	cleanedAds := t.cleanAdsData(log, adsData, since, until)
+	cleanedCrm := t.cleanCrmData(log, crmData, since, until)
 
 	adsByUtm := t.groupAdsByUtm(cleanedAds)
 	crmByUtm := t.groupCrmByUtm(cleanedCrm)
 
 	metrics := t.joinAndCalculateMetrics(adsByUtm, crmByUtm)
 
-	t.logger.Infof("Transformed data into %d metric records", len(metrics))
+	tenantID := tenant.FromContext(ctx)
+	for i := range metrics {
+		metrics[i].TenantID = tenantID
+	}
+	t.enrichWithCampaigns(tenantID, metrics)
+
+	log.Infof("Transformed data into %d metric records", len(metrics))
 	return metrics, nil
 }
 
-func (t *Transformer) cleanAdsData(adsData *models.AdsData, since time.Time) []models.AdsPerformance {
+func (t *Transformer) cleanAdsData(log *logrus.Entry, adsData *models.AdsData, since, until time.Time) []models.AdsPerformance {
 	var cleaned []models.AdsPerformance
 
 	for _, record := range adsData.External.Ads.Performance {
 		// Validar fecha
 		recordDate, err := time.Parse("2006-01-02", record.Date)
 		if err != nil {
-			t.logger.Warnf("Invalid date in ads record: %s", record.Date)
+			log.Warnf("Invalid date in ads record: %s", record.Date)
+			monitoring.ETLRecords.WithLabelValues(SourceAds, "rejected").Inc()
 			continue
 		}
 
@@ -46,8 +73,13 @@ func (t *Transformer) cleanAdsData(adsData *models.AdsData, since time.Time) []m
 			continue
 		}
 
+		if !until.IsZero() && !recordDate.Before(until.AddDate(0, 0, 1)) {
+			continue
+		}
+
 		if record.CampaignID == "" || record.Channel == "" {
-			t.logger.Warnf("Missing required fields in ads record: %+v", record)
+			log.Warnf("Missing required fields in ads record: %+v", record)
+			monitoring.ETLRecords.WithLabelValues(SourceAds, "rejected").Inc()
 			continue
 		}
 
@@ -67,7 +99,7 @@ func (t *Transformer) cleanAdsData(adsData *models.AdsData, since time.Time) []m
 	return cleaned
 }
 
-func (t *Transformer) cleanCrmData(crmData *models.CrmData, since time.Time) []models.CrmOpportunity {
+func (t *Transformer) cleanCrmData(log *logrus.Entry, crmData *models.CrmData, since, until time.Time) []models.CrmOpportunity {
 	var cleaned []models.CrmOpportunity
 
 	for _, record := range crmData.External.Crm.Opportunities {
@@ -75,8 +107,13 @@ func (t *Transformer) cleanCrmData(crmData *models.CrmData, since time.Time) []m
 			continue
 		}
 
+		if !until.IsZero() && !record.CreatedAt.Before(until.AddDate(0, 0, 1)) {
+			continue
+		}
+
 		if record.OpportunityID == "" || record.Stage == "" {
-			t.logger.Warnf("Missing required fields in CRM record: %+v", record)
+			log.Warnf("Missing required fields in CRM record: %+v", record)
+			monitoring.ETLRecords.WithLabelValues(SourceCrm, "rejected").Inc()
 			continue
 		}
 
@@ -175,25 +212,26 @@ func (t *Transformer) calculateMetricsForUtm(
 	}
 
 	// Calcular métricas derivadas
-	if metric.Clicks > 0 {
-		metric.CPC = metric.Cost / float64(metric.Clicks)
-	}
+	metric.ComputeDerived()
 
-	if metric.Leads > 0 {
-		metric.CPA = metric.Cost / float64(metric.Leads)
-	}
+	return metric
+}
 
-	if metric.Leads > 0 {
-		metric.CvrLeadToOpp = float64(metric.Opportunities) / float64(metric.Leads)
+func (t *Transformer) enrichWithCampaigns(tenantID string, metrics []models.Metric) {
+	if t.catalog == nil {
+		return
 	}
 
-	if metric.Opportunities > 0 {
-		metric.CvrOppToWon = float64(metric.ClosedWon) / float64(metric.Opportunities)
-	}
+	for i := range metrics {
+		campaign, ok := t.catalog.Lookup(tenantID, metrics[i].CampaignID)
+		if !ok {
+			continue
+		}
 
-	if metric.Cost > 0 {
-		metric.Roas = metric.Revenue / metric.Cost
+		metrics[i].CampaignName = campaign.Name
+		metrics[i].Owner = campaign.Owner
+		metrics[i].Objective = campaign.Objective
+		metrics[i].BusinessUnit = campaign.BusinessUnit
+		metrics[i].Tags = campaign.Tags
 	}
-
-	return metric
 }
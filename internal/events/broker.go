@@ -0,0 +1,149 @@
+package events
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	IngestionStarted        = "ingestion.started"
+	IngestionStageCompleted = "ingestion.stage_completed"
+	IngestionFinished       = "ingestion.finished"
+	IngestionFailed         = "ingestion.failed"
+	MetricsSaved            = "metrics.saved"
+)
+
+// subscriberBuffer es la cola por suscriptor; si se llena, el suscriptor se descarta.
+const subscriberBuffer = 64
+
+type Event struct {
+	ID     uint64          `json:"id"`
+	Type   string          `json:"type"`
+	Tenant string          `json:"tenant_id"`
+	Time   time.Time       `json:"time"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// IDString es el id tal y como viaja en el campo id: de SSE.
+func (e Event) IDString() string {
+	return strconv.FormatUint(e.ID, 10)
+}
+
+type subscriber struct {
+	tenant string
+	events chan Event
+}
+
+// Broker reparte los eventos entre los suscriptores de cada tenant y guarda
+// los últimos en memoria para que un cliente reconectado recupere lo perdido.
+type Broker struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []Event
+	historySize int
+	subscribers map[*subscriber]struct{}
+	closed      bool
+}
+
+func NewBroker(historySize int) *Broker {
+	if historySize < 0 {
+		historySize = 0
+	}
+
+	return &Broker{
+		historySize: historySize,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Publish serializa data y entrega el evento sin bloquear: un suscriptor lento pierde la conexión, no frena la ingesta.
+func (b *Broker) Publish(eventType, tenantID string, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event := Event{ID: b.nextID, Type: eventType, Tenant: tenantID, Time: time.Now().UTC(), Data: payload}
+
+	if b.historySize > 0 {
+		if len(b.history) >= b.historySize {
+			b.history = append(b.history[:0], b.history[1:]...)
+		}
+		b.history = append(b.history, event)
+	}
+
+	for sub := range b.subscribers {
+		if sub.tenant != tenantID {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.events)
+		}
+	}
+
+	return event, nil
+}
+
+// Subscribe devuelve el canal de eventos del tenant y los eventos guardados posteriores a lastEventID.
+// El canal se cierra al cancelar o si el suscriptor no consume a tiempo.
+func (b *Broker) Subscribe(tenantID string, lastEventID uint64) (<-chan Event, []Event, func()) {
+	sub := &subscriber{tenant: tenantID, events: make(chan Event, subscriberBuffer)}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(sub.events)
+		return sub.events, nil, func() {}
+	}
+
+	var backlog []Event
+	if lastEventID > 0 {
+		for _, event := range b.history {
+			if event.ID > lastEventID && event.Tenant == tenantID {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[sub]; ok {
+			delete(b.subscribers, sub)
+			close(sub.events)
+		}
+	}
+
+	return sub.events, backlog, cancel
+}
+
+// Close cierra todos los streams abiertos; las suscripciones posteriores reciben un canal ya cerrado.
+// Se usa al apagar el servidor, que si no esperaría a que los clientes se desconectasen.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Subscribers devuelve el número de conexiones abiertas.
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
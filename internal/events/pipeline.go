@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+// metricsBatchSize limita el tamaño de cada evento metrics.saved.
+const metricsBatchSize = 500
+
+type RunEvent struct {
+	RunID string `json:"run_id"`
+}
+
+type StageEvent struct {
+	RunID      string  `json:"run_id"`
+	Stage      string  `json:"stage"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+type FailedEvent struct {
+	RunID string `json:"run_id"`
+	Error string `json:"error"`
+}
+
+type MetricsEvent struct {
+	Count   int             `json:"count"`
+	Metrics []models.Metric `json:"metrics"`
+}
+
+// PipelineEvents publica en el broker el ciclo de vida de la ingesta y los lotes guardados.
+// Implementa etl.RunObserver y etl.IngestHook.
+type PipelineEvents struct {
+	broker *Broker
+	logger *logrus.Logger
+}
+
+func NewPipelineEvents(broker *Broker, logger *logrus.Logger) *PipelineEvents {
+	return &PipelineEvents{broker: broker, logger: logger}
+}
+
+func (p *PipelineEvents) RunStarted(ctx context.Context, runID string) {
+	p.publish(ctx, IngestionStarted, RunEvent{RunID: runID})
+}
+
+func (p *PipelineEvents) StageCompleted(ctx context.Context, runID, stage string, duration time.Duration) {
+	p.publish(ctx, IngestionStageCompleted, StageEvent{
+		RunID:      runID,
+		Stage:      stage,
+		DurationMs: float64(duration.Microseconds()) / 1000,
+	})
+}
+
+func (p *PipelineEvents) RunFinished(ctx context.Context, result *etl.RunResult) {
+	p.publish(ctx, IngestionFinished, result)
+}
+
+func (p *PipelineEvents) RunFailed(ctx context.Context, runID string, err error) {
+	p.publish(ctx, IngestionFailed, FailedEvent{RunID: runID, Error: err.Error()})
+}
+
+func (p *PipelineEvents) AfterIngest(ctx context.Context, metrics []models.Metric) {
+	for start := 0; start < len(metrics); start += metricsBatchSize {
+		end := start + metricsBatchSize
+		if end > len(metrics) {
+			end = len(metrics)
+		}
+		p.publish(ctx, MetricsSaved, MetricsEvent{Count: end - start, Metrics: metrics[start:end]})
+	}
+}
+
+func (p *PipelineEvents) publish(ctx context.Context, eventType string, data interface{}) {
+	if _, err := p.broker.Publish(eventType, tenant.FromContext(ctx), data); err != nil {
+		p.logger.Errorf("Failed to publish %s event: %v", eventType, err)
+	}
+}
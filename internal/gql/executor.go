@@ -0,0 +1,172 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+var (
+	ErrQueryTooDeep    = errors.New("query exceeds maximum depth")
+	ErrQueryTooComplex = errors.New("query exceeds maximum complexity")
+)
+
+// paginatedFields multiplican el coste de su selección por su limit.
+var paginatedFields = map[string]bool{"metrics": true, "metricGroups": true}
+
+// Limits acota cada consulta antes de ejecutarla. Un valor <= 0 desactiva el límite.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type Executor struct {
+	schema graphql.Schema
+	limits Limits
+}
+
+func NewExecutor(store storage.Storage, limits Limits) (*Executor, error) {
+	schema, err := newSchema(store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build GraphQL schema: %v", err)
+	}
+
+	return &Executor{schema: schema, limits: limits}, nil
+}
+
+// Execute comprueba profundidad y complejidad y resuelve la consulta. Los errores de
+// sintaxis o de resolución van en el resultado, como manda GraphQL; sólo los de límites se devuelven aparte.
+func (e *Executor) Execute(ctx context.Context, request Request) (*graphql.Result, error) {
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(request.Query)})})
+	if err == nil {
+		if err := e.checkLimits(document, request); err != nil {
+			return nil, err
+		}
+	}
+
+	return graphql.Do(graphql.Params{
+		Schema:         e.schema,
+		RequestString:  request.Query,
+		OperationName:  request.OperationName,
+		VariableValues: request.Variables,
+		Context:        ctx,
+	}), nil
+}
+
+func (e *Executor) checkLimits(document *ast.Document, request Request) error {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if request.OperationName != "" && (operation.Name == nil || operation.Name.Value != request.OperationName) {
+			continue
+		}
+
+		cost := costWalker{fragments: fragments, variables: request.Variables, visiting: make(map[string]bool)}
+		depth, complexity := cost.selectionSet(operation.SelectionSet)
+
+		if e.limits.MaxDepth > 0 && depth > e.limits.MaxDepth {
+			return fmt.Errorf("%w: %d > %d", ErrQueryTooDeep, depth, e.limits.MaxDepth)
+		}
+		if e.limits.MaxComplexity > 0 && complexity > e.limits.MaxComplexity {
+			return fmt.Errorf("%w: %d > %d", ErrQueryTooComplex, complexity, e.limits.MaxComplexity)
+		}
+	}
+
+	return nil
+}
+
+// costWalker calcula la profundidad y el coste de una selección: cada campo cuesta 1
+// más el coste de sus hijos, multiplicado por limit en los campos paginados.
+type costWalker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	visiting  map[string]bool
+}
+
+func (c costWalker) selectionSet(set *ast.SelectionSet) (int, int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	maxDepth, total := 0, 0
+	for _, selection := range set.Selections {
+		var depth, cost int
+
+		switch node := selection.(type) {
+		case *ast.Field:
+			childDepth, childCost := c.selectionSet(node.SelectionSet)
+			depth = childDepth + 1
+			cost = 1 + childCost*c.multiplier(node)
+		case *ast.InlineFragment:
+			depth, cost = c.selectionSet(node.SelectionSet)
+		case *ast.FragmentSpread:
+			name := node.Name.Value
+			fragment, ok := c.fragments[name]
+			// Un fragmento cíclico lo rechaza la validación de GraphQL; aquí sólo se evita el bucle
+			if !ok || c.visiting[name] {
+				continue
+			}
+			c.visiting[name] = true
+			depth, cost = c.selectionSet(fragment.SelectionSet)
+			delete(c.visiting, name)
+		}
+
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		total += cost
+	}
+
+	return maxDepth, total
+}
+
+func (c costWalker) multiplier(field *ast.Field) int {
+	if !paginatedFields[field.Name.Value] {
+		return 1
+	}
+
+	limit := defaultLimit
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "limit" {
+			continue
+		}
+
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			fmt.Sscan(value.Value, &limit)
+		case *ast.Variable:
+			switch v := c.variables[value.Name.Value].(type) {
+			case float64:
+				limit = int(v)
+			case int:
+				limit = v
+			}
+		}
+	}
+
+	if limit < 1 {
+		limit = 1
+	}
+	return limit
+}
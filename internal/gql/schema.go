@@ -0,0 +1,393 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 1000
+)
+
+// metricField describe un campo de models.Metric expuesto en GraphQL.
+// Los campos aggregated también existen en MetricGroup.
+type metricField struct {
+	name       string
+	enum       string
+	output     graphql.Output
+	aggregated bool
+	value      func(models.Metric) interface{}
+}
+
+var metricFields = []metricField{
+	{"tenantId", "TENANT_ID", graphql.String, false, func(m models.Metric) interface{} { return m.TenantID }},
+	{"date", "DATE", graphql.NewNonNull(graphql.String), false, func(m models.Metric) interface{} { return m.Date }},
+	{"channel", "CHANNEL", graphql.NewNonNull(graphql.String), false, func(m models.Metric) interface{} { return m.Channel }},
+	{"campaignId", "CAMPAIGN_ID", graphql.NewNonNull(graphql.String), false, func(m models.Metric) interface{} { return m.CampaignID }},
+	{"campaignName", "CAMPAIGN_NAME", graphql.String, false, func(m models.Metric) interface{} { return m.CampaignName }},
+	{"owner", "OWNER", graphql.String, false, func(m models.Metric) interface{} { return m.Owner }},
+	{"objective", "OBJECTIVE", graphql.String, false, func(m models.Metric) interface{} { return m.Objective }},
+	{"businessUnit", "BUSINESS_UNIT", graphql.String, false, func(m models.Metric) interface{} { return m.BusinessUnit }},
+	{"tags", "", graphql.NewList(graphql.NewNonNull(graphql.String)), false, func(m models.Metric) interface{} { return m.Tags }},
+	{"utmCampaign", "UTM_CAMPAIGN", graphql.String, false, func(m models.Metric) interface{} { return m.UtmCampaign }},
+	{"utmSource", "UTM_SOURCE", graphql.String, false, func(m models.Metric) interface{} { return m.UtmSource }},
+	{"utmMedium", "UTM_MEDIUM", graphql.String, false, func(m models.Metric) interface{} { return m.UtmMedium }},
+	{"clicks", "CLICKS", graphql.NewNonNull(graphql.Int), true, func(m models.Metric) interface{} { return m.Clicks }},
+	{"impressions", "IMPRESSIONS", graphql.NewNonNull(graphql.Int), true, func(m models.Metric) interface{} { return m.Impressions }},
+	{"cost", "COST", graphql.NewNonNull(graphql.Float), true, func(m models.Metric) interface{} { return m.Cost }},
+	{"leads", "LEADS", graphql.NewNonNull(graphql.Int), true, func(m models.Metric) interface{} { return m.Leads }},
+	{"opportunities", "OPPORTUNITIES", graphql.NewNonNull(graphql.Int), true, func(m models.Metric) interface{} { return m.Opportunities }},
+	{"closedWon", "CLOSED_WON", graphql.NewNonNull(graphql.Int), true, func(m models.Metric) interface{} { return m.ClosedWon }},
+	{"revenue", "REVENUE", graphql.NewNonNull(graphql.Float), true, func(m models.Metric) interface{} { return m.Revenue }},
+	{"cpc", "CPC", graphql.NewNonNull(graphql.Float), true, func(m models.Metric) interface{} { return m.CPC }},
+	{"cpa", "CPA", graphql.NewNonNull(graphql.Float), true, func(m models.Metric) interface{} { return m.CPA }},
+	{"cvrLeadToOpp", "CVR_LEAD_TO_OPP", graphql.NewNonNull(graphql.Float), true, func(m models.Metric) interface{} { return m.CvrLeadToOpp }},
+	{"cvrOppToWon", "CVR_OPP_TO_WON", graphql.NewNonNull(graphql.Float), true, func(m models.Metric) interface{} { return m.CvrOppToWon }},
+	{"roas", "ROAS", graphql.NewNonNull(graphql.Float), true, func(m models.Metric) interface{} { return m.Roas }},
+}
+
+// groupByValues traduce el enum GroupBy a las dimensiones de storage.Aggregate.
+var groupByValues = map[string]string{
+	"CAMPAIGN_NAME": "campaign_name",
+	"CAMPAIGN_ID":   "campaign_id",
+	"CHANNEL":       "channel",
+	"OWNER":         "owner",
+	"OBJECTIVE":     "objective",
+	"BUSINESS_UNIT": "business_unit",
+	"UTM_CAMPAIGN":  "utm_campaign",
+	"TAG":           "tag",
+}
+
+// groupKeyField ordena los grupos por su clave.
+const groupKeyField = "KEY"
+
+func newSchema(store storage.Storage) (graphql.Schema, error) {
+	metricType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Metric",
+		Description: "Métricas diarias de una campaña en un canal.",
+		Fields:      metricObjectFields(false, func(source interface{}) models.Metric { return source.(models.Metric) }),
+	})
+
+	groupFields := metricObjectFields(true, func(source interface{}) models.Metric { return source.(models.MetricGroup).Metric })
+	groupFields["groupBy"] = &graphql.Field{
+		Type:    graphql.NewNonNull(graphql.String),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(models.MetricGroup).GroupBy, nil },
+	}
+	groupFields["key"] = &graphql.Field{
+		Type:    graphql.NewNonNull(graphql.String),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(models.MetricGroup).Key, nil },
+	}
+	groupType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "MetricGroup",
+		Description: "Métricas agregadas por una dimensión, con los ratios recalculados.",
+		Fields:      groupFields,
+	})
+
+	directionEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "SortDirection",
+		Values: graphql.EnumValueConfigMap{
+			"ASC":  &graphql.EnumValueConfig{Value: "ASC"},
+			"DESC": &graphql.EnumValueConfig{Value: "DESC"},
+		},
+	})
+
+	metricSortValues := graphql.EnumValueConfigMap{}
+	groupSortValues := graphql.EnumValueConfigMap{groupKeyField: &graphql.EnumValueConfig{Value: groupKeyField}}
+	for _, field := range metricFields {
+		if field.enum == "" {
+			continue
+		}
+		metricSortValues[field.enum] = &graphql.EnumValueConfig{Value: field.enum}
+		if field.aggregated {
+			groupSortValues[field.enum] = &graphql.EnumValueConfig{Value: field.enum}
+		}
+	}
+
+	groupByConfig := graphql.EnumValueConfigMap{}
+	for name, value := range groupByValues {
+		groupByConfig[name] = &graphql.EnumValueConfig{Value: value}
+	}
+	groupByEnum := graphql.NewEnum(graphql.EnumConfig{Name: "GroupBy", Values: groupByConfig})
+
+	filterInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "MetricFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"from":         &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "YYYY-MM-DD, inclusive"},
+			"to":           &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "YYYY-MM-DD, inclusive"},
+			"channel":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"campaignId":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"campaignName": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"owner":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"objective":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"businessUnit": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"utmCampaign":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"tag":          &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	sortInput := func(name string, values graphql.EnumValueConfigMap) *graphql.InputObject {
+		return graphql.NewInputObject(graphql.InputObjectConfig{
+			Name: name,
+			Fields: graphql.InputObjectConfigFieldMap{
+				"field":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.NewEnum(graphql.EnumConfig{Name: name + "Field", Values: values}))},
+				"direction": &graphql.InputObjectFieldConfig{Type: directionEnum, DefaultValue: "ASC"},
+			},
+		})
+	}
+
+	page := func(name string, item graphql.Output) *graphql.Object {
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name: name,
+			Fields: graphql.Fields{
+				"total":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"limit":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"offset": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"items":  &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(item)))},
+			},
+		})
+	}
+
+	pageArgs := func(sort *graphql.InputObject) graphql.FieldConfigArgument {
+		return graphql.FieldConfigArgument{
+			"filter": &graphql.ArgumentConfig{Type: filterInput},
+			"sort":   &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(sort))},
+			"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+			"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+		}
+	}
+
+	metricsArgs := pageArgs(sortInput("MetricSort", metricSortValues))
+	groupsArgs := pageArgs(sortInput("MetricGroupSort", groupSortValues))
+	groupsArgs["groupBy"] = &graphql.ArgumentConfig{Type: groupByEnum, DefaultValue: "campaign_name"}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"metrics": &graphql.Field{
+				Type:        graphql.NewNonNull(page("MetricPage", metricType)),
+				Description: "Métricas diarias del tenant de la petición.",
+				Args:        metricsArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					metrics, err := loadMetrics(p.Context, store, p.Args["filter"])
+					if err != nil {
+						return nil, err
+					}
+
+					sortBy(p.Args["sort"], metrics, func(m models.Metric, field string) interface{} {
+						return fieldValue(m, field)
+					})
+					return paginate(metrics, p.Args)
+				},
+			},
+			"metricGroups": &graphql.Field{
+				Type:        graphql.NewNonNull(page("MetricGroupPage", groupType)),
+				Description: "Métricas del tenant de la petición agregadas por groupBy.",
+				Args:        groupsArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					metrics, err := loadMetrics(p.Context, store, p.Args["filter"])
+					if err != nil {
+						return nil, err
+					}
+
+					groups, err := storage.Aggregate(metrics, p.Args["groupBy"].(string))
+					if err != nil {
+						return nil, err
+					}
+
+					sortBy(p.Args["sort"], groups, func(g models.MetricGroup, field string) interface{} {
+						if field == groupKeyField {
+							return g.Key
+						}
+						return fieldValue(g.Metric, field)
+					})
+					return paginate(groups, p.Args)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+func metricObjectFields(aggregatedOnly bool, metricOf func(interface{}) models.Metric) graphql.Fields {
+	fields := graphql.Fields{}
+	for _, field := range metricFields {
+		if aggregatedOnly && !field.aggregated {
+			continue
+		}
+
+		value := field.value
+		fields[field.name] = &graphql.Field{
+			Type: field.output,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return value(metricOf(p.Source)), nil
+			},
+		}
+	}
+	return fields
+}
+
+func fieldValue(metric models.Metric, enum string) interface{} {
+	for _, field := range metricFields {
+		if field.enum == enum {
+			return field.value(metric)
+		}
+	}
+	return nil
+}
+
+// loadMetrics lee el rango del almacenamiento y aplica el tenant del contexto y el resto de filtros.
+func loadMetrics(ctx context.Context, store storage.Storage, rawFilter interface{}) ([]models.Metric, error) {
+	filter, _ := rawFilter.(map[string]interface{})
+	text := func(name string) string {
+		value, _ := filter[name].(string)
+		return value
+	}
+
+	for _, name := range []string{"from", "to"} {
+		if value := text(name); value != "" {
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return nil, fmt.Errorf("filter.%s must be a date in YYYY-MM-DD format", name)
+			}
+		}
+	}
+
+	stored, err := store.GetMetricsInRange(text("from"), text("to"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metrics: %v", err)
+	}
+
+	tenantID := tenant.FromContext(ctx)
+	equals := map[string]func(models.Metric) string{
+		"channel":      func(m models.Metric) string { return m.Channel },
+		"campaignId":   func(m models.Metric) string { return m.CampaignID },
+		"campaignName": func(m models.Metric) string { return m.CampaignName },
+		"owner":        func(m models.Metric) string { return m.Owner },
+		"objective":    func(m models.Metric) string { return m.Objective },
+		"businessUnit": func(m models.Metric) string { return m.BusinessUnit },
+		"utmCampaign":  func(m models.Metric) string { return m.UtmCampaign },
+	}
+
+	metrics := make([]models.Metric, 0, len(stored))
+	for _, metric := range stored {
+		if metric.TenantID != tenantID && !(metric.TenantID == "" && tenantID == tenant.Default) {
+			continue
+		}
+
+		matches := true
+		for name, valueOf := range equals {
+			if wanted := text(name); wanted != "" && valueOf(metric) != wanted {
+				matches = false
+				break
+			}
+		}
+		if matches && text("tag") != "" {
+			matches = hasTag(metric, text("tag"))
+		}
+
+		if matches {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	return metrics, nil
+}
+
+func hasTag(metric models.Metric, wanted string) bool {
+	for _, tag := range metric.Tags {
+		if tag == wanted {
+			return true
+		}
+	}
+	return false
+}
+
+// sortBy ordena de forma estable por varias claves; sin criterios se mantiene el orden del almacenamiento.
+func sortBy[T any](rawSort interface{}, items []T, valueOf func(T, string) interface{}) {
+	criteria, _ := rawSort.([]interface{})
+	if len(criteria) == 0 {
+		return
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, raw := range criteria {
+			criterion := raw.(map[string]interface{})
+			field := criterion["field"].(string)
+
+			cmp := compare(valueOf(items[i], field), valueOf(items[j], field))
+			if cmp == 0 {
+				continue
+			}
+			if criterion["direction"] == "DESC" {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+func compare(a, b interface{}) int {
+	switch av := a.(type) {
+	case string:
+		bv := b.(string)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	case int:
+		return compareFloat(float64(av), float64(b.(int)))
+	case float64:
+		return compareFloat(av, b.(float64))
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func paginate[T any](items []T, args map[string]interface{}) (interface{}, error) {
+	limit, _ := args["limit"].(int)
+	offset, _ := args["offset"].(int)
+
+	if limit < 1 || limit > maxLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must be >= 0")
+	}
+
+	start := offset
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+
+	return map[string]interface{}{
+		"total":  len(items),
+		"limit":  limit,
+		"offset": offset,
+		"items":  items[start:end],
+	}, nil
+}
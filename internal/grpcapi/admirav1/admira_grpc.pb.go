@@ -0,0 +1,495 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: admira/v1/admira.proto
+
+package admirav1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	MetricsService_GetMetricsByChannel_FullMethodName = "/admira.v1.MetricsService/GetMetricsByChannel"
+	MetricsService_GetMetricsByFunnel_FullMethodName  = "/admira.v1.MetricsService/GetMetricsByFunnel"
+	MetricsService_GetMetricsGrouped_FullMethodName   = "/admira.v1.MetricsService/GetMetricsGrouped"
+)
+
+// MetricsServiceClient is the client API for MetricsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MetricsServiceClient interface {
+	GetMetricsByChannel(ctx context.Context, in *MetricsRequest, opts ...grpc.CallOption) (*MetricsResponse, error)
+	GetMetricsByFunnel(ctx context.Context, in *MetricsRequest, opts ...grpc.CallOption) (*MetricsResponse, error)
+	GetMetricsGrouped(ctx context.Context, in *MetricsRequest, opts ...grpc.CallOption) (*MetricGroupsResponse, error)
+}
+
+type metricsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMetricsServiceClient(cc grpc.ClientConnInterface) MetricsServiceClient {
+	return &metricsServiceClient{cc}
+}
+
+func (c *metricsServiceClient) GetMetricsByChannel(ctx context.Context, in *MetricsRequest, opts ...grpc.CallOption) (*MetricsResponse, error) {
+	out := new(MetricsResponse)
+	err := c.cc.Invoke(ctx, MetricsService_GetMetricsByChannel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsServiceClient) GetMetricsByFunnel(ctx context.Context, in *MetricsRequest, opts ...grpc.CallOption) (*MetricsResponse, error) {
+	out := new(MetricsResponse)
+	err := c.cc.Invoke(ctx, MetricsService_GetMetricsByFunnel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsServiceClient) GetMetricsGrouped(ctx context.Context, in *MetricsRequest, opts ...grpc.CallOption) (*MetricGroupsResponse, error) {
+	out := new(MetricGroupsResponse)
+	err := c.cc.Invoke(ctx, MetricsService_GetMetricsGrouped_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MetricsServiceServer is the server API for MetricsService service.
+// All implementations must embed UnimplementedMetricsServiceServer
+// for forward compatibility
+type MetricsServiceServer interface {
+	GetMetricsByChannel(context.Context, *MetricsRequest) (*MetricsResponse, error)
+	GetMetricsByFunnel(context.Context, *MetricsRequest) (*MetricsResponse, error)
+	GetMetricsGrouped(context.Context, *MetricsRequest) (*MetricGroupsResponse, error)
+	mustEmbedUnimplementedMetricsServiceServer()
+}
+
+// UnimplementedMetricsServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedMetricsServiceServer struct {
+}
+
+func (UnimplementedMetricsServiceServer) GetMetricsByChannel(context.Context, *MetricsRequest) (*MetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetricsByChannel not implemented")
+}
+func (UnimplementedMetricsServiceServer) GetMetricsByFunnel(context.Context, *MetricsRequest) (*MetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetricsByFunnel not implemented")
+}
+func (UnimplementedMetricsServiceServer) GetMetricsGrouped(context.Context, *MetricsRequest) (*MetricGroupsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetricsGrouped not implemented")
+}
+func (UnimplementedMetricsServiceServer) mustEmbedUnimplementedMetricsServiceServer() {}
+
+// UnsafeMetricsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MetricsServiceServer will
+// result in compilation errors.
+type UnsafeMetricsServiceServer interface {
+	mustEmbedUnimplementedMetricsServiceServer()
+}
+
+func RegisterMetricsServiceServer(s grpc.ServiceRegistrar, srv MetricsServiceServer) {
+	s.RegisterService(&MetricsService_ServiceDesc, srv)
+}
+
+func _MetricsService_GetMetricsByChannel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).GetMetricsByChannel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_GetMetricsByChannel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).GetMetricsByChannel(ctx, req.(*MetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetricsService_GetMetricsByFunnel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).GetMetricsByFunnel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_GetMetricsByFunnel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).GetMetricsByFunnel(ctx, req.(*MetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetricsService_GetMetricsGrouped_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).GetMetricsGrouped(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_GetMetricsGrouped_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).GetMetricsGrouped(ctx, req.(*MetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MetricsService_ServiceDesc is the grpc.ServiceDesc for MetricsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MetricsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "admira.v1.MetricsService",
+	HandlerType: (*MetricsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetMetricsByChannel",
+			Handler:    _MetricsService_GetMetricsByChannel_Handler,
+		},
+		{
+			MethodName: "GetMetricsByFunnel",
+			Handler:    _MetricsService_GetMetricsByFunnel_Handler,
+		},
+		{
+			MethodName: "GetMetricsGrouped",
+			Handler:    _MetricsService_GetMetricsGrouped_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admira/v1/admira.proto",
+}
+
+const (
+	IngestionService_RunIngestion_FullMethodName       = "/admira.v1.IngestionService/RunIngestion"
+	IngestionService_ListRuns_FullMethodName           = "/admira.v1.IngestionService/ListRuns"
+	IngestionService_ReplayRun_FullMethodName          = "/admira.v1.IngestionService/ReplayRun"
+	IngestionService_CreateIngestionJob_FullMethodName = "/admira.v1.IngestionService/CreateIngestionJob"
+	IngestionService_GetIngestionJob_FullMethodName    = "/admira.v1.IngestionService/GetIngestionJob"
+	IngestionService_ListIngestionJobs_FullMethodName  = "/admira.v1.IngestionService/ListIngestionJobs"
+	IngestionService_ResumeIngestionJob_FullMethodName = "/admira.v1.IngestionService/ResumeIngestionJob"
+)
+
+// IngestionServiceClient is the client API for IngestionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type IngestionServiceClient interface {
+	RunIngestion(ctx context.Context, in *RunIngestionRequest, opts ...grpc.CallOption) (*IngestionRun, error)
+	ListRuns(ctx context.Context, in *ListRunsRequest, opts ...grpc.CallOption) (*ListRunsResponse, error)
+	ReplayRun(ctx context.Context, in *ReplayRunRequest, opts ...grpc.CallOption) (*IngestionRun, error)
+	CreateIngestionJob(ctx context.Context, in *CreateIngestionJobRequest, opts ...grpc.CallOption) (*IngestionJob, error)
+	GetIngestionJob(ctx context.Context, in *IngestionJobRequest, opts ...grpc.CallOption) (*IngestionJob, error)
+	ListIngestionJobs(ctx context.Context, in *ListIngestionJobsRequest, opts ...grpc.CallOption) (*ListIngestionJobsResponse, error)
+	ResumeIngestionJob(ctx context.Context, in *IngestionJobRequest, opts ...grpc.CallOption) (*IngestionJob, error)
+}
+
+type ingestionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIngestionServiceClient(cc grpc.ClientConnInterface) IngestionServiceClient {
+	return &ingestionServiceClient{cc}
+}
+
+func (c *ingestionServiceClient) RunIngestion(ctx context.Context, in *RunIngestionRequest, opts ...grpc.CallOption) (*IngestionRun, error) {
+	out := new(IngestionRun)
+	err := c.cc.Invoke(ctx, IngestionService_RunIngestion_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestionServiceClient) ListRuns(ctx context.Context, in *ListRunsRequest, opts ...grpc.CallOption) (*ListRunsResponse, error) {
+	out := new(ListRunsResponse)
+	err := c.cc.Invoke(ctx, IngestionService_ListRuns_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestionServiceClient) ReplayRun(ctx context.Context, in *ReplayRunRequest, opts ...grpc.CallOption) (*IngestionRun, error) {
+	out := new(IngestionRun)
+	err := c.cc.Invoke(ctx, IngestionService_ReplayRun_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestionServiceClient) CreateIngestionJob(ctx context.Context, in *CreateIngestionJobRequest, opts ...grpc.CallOption) (*IngestionJob, error) {
+	out := new(IngestionJob)
+	err := c.cc.Invoke(ctx, IngestionService_CreateIngestionJob_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestionServiceClient) GetIngestionJob(ctx context.Context, in *IngestionJobRequest, opts ...grpc.CallOption) (*IngestionJob, error) {
+	out := new(IngestionJob)
+	err := c.cc.Invoke(ctx, IngestionService_GetIngestionJob_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestionServiceClient) ListIngestionJobs(ctx context.Context, in *ListIngestionJobsRequest, opts ...grpc.CallOption) (*ListIngestionJobsResponse, error) {
+	out := new(ListIngestionJobsResponse)
+	err := c.cc.Invoke(ctx, IngestionService_ListIngestionJobs_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestionServiceClient) ResumeIngestionJob(ctx context.Context, in *IngestionJobRequest, opts ...grpc.CallOption) (*IngestionJob, error) {
+	out := new(IngestionJob)
+	err := c.cc.Invoke(ctx, IngestionService_ResumeIngestionJob_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IngestionServiceServer is the server API for IngestionService service.
+// All implementations must embed UnimplementedIngestionServiceServer
+// for forward compatibility
+type IngestionServiceServer interface {
+	RunIngestion(context.Context, *RunIngestionRequest) (*IngestionRun, error)
+	ListRuns(context.Context, *ListRunsRequest) (*ListRunsResponse, error)
+	ReplayRun(context.Context, *ReplayRunRequest) (*IngestionRun, error)
+	CreateIngestionJob(context.Context, *CreateIngestionJobRequest) (*IngestionJob, error)
+	GetIngestionJob(context.Context, *IngestionJobRequest) (*IngestionJob, error)
+	ListIngestionJobs(context.Context, *ListIngestionJobsRequest) (*ListIngestionJobsResponse, error)
+	ResumeIngestionJob(context.Context, *IngestionJobRequest) (*IngestionJob, error)
+	mustEmbedUnimplementedIngestionServiceServer()
+}
+
+// UnimplementedIngestionServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedIngestionServiceServer struct {
+}
+
+func (UnimplementedIngestionServiceServer) RunIngestion(context.Context, *RunIngestionRequest) (*IngestionRun, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunIngestion not implemented")
+}
+func (UnimplementedIngestionServiceServer) ListRuns(context.Context, *ListRunsRequest) (*ListRunsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRuns not implemented")
+}
+func (UnimplementedIngestionServiceServer) ReplayRun(context.Context, *ReplayRunRequest) (*IngestionRun, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplayRun not implemented")
+}
+func (UnimplementedIngestionServiceServer) CreateIngestionJob(context.Context, *CreateIngestionJobRequest) (*IngestionJob, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateIngestionJob not implemented")
+}
+func (UnimplementedIngestionServiceServer) GetIngestionJob(context.Context, *IngestionJobRequest) (*IngestionJob, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetIngestionJob not implemented")
+}
+func (UnimplementedIngestionServiceServer) ListIngestionJobs(context.Context, *ListIngestionJobsRequest) (*ListIngestionJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListIngestionJobs not implemented")
+}
+func (UnimplementedIngestionServiceServer) ResumeIngestionJob(context.Context, *IngestionJobRequest) (*IngestionJob, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeIngestionJob not implemented")
+}
+func (UnimplementedIngestionServiceServer) mustEmbedUnimplementedIngestionServiceServer() {}
+
+// UnsafeIngestionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IngestionServiceServer will
+// result in compilation errors.
+type UnsafeIngestionServiceServer interface {
+	mustEmbedUnimplementedIngestionServiceServer()
+}
+
+func RegisterIngestionServiceServer(s grpc.ServiceRegistrar, srv IngestionServiceServer) {
+	s.RegisterService(&IngestionService_ServiceDesc, srv)
+}
+
+func _IngestionService_RunIngestion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunIngestionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestionServiceServer).RunIngestion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestionService_RunIngestion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestionServiceServer).RunIngestion(ctx, req.(*RunIngestionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IngestionService_ListRuns_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRunsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestionServiceServer).ListRuns(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestionService_ListRuns_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestionServiceServer).ListRuns(ctx, req.(*ListRunsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IngestionService_ReplayRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplayRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestionServiceServer).ReplayRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestionService_ReplayRun_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestionServiceServer).ReplayRun(ctx, req.(*ReplayRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IngestionService_CreateIngestionJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateIngestionJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestionServiceServer).CreateIngestionJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestionService_CreateIngestionJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestionServiceServer).CreateIngestionJob(ctx, req.(*CreateIngestionJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IngestionService_GetIngestionJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IngestionJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestionServiceServer).GetIngestionJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestionService_GetIngestionJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestionServiceServer).GetIngestionJob(ctx, req.(*IngestionJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IngestionService_ListIngestionJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListIngestionJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestionServiceServer).ListIngestionJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestionService_ListIngestionJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestionServiceServer).ListIngestionJobs(ctx, req.(*ListIngestionJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IngestionService_ResumeIngestionJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IngestionJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestionServiceServer).ResumeIngestionJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestionService_ResumeIngestionJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestionServiceServer).ResumeIngestionJob(ctx, req.(*IngestionJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// IngestionService_ServiceDesc is the grpc.ServiceDesc for IngestionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var IngestionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "admira.v1.IngestionService",
+	HandlerType: (*IngestionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RunIngestion",
+			Handler:    _IngestionService_RunIngestion_Handler,
+		},
+		{
+			MethodName: "ListRuns",
+			Handler:    _IngestionService_ListRuns_Handler,
+		},
+		{
+			MethodName: "ReplayRun",
+			Handler:    _IngestionService_ReplayRun_Handler,
+		},
+		{
+			MethodName: "CreateIngestionJob",
+			Handler:    _IngestionService_CreateIngestionJob_Handler,
+		},
+		{
+			MethodName: "GetIngestionJob",
+			Handler:    _IngestionService_GetIngestionJob_Handler,
+		},
+		{
+			MethodName: "ListIngestionJobs",
+			Handler:    _IngestionService_ListIngestionJobs_Handler,
+		},
+		{
+			MethodName: "ResumeIngestionJob",
+			Handler:    _IngestionService_ResumeIngestionJob_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admira/v1/admira.proto",
+}
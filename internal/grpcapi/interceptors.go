@@ -0,0 +1,111 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/grpcapi/admirav1"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Los métodos de sólo lectura piden viewer; el resto, operator.
+var viewerMethods = map[string]bool{
+	admirav1.MetricsService_GetMetricsByChannel_FullMethodName: true,
+	admirav1.MetricsService_GetMetricsByFunnel_FullMethodName:  true,
+	admirav1.MetricsService_GetMetricsGrouped_FullMethodName:   true,
+	admirav1.IngestionService_ListRuns_FullMethodName:          true,
+	admirav1.IngestionService_GetIngestionJob_FullMethodName:   true,
+	admirav1.IngestionService_ListIngestionJobs_FullMethodName: true,
+}
+
+// La reflexión sólo describe el esquema, igual que /openapi.json en HTTP.
+const reflectionPrefix = "/grpc.reflection."
+
+type tenantScoped interface {
+	GetTenantId() string
+}
+
+// authInterceptor valida x-api-key o authorization en los metadatos y fija el tenant de la llamada.
+func authInterceptor(authn *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, reflectionPrefix) {
+			return handler(ctx, req)
+		}
+
+		var bound string
+		if !authn.Disabled() {
+			md, _ := metadata.FromIncomingContext(ctx)
+			principal, err := authn.AuthenticateCredentials(firstValue(md, "x-api-key"), firstValue(md, "authorization"))
+			if err != nil {
+				return nil, status.Error(codes.Unauthenticated, "unauthorized")
+			}
+
+			role := auth.RoleOperator
+			if viewerMethods[info.FullMethod] {
+				role = auth.RoleViewer
+			}
+			if !principal.HasRole(role) {
+				return nil, status.Error(codes.PermissionDenied, "forbidden")
+			}
+
+			ctx = auth.WithPrincipal(ctx, principal)
+			bound = principal.BoundTenant()
+		}
+
+		var requested string
+		if scoped, ok := req.(tenantScoped); ok {
+			requested = scoped.GetTenantId()
+		}
+
+		tenantID, err := tenant.Resolve(requested, bound)
+		if errors.Is(err, tenant.ErrInvalidTenant) {
+			return nil, status.Error(codes.InvalidArgument, "invalid tenant_id")
+		}
+		if err != nil {
+			return nil, status.Error(codes.PermissionDenied, "forbidden")
+		}
+
+		return handler(tenant.WithID(ctx, tenantID), req)
+	}
+}
+
+// loggingInterceptor propaga x-request-id igual que RequestIDMiddleware y registra cada llamada.
+func loggingInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		requestID := firstValue(md, strings.ToLower(tracing.RequestIDHeader))
+		if requestID == "" || len(requestID) > 128 {
+			requestID = tracing.NewRequestID()
+		}
+		ctx = tracing.WithRequestID(ctx, requestID)
+		grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(tracing.RequestIDHeader), requestID))
+
+		resp, err := handler(ctx, req)
+
+		tracing.Logger(ctx, logger).WithFields(logrus.Fields{
+			"method":   info.FullMethod,
+			"code":     status.Code(err).String(),
+			"duration": time.Since(start).String(),
+		}).Info("gRPC request")
+
+		return resp, err
+	}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
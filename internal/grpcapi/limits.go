@@ -0,0 +1,77 @@
+package grpcapi
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/grpcapi/admirav1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodRoutes asigna a cada método la ruta HTTP equivalente, de modo que API_RATE_LIMIT_ROUTES
+// se aplica igual y un cliente comparte el mismo bucket por HTTP y por gRPC.
+var methodRoutes = map[string]string{
+	admirav1.MetricsService_GetMetricsByChannel_FullMethodName:  "/metrics/channel",
+	admirav1.MetricsService_GetMetricsByFunnel_FullMethodName:   "/metrics/funnel",
+	admirav1.MetricsService_GetMetricsGrouped_FullMethodName:    "/metrics/campaigns",
+	admirav1.IngestionService_RunIngestion_FullMethodName:       "/ingest/run",
+	admirav1.IngestionService_ListRuns_FullMethodName:           "/ingest/runs",
+	admirav1.IngestionService_ReplayRun_FullMethodName:          "/ingest/runs/{id}/replay",
+	admirav1.IngestionService_CreateIngestionJob_FullMethodName: "/backfill",
+	admirav1.IngestionService_ListIngestionJobs_FullMethodName:  "/backfill",
+	admirav1.IngestionService_GetIngestionJob_FullMethodName:    "/backfill/{id}",
+	admirav1.IngestionService_ResumeIngestionJob_FullMethodName: "/backfill/{id}/resume",
+}
+
+// Los métodos que lanzan ingestas cuentan para INGEST_DAILY_QUOTA, como sus rutas HTTP.
+var quotaMethods = map[string]bool{
+	admirav1.IngestionService_RunIngestion_FullMethodName:       true,
+	admirav1.IngestionService_ReplayRun_FullMethodName:          true,
+	admirav1.IngestionService_CreateIngestionJob_FullMethodName: true,
+	admirav1.IngestionService_ResumeIngestionJob_FullMethodName: true,
+}
+
+// RateLimitInterceptor aplica el ClientLimiter de la API HTTP. Va después de la
+// autenticación para identificar al cliente por su credencial.
+func RateLimitInterceptor(limiter *api.ClientLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		route, ok := methodRoutes[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		wait, ok := limiter.Allow(callerKey(ctx), route)
+		if !ok {
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(wait.Seconds())))))
+			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// QuotaInterceptor aplica la cuota diaria de ingestas de la API HTTP.
+func QuotaInterceptor(quota *api.DailyQuota) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if quotaMethods[info.FullMethod] && !quota.Allow(callerKey(ctx)) {
+			return nil, status.Error(codes.ResourceExhausted, "daily quota exceeded")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func callerKey(ctx context.Context) string {
+	principal, _ := auth.PrincipalFrom(ctx)
+
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+	return api.ClientKey(principal, addr)
+}
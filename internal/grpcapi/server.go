@@ -0,0 +1,412 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/backfill"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/grpcapi/admirav1"
+	"github.com/admira-project/backend/internal/landing"
+	"github.com/admira-project/backend/internal/lock"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/queue"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const maxLimit = 1000
+
+// NewServer expone los servicios de métricas e ingesta sobre el mismo storage, pipeline
+// y backfill que la API HTTP, con la misma autenticación y el mismo reparto por tenant.
+// Con ingestQueue, RunIngestion encola como la API HTTP en lugar de ingerir en la llamada.
+// interceptors se encadenan tras la autenticación, con la identidad ya en el contexto.
+func NewServer(store storage.Storage, pipeline *etl.Pipeline, manager *backfill.Manager, ingestQueue queue.Queue, authn *auth.Authenticator, logger *logrus.Logger, interceptors ...grpc.UnaryServerInterceptor) *grpc.Server {
+	chain := append([]grpc.UnaryServerInterceptor{loggingInterceptor(logger), authInterceptor(authn)}, interceptors...)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(chain...))
+
+	admirav1.RegisterMetricsServiceServer(server, &metricsServer{storage: store, logger: logger})
+	admirav1.RegisterIngestionServiceServer(server, &ingestionServer{pipeline: pipeline, backfill: manager, queue: ingestQueue, logger: logger})
+	reflection.Register(server)
+
+	return server
+}
+
+type metricsServer struct {
+	admirav1.UnimplementedMetricsServiceServer
+
+	storage storage.Storage
+	logger  *logrus.Logger
+}
+
+func (s *metricsServer) GetMetricsByChannel(ctx context.Context, req *admirav1.MetricsRequest) (*admirav1.MetricsResponse, error) {
+	request, err := metricsRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := s.storage.GetMetricsByChannel(request)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get metrics: %v", err)
+	}
+
+	return &admirav1.MetricsResponse{Metrics: toProtoMetrics(metrics)}, nil
+}
+
+func (s *metricsServer) GetMetricsByFunnel(ctx context.Context, req *admirav1.MetricsRequest) (*admirav1.MetricsResponse, error) {
+	request, err := metricsRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := s.storage.GetMetricsByFunnel(request)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get metrics: %v", err)
+	}
+
+	return &admirav1.MetricsResponse{Metrics: toProtoMetrics(metrics)}, nil
+}
+
+func (s *metricsServer) GetMetricsGrouped(ctx context.Context, req *admirav1.MetricsRequest) (*admirav1.MetricGroupsResponse, error) {
+	request, err := metricsRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !storage.ValidGroupBy(request.GroupBy) {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported group_by: %s", request.GroupBy)
+	}
+
+	groups, err := s.storage.GetMetricsGrouped(request)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get metrics: %v", err)
+	}
+
+	response := &admirav1.MetricGroupsResponse{Groups: make([]*admirav1.MetricGroup, 0, len(groups))}
+	for _, group := range groups {
+		response.Groups = append(response.Groups, &admirav1.MetricGroup{
+			GroupBy: group.GroupBy,
+			Key:     group.Key,
+			Totals:  toProtoMetric(group.Metric),
+		})
+	}
+	return response, nil
+}
+
+type ingestionServer struct {
+	admirav1.UnimplementedIngestionServiceServer
+
+	pipeline *etl.Pipeline
+	backfill *backfill.Manager
+	queue    queue.Queue
+	logger   *logrus.Logger
+}
+
+func (s *ingestionServer) RunIngestion(ctx context.Context, req *admirav1.RunIngestionRequest) (*admirav1.IngestionRun, error) {
+	var since time.Time
+	if req.GetSince() != "" {
+		parsed, err := time.Parse("2006-01-02", req.GetSince())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "since must be a date in YYYY-MM-DD format")
+		}
+		since = parsed
+	}
+
+	if s.queue != nil {
+		return s.enqueueIngestion(ctx, req)
+	}
+
+	var result *etl.RunResult
+	var err error
+	if req.GetForce() {
+		result, err = s.pipeline.ForceRunRange(ctx, etl.DateRange{From: since})
+	} else {
+		result, err = s.pipeline.Run(ctx, since)
+	}
+	if err != nil {
+		return nil, s.statusOf(ctx, err)
+	}
+
+	return toProtoRun(result), nil
+}
+
+func (s *ingestionServer) enqueueIngestion(ctx context.Context, req *admirav1.RunIngestionRequest) (*admirav1.IngestionRun, error) {
+	job := &queue.Job{
+		Tenant: tenant.FromContext(ctx),
+		Since:  req.GetSince(),
+		Force:  req.GetForce(),
+	}
+	if err := s.queue.Enqueue(job); err != nil {
+		tracing.Logger(ctx, s.logger).Errorf("Failed to enqueue ingestion: %v", err)
+		return nil, status.Error(codes.Internal, "failed to enqueue ingestion")
+	}
+	monitoring.IngestJobs.WithLabelValues("enqueued").Inc()
+	tracing.Logger(ctx, s.logger).Infof("Enqueued ingestion job %s", job.ID)
+
+	return &admirav1.IngestionRun{
+		TenantId:  job.Tenant,
+		Since:     job.Since,
+		JobId:     job.ID,
+		Status:    job.Status,
+		CreatedAt: timestamppb.New(job.CreatedAt),
+	}, nil
+}
+
+func (s *ingestionServer) ListRuns(ctx context.Context, req *admirav1.ListRunsRequest) (*admirav1.ListRunsResponse, error) {
+	store := s.pipeline.Landing()
+	if store == nil {
+		return nil, status.Error(codes.FailedPrecondition, "landing zone is not configured")
+	}
+
+	manifests, err := store.ListManifests()
+	if err != nil {
+		return nil, s.statusOf(ctx, err)
+	}
+
+	tenantID := tenant.FromContext(ctx)
+	response := &admirav1.ListRunsResponse{Runs: make([]*admirav1.IngestionRun, 0, len(manifests))}
+	for _, manifest := range manifests {
+		if manifest.Tenant == tenantID || (manifest.Tenant == "" && tenantID == tenant.Default) {
+			response.Runs = append(response.Runs, toProtoManifest(manifest))
+		}
+	}
+	return response, nil
+}
+
+func (s *ingestionServer) ReplayRun(ctx context.Context, req *admirav1.ReplayRunRequest) (*admirav1.IngestionRun, error) {
+	result, err := s.pipeline.Replay(ctx, req.GetRunId(), true)
+	if err != nil {
+		return nil, s.statusOf(ctx, err)
+	}
+
+	return toProtoRun(result), nil
+}
+
+func (s *ingestionServer) CreateIngestionJob(ctx context.Context, req *admirav1.CreateIngestionJobRequest) (*admirav1.IngestionJob, error) {
+	from, err := time.Parse("2006-01-02", req.GetFrom())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "from must be a date in YYYY-MM-DD format")
+	}
+
+	to, err := time.Parse("2006-01-02", req.GetTo())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "to must be a date in YYYY-MM-DD format")
+	}
+
+	job, err := s.backfill.CreateForTenant(tenant.FromContext(ctx), from, to, int(req.GetChunkDays()))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// El job sobrevive a la llamada, por eso no se usa ctx
+	if err := s.backfill.Start(context.Background(), job.ID); err != nil {
+		return nil, s.statusOf(ctx, err)
+	}
+
+	return s.job(ctx, job.ID)
+}
+
+func (s *ingestionServer) GetIngestionJob(ctx context.Context, req *admirav1.IngestionJobRequest) (*admirav1.IngestionJob, error) {
+	return s.job(ctx, req.GetId())
+}
+
+func (s *ingestionServer) ListIngestionJobs(ctx context.Context, req *admirav1.ListIngestionJobsRequest) (*admirav1.ListIngestionJobsResponse, error) {
+	jobs, err := s.backfill.List()
+	if err != nil {
+		return nil, s.statusOf(ctx, err)
+	}
+
+	response := &admirav1.ListIngestionJobsResponse{Jobs: make([]*admirav1.IngestionJob, 0, len(jobs))}
+	for i := range jobs {
+		if ownsJob(ctx, &jobs[i]) {
+			response.Jobs = append(response.Jobs, toProtoJob(&jobs[i]))
+		}
+	}
+	return response, nil
+}
+
+func (s *ingestionServer) ResumeIngestionJob(ctx context.Context, req *admirav1.IngestionJobRequest) (*admirav1.IngestionJob, error) {
+	if _, err := s.job(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.backfill.Start(context.Background(), req.GetId()); err != nil {
+		return nil, s.statusOf(ctx, err)
+	}
+
+	return s.job(ctx, req.GetId())
+}
+
+func (s *ingestionServer) job(ctx context.Context, jobID string) (*admirav1.IngestionJob, error) {
+	job, err := s.backfill.Get(jobID)
+	if err == nil && !ownsJob(ctx, job) {
+		err = backfill.ErrJobNotFound
+	}
+	if err != nil {
+		return nil, s.statusOf(ctx, err)
+	}
+
+	return toProtoJob(job), nil
+}
+
+// statusOf traduce los errores del pipeline y del backfill a códigos gRPC, como writePipelineError en HTTP.
+func (s *ingestionServer) statusOf(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, landing.ErrRunNotFound):
+		return status.Error(codes.NotFound, "ingestion run not found")
+	case errors.Is(err, etl.ErrUnknownTenant):
+		return status.Error(codes.NotFound, "unknown tenant")
+	case errors.Is(err, backfill.ErrJobNotFound):
+		return status.Error(codes.NotFound, "backfill job not found")
+	case errors.Is(err, backfill.ErrJobAlreadyActive):
+		return status.Error(codes.FailedPrecondition, "backfill job is already running")
+	}
+
+	var held *lock.HeldError
+	if errors.As(err, &held) {
+		return status.Error(codes.Aborted, "ingestion already in progress: run "+held.Holder)
+	}
+
+	tracing.Logger(ctx, s.logger).Errorf("Ingestion failed: %v", err)
+
+	var pipelineErr *etl.PipelineError
+	if errors.As(err, &pipelineErr) {
+		return status.Error(codes.Internal, "failed to "+pipelineErr.Stage)
+	}
+	return status.Error(codes.Internal, "ingestion failed")
+}
+
+// ownsJob oculta los jobs de otros tenants como si no existieran.
+func ownsJob(ctx context.Context, job *backfill.Job) bool {
+	jobTenant := job.Tenant
+	if jobTenant == "" {
+		jobTenant = tenant.Default
+	}
+	return jobTenant == tenant.FromContext(ctx)
+}
+
+func metricsRequest(ctx context.Context, req *admirav1.MetricsRequest) (models.MetricsRequest, error) {
+	for name, value := range map[string]string{"from": req.GetFrom(), "to": req.GetTo()} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return models.MetricsRequest{}, status.Errorf(codes.InvalidArgument, "%s must be a date in YYYY-MM-DD format", name)
+		}
+	}
+
+	if req.GetLimit() < 0 || req.GetLimit() > maxLimit {
+		return models.MetricsRequest{}, status.Errorf(codes.InvalidArgument, "limit must be between 0 and %d", maxLimit)
+	}
+	if req.GetOffset() < 0 {
+		return models.MetricsRequest{}, status.Error(codes.InvalidArgument, "offset must be >= 0")
+	}
+
+	return models.MetricsRequest{
+		TenantID:     tenant.FromContext(ctx),
+		From:         req.GetFrom(),
+		To:           req.GetTo(),
+		Channel:      req.GetChannel(),
+		UtmCampaign:  req.GetUtmCampaign(),
+		CampaignName: req.GetCampaignName(),
+		Owner:        req.GetOwner(),
+		BusinessUnit: req.GetBusinessUnit(),
+		Tag:          req.GetTag(),
+		GroupBy:      req.GetGroupBy(),
+		Limit:        int(req.GetLimit()),
+		Offset:       int(req.GetOffset()),
+	}, nil
+}
+
+func toProtoMetrics(metrics []models.Metric) []*admirav1.Metric {
+	result := make([]*admirav1.Metric, 0, len(metrics))
+	for _, metric := range metrics {
+		result = append(result, toProtoMetric(metric))
+	}
+	return result
+}
+
+func toProtoMetric(m models.Metric) *admirav1.Metric {
+	return &admirav1.Metric{
+		TenantId:      m.TenantID,
+		Date:          m.Date,
+		Channel:       m.Channel,
+		CampaignId:    m.CampaignID,
+		CampaignName:  m.CampaignName,
+		Owner:         m.Owner,
+		Objective:     m.Objective,
+		BusinessUnit:  m.BusinessUnit,
+		Tags:          m.Tags,
+		UtmCampaign:   m.UtmCampaign,
+		UtmSource:     m.UtmSource,
+		UtmMedium:     m.UtmMedium,
+		Clicks:        int64(m.Clicks),
+		Impressions:   int64(m.Impressions),
+		Cost:          m.Cost,
+		Leads:         int64(m.Leads),
+		Opportunities: int64(m.Opportunities),
+		ClosedWon:     int64(m.ClosedWon),
+		Revenue:       m.Revenue,
+		Cpc:           m.CPC,
+		Cpa:           m.CPA,
+		CvrLeadToOpp:  m.CvrLeadToOpp,
+		CvrOppToWon:   m.CvrOppToWon,
+		Roas:          m.Roas,
+	}
+}
+
+func toProtoRun(result *etl.RunResult) *admirav1.IngestionRun {
+	return &admirav1.IngestionRun{
+		RunId:     result.RunID,
+		TenantId:  result.Tenant,
+		Since:     result.Since,
+		Until:     result.Until,
+		Count:     int32(result.Count),
+		Unchanged: result.Unchanged,
+	}
+}
+
+func toProtoManifest(manifest landing.Manifest) *admirav1.IngestionRun {
+	return &admirav1.IngestionRun{
+		RunId:     manifest.RunID,
+		TenantId:  manifest.Tenant,
+		Since:     manifest.Since,
+		Until:     manifest.Until,
+		CreatedAt: timestamppb.New(manifest.CreatedAt),
+	}
+}
+
+func toProtoJob(job *backfill.Job) *admirav1.IngestionJob {
+	result := &admirav1.IngestionJob{
+		Id:        job.ID,
+		TenantId:  job.Tenant,
+		From:      job.From,
+		To:        job.To,
+		ChunkDays: int32(job.ChunkDays),
+		Status:    job.Status,
+		Chunks:    make([]*admirav1.BackfillChunk, 0, len(job.Chunks)),
+		CreatedAt: timestamppb.New(job.CreatedAt),
+		UpdatedAt: timestamppb.New(job.UpdatedAt),
+	}
+	for _, chunk := range job.Chunks {
+		result.Chunks = append(result.Chunks, &admirav1.BackfillChunk{
+			From:   chunk.From,
+			To:     chunk.To,
+			Status: chunk.Status,
+			RunId:  chunk.RunID,
+			Count:  int32(chunk.Count),
+			Error:  chunk.Error,
+		})
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+func StorageCheck(storage Pinger) CheckFunc {
+	return func(ctx context.Context) (interface{}, error) {
+		return nil, storage.Ping(ctx)
+	}
+}
+
+type probeResult struct {
+	checkedAt time.Time
+	details   map[string]interface{}
+	err       error
+}
+
+// HTTPProbe comprueba que una URL responde. El resultado se cachea durante ttl para que
+// los sondeos de readiness no se conviertan en carga para la fuente.
+func HTTPProbe(client *http.Client, url string, ttl time.Duration) CheckFunc {
+	var mu sync.Mutex
+	var last *probeResult
+
+	return func(ctx context.Context) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if last != nil && time.Since(last.checkedAt) < ttl {
+			return last.details, last.err
+		}
+
+		result := &probeResult{
+			checkedAt: time.Now(),
+			details:   map[string]interface{}{"url": url, "checked_at": time.Now().UTC()},
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+		if err != nil {
+			return result.details, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			result.err = fmt.Errorf("unreachable: %v", err)
+		} else {
+			resp.Body.Close()
+			result.details["status_code"] = resp.StatusCode
+			if resp.StatusCode >= 500 {
+				result.err = fmt.Errorf("unhealthy: status %d", resp.StatusCode)
+			}
+		}
+
+		last = result
+		return result.details, result.err
+	}
+}
+
+// FreshnessCheck falla si todavía no hubo ninguna ingesta correcta o si la última
+// es más antigua que maxAge (0 = sin límite).
+func FreshnessCheck(lastSuccess func() time.Time, maxAge time.Duration) CheckFunc {
+	return func(ctx context.Context) (interface{}, error) {
+		last := lastSuccess()
+		if last.IsZero() {
+			return nil, fmt.Errorf("no successful ingestion yet")
+		}
+
+		age := time.Since(last)
+		details := map[string]interface{}{
+			"last_success": last.UTC(),
+			"age":          age.Round(time.Second).String(),
+		}
+		if maxAge > 0 && age > maxAge {
+			return details, fmt.Errorf("data is stale: last ingestion %s ago exceeds %s", age.Round(time.Second), maxAge)
+		}
+		return details, nil
+	}
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+)
+
+type CheckFunc func(ctx context.Context) (details interface{}, err error)
+
+type CheckResult struct {
+	Status   string      `json:"status"`
+	Error    string      `json:"error,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+	Duration string      `json:"duration"`
+}
+
+type Report struct {
+	Ready  bool                   `json:"-"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Public devuelve sólo el estado y la duración de cada check, sin errores ni detalles,
+// que pueden incluir URLs internas.
+func (r Report) Public() map[string]CheckResult {
+	checks := make(map[string]CheckResult, len(r.Checks))
+	for name, check := range r.Checks {
+		checks[name] = CheckResult{Status: check.Status, Duration: check.Duration}
+	}
+	return checks
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Readiness ejecuta en paralelo los checks registrados, cada uno con su propio timeout.
+type Readiness struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+func NewReadiness(timeout time.Duration) *Readiness {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Readiness{timeout: timeout}
+}
+
+func (r *Readiness) Add(name string, check CheckFunc) {
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+func (r *Readiness) Run(ctx context.Context) Report {
+	report := Report{Ready: true, Checks: make(map[string]CheckResult, len(r.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range r.checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+
+			start := time.Now()
+			details, err := c.check(checkCtx)
+			result := CheckResult{Status: StatusPass, Details: details, Duration: time.Since(start).String()}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Checks[c.name] = result
+			if err != nil {
+				report.Ready = false
+			}
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	return report
+}
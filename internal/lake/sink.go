@@ -0,0 +1,443 @@
+package lake
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/parquet-go/parquet-go"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	Table = "metrics"
+
+	manifestVersion = 1
+	// defaultPartition es el valor que usa Hive para particiones vacías.
+	defaultPartition = "__HIVE_DEFAULT_PARTITION__"
+)
+
+// MetricRow es una fila de los ficheros Parquet. tenant, date y channel no se
+// repiten dentro del fichero: van en la ruta, como en cualquier tabla Hive.
+type MetricRow struct {
+	RunID         string   `parquet:"run_id,dict"`
+	CampaignID    string   `parquet:"campaign_id,dict"`
+	CampaignName  string   `parquet:"campaign_name,dict"`
+	Owner         string   `parquet:"owner,dict"`
+	Objective     string   `parquet:"objective,dict"`
+	BusinessUnit  string   `parquet:"business_unit,dict"`
+	Tags          []string `parquet:"tags,list"`
+	UtmCampaign   string   `parquet:"utm_campaign,dict"`
+	UtmSource     string   `parquet:"utm_source,dict"`
+	UtmMedium     string   `parquet:"utm_medium,dict"`
+	Clicks        int64    `parquet:"clicks"`
+	Impressions   int64    `parquet:"impressions"`
+	Cost          float64  `parquet:"cost"`
+	Leads         int64    `parquet:"leads"`
+	Opportunities int64    `parquet:"opportunities"`
+	ClosedWon     int64    `parquet:"closed_won"`
+	Revenue       float64  `parquet:"revenue"`
+	CPC           float64  `parquet:"cpc"`
+	CPA           float64  `parquet:"cpa"`
+	CvrLeadToOpp  float64  `parquet:"cvr_lead_to_opp"`
+	CvrOppToWon   float64  `parquet:"cvr_opp_to_won"`
+	Roas          float64  `parquet:"roas"`
+}
+
+type ManifestFile struct {
+	Path    string `json:"path"`
+	Date    string `json:"date"`
+	Channel string `json:"channel"`
+	Rows    int    `json:"rows"`
+	Bytes   int    `json:"bytes"`
+	SHA256  string `json:"sha256"`
+}
+
+// Manifest registra los ficheros de una ejecución. Se escribe después de promoverlos,
+// así que una ejecución sólo está confirmada si existe su manifiesto.
+type Manifest struct {
+	Version   int            `json:"version"`
+	Table     string         `json:"table"`
+	RunID     string         `json:"run_id"`
+	Tenant    string         `json:"tenant_id"`
+	Since     string         `json:"since,omitempty"`
+	Until     string         `json:"until,omitempty"`
+	Rows      int            `json:"rows"`
+	Files     []ManifestFile `json:"files"`
+	Location  string         `json:"location"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Sink exporta cada ejecución guardada a una tabla Parquet particionada
+// tenant=/date=/channel=. Implementa etl.RunObserver.
+type Sink struct {
+	store  ObjectStore
+	logger *logrus.Logger
+}
+
+func NewSink(store ObjectStore, logger *logrus.Logger) *Sink {
+	return &Sink{store: store, logger: logger}
+}
+
+// Export escribe los ficheros y el manifiesto en _staging/<run_id>/ y después los promueve a
+// la tabla. Si algo falla antes de completar el staging, lo borra. Los ficheros llevan el id
+// de la ejecución en el nombre, por lo que repetir el export de una ejecución (un replay)
+// sobrescribe los suyos sin duplicar filas, y borra los que el nuevo manifiesto ya no incluye.
+func (s *Sink) Export(ctx context.Context, result *etl.RunResult) (*Manifest, error) {
+	tenantID := result.Tenant
+	if tenantID == "" {
+		tenantID = tenant.Default
+	}
+
+	ctx, span := tracing.Start(ctx, "lake export", attribute.String("etl.run_id", result.RunID), attribute.Int("etl.records", len(result.Metrics)))
+	manifest, err := s.export(ctx, tenantID, result)
+	tracing.End(span, err)
+
+	if err != nil {
+		monitoring.LakeExports.WithLabelValues("failure").Inc()
+		return nil, err
+	}
+	monitoring.LakeExports.WithLabelValues("success").Inc()
+	return manifest, nil
+}
+
+// pendingExport es lo que queda en staging cuando ya está todo escrito: el manifiesto nuevo
+// y los ficheros del anterior que hay que borrar al promover.
+type pendingExport struct {
+	Manifest *Manifest `json:"manifest"`
+	Stale    []string  `json:"stale,omitempty"`
+}
+
+func (s *Sink) export(ctx context.Context, tenantID string, result *etl.RunResult) (*Manifest, error) {
+	// Un export anterior de la misma ejecución que se cortó se termina o se descarta antes
+	if err := s.recoverRun(ctx, result.RunID); err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Version:   manifestVersion,
+		Table:     Table,
+		RunID:     result.RunID,
+		Tenant:    tenantID,
+		Since:     result.Since,
+		Until:     result.Until,
+		Files:     make([]ManifestFile, 0),
+		Location:  s.store.Location(),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	var written []string
+	rollback := func(err error) (*Manifest, error) {
+		// El contexto original puede estar cancelado; la limpieza se hace igualmente
+		cleanupCtx := context.WithoutCancel(ctx)
+		for _, key := range written {
+			if err := s.store.Delete(cleanupCtx, key); err != nil {
+				s.logger.Errorf("Failed to roll back export file %s: %v", key, err)
+			}
+		}
+		return nil, err
+	}
+
+	for _, partition := range partitionMetrics(result.RunID, result.Metrics) {
+		data, err := encodeRows(partition.rows)
+		if err != nil {
+			return rollback(fmt.Errorf("failed to encode partition %s/%s: %v", partition.date, partition.channel, err))
+		}
+
+		key := path.Join(
+			Table,
+			"tenant="+escapePartition(tenantID),
+			"date="+escapePartition(partition.date),
+			"channel="+escapePartition(partition.channel),
+			"part-"+result.RunID+".parquet",
+		)
+		if err := s.store.Put(ctx, stagingKey(result.RunID, key), data, "application/vnd.apache.parquet"); err != nil {
+			return rollback(err)
+		}
+		written = append(written, stagingKey(result.RunID, key))
+
+		checksum := sha256.Sum256(data)
+		manifest.Files = append(manifest.Files, ManifestFile{
+			Path:    key,
+			Date:    partition.date,
+			Channel: partition.channel,
+			Rows:    len(partition.rows),
+			Bytes:   len(data),
+			SHA256:  hex.EncodeToString(checksum[:]),
+		})
+		manifest.Rows += len(partition.rows)
+	}
+
+	pending := pendingExport{Manifest: manifest}
+	previous, err := s.Manifest(ctx, tenantID, result.RunID)
+	if err != nil && !errors.Is(err, ErrObjectNotFound) {
+		return rollback(err)
+	}
+	if previous != nil {
+		pending.Stale = staleFiles(previous, manifest)
+	}
+
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return rollback(err)
+	}
+	if err := s.store.Put(ctx, pendingKey(result.RunID), data, "application/json"); err != nil {
+		return rollback(err)
+	}
+
+	// Desde aquí el export ya no se deshace: si la promoción falla, Recover la termina
+	if err := s.promote(ctx, &pending); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// promote copia los ficheros de staging a la tabla, escribe el manifiesto, borra los ficheros
+// que ya no lista y por último el staging. Se puede repetir si se corta a mitad.
+func (s *Sink) promote(ctx context.Context, pending *pendingExport) error {
+	manifest := pending.Manifest
+	for _, file := range manifest.Files {
+		data, err := s.store.Get(ctx, stagingKey(manifest.RunID, file.Path))
+		if err != nil {
+			return err
+		}
+		if err := s.store.Put(ctx, file.Path, data, "application/vnd.apache.parquet"); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := s.store.Put(ctx, ManifestKey(manifest.Tenant, manifest.RunID), data, "application/json"); err != nil {
+		return err
+	}
+
+	for _, key := range pending.Stale {
+		if err := s.store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range manifest.Files {
+		if err := s.store.Delete(ctx, stagingKey(manifest.RunID, file.Path)); err != nil {
+			return err
+		}
+	}
+	return s.store.Delete(ctx, pendingKey(manifest.RunID))
+}
+
+// Recover termina los exports que quedaron a medias tras una caída: promueve los que llegaron
+// a completar el staging y borra los restos de los demás.
+func (s *Sink) Recover(ctx context.Context) error {
+	keys, err := s.store.List(ctx, stagingPrefix+"/")
+	if err != nil {
+		return err
+	}
+
+	runs := make(map[string]bool)
+	for _, key := range keys {
+		runID, _, _ := strings.Cut(strings.TrimPrefix(key, stagingPrefix+"/"), "/")
+		if runID != "" && !runs[runID] {
+			runs[runID] = true
+			if err := s.recoverRun(ctx, runID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Sink) recoverRun(ctx context.Context, runID string) error {
+	data, err := s.store.Get(ctx, pendingKey(runID))
+	if err == nil {
+		var pending pendingExport
+		if err := json.Unmarshal(data, &pending); err != nil || pending.Manifest == nil {
+			return fmt.Errorf("failed to unmarshal pending export %s: %v", runID, err)
+		}
+		s.logger.Warnf("Completing interrupted export of run %s", runID)
+		return s.promote(ctx, &pending)
+	}
+	if !errors.Is(err, ErrObjectNotFound) {
+		return err
+	}
+
+	// Sin manifiesto en staging el export no llegó a completarse y lo escrito se descarta
+	keys, err := s.store.List(ctx, path.Join(stagingPrefix, runID)+"/")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	if len(keys) > 0 {
+		s.logger.Warnf("Discarded %d files of interrupted export of run %s", len(keys), runID)
+	}
+	return nil
+}
+
+// staleFiles devuelve los ficheros de previous que current ya no incluye.
+func staleFiles(previous, current *Manifest) []string {
+	kept := make(map[string]bool, len(current.Files))
+	for _, file := range current.Files {
+		kept[file.Path] = true
+	}
+
+	var stale []string
+	for _, file := range previous.Files {
+		if !kept[file.Path] {
+			stale = append(stale, file.Path)
+		}
+	}
+	return stale
+}
+
+// Manifest lee el manifiesto de una ejecución ya exportada.
+func (s *Sink) Manifest(ctx context.Context, tenantID, runID string) (*Manifest, error) {
+	data, err := s.store.Get(ctx, ManifestKey(tenantID, runID))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest %s: %v", runID, err)
+	}
+	return &manifest, nil
+}
+
+// RunFinished exporta la ejecución en cuanto termina. Un fallo del export no
+// invalida la ingesta: se registra y la ejecución puede reexportarse con un replay.
+func (s *Sink) RunFinished(ctx context.Context, result *etl.RunResult) {
+	if result == nil || result.Unchanged {
+		return
+	}
+
+	manifest, err := s.Export(context.WithoutCancel(ctx), result)
+	if err != nil {
+		tracing.Logger(ctx, s.logger).Errorf("Failed to export run %s: %v", result.RunID, err)
+		return
+	}
+	tracing.Logger(ctx, s.logger).Infof("Exported run %s: %d rows in %d files to %s", result.RunID, manifest.Rows, len(manifest.Files), manifest.Location)
+}
+
+// El resto de RunObserver no exporta nada.
+func (s *Sink) RunStarted(ctx context.Context, runID string) {}
+
+func (s *Sink) StageCompleted(ctx context.Context, runID, stage string, duration time.Duration) {}
+
+func (s *Sink) RunFailed(ctx context.Context, runID string, err error) {}
+
+const stagingPrefix = "_staging"
+
+func stagingKey(runID, key string) string {
+	return path.Join(stagingPrefix, runID, key)
+}
+
+func pendingKey(runID string) string {
+	return path.Join(stagingPrefix, runID, "manifest.json")
+}
+
+func ManifestKey(tenantID, runID string) string {
+	return path.Join("_manifests", Table, "tenant="+escapePartition(tenantID), runID+".json")
+}
+
+type partition struct {
+	date    string
+	channel string
+	rows    []MetricRow
+}
+
+func partitionMetrics(runID string, metrics []models.Metric) []partition {
+	index := make(map[[2]string]int)
+	var partitions []partition
+
+	for _, metric := range metrics {
+		key := [2]string{metric.Date, metric.Channel}
+		i, ok := index[key]
+		if !ok {
+			i = len(partitions)
+			index[key] = i
+			partitions = append(partitions, partition{date: metric.Date, channel: metric.Channel})
+		}
+		partitions[i].rows = append(partitions[i].rows, toRow(runID, metric))
+	}
+
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].date != partitions[j].date {
+			return partitions[i].date < partitions[j].date
+		}
+		return partitions[i].channel < partitions[j].channel
+	})
+	return partitions
+}
+
+func toRow(runID string, metric models.Metric) MetricRow {
+	return MetricRow{
+		RunID:         runID,
+		CampaignID:    metric.CampaignID,
+		CampaignName:  metric.CampaignName,
+		Owner:         metric.Owner,
+		Objective:     metric.Objective,
+		BusinessUnit:  metric.BusinessUnit,
+		Tags:          metric.Tags,
+		UtmCampaign:   metric.UtmCampaign,
+		UtmSource:     metric.UtmSource,
+		UtmMedium:     metric.UtmMedium,
+		Clicks:        int64(metric.Clicks),
+		Impressions:   int64(metric.Impressions),
+		Cost:          metric.Cost,
+		Leads:         int64(metric.Leads),
+		Opportunities: int64(metric.Opportunities),
+		ClosedWon:     int64(metric.ClosedWon),
+		Revenue:       metric.Revenue,
+		CPC:           metric.CPC,
+		CPA:           metric.CPA,
+		CvrLeadToOpp:  metric.CvrLeadToOpp,
+		CvrOppToWon:   metric.CvrOppToWon,
+		Roas:          metric.Roas,
+	}
+}
+
+func encodeRows(rows []MetricRow) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := parquet.Write(&buf, rows, parquet.Compression(&parquet.Snappy)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// escapePartition codifica los caracteres que no son seguros en una ruta Hive como %XX.
+func escapePartition(value string) string {
+	if value == "" {
+		return defaultPartition
+	}
+
+	var escaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' {
+			escaped.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&escaped, "%%%02X", c)
+	}
+	return escaped.String()
+}
@@ -0,0 +1,196 @@
+package landing
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrRunNotFound = errors.New("run not found")
+
+type Payload struct {
+	SHA256     string `json:"sha256"`
+	Size       int64  `json:"size"`
+	StoredSize int64  `json:"stored_size"`
+}
+
+type Manifest struct {
+	RunID     string             `json:"run_id"`
+	Tenant    string             `json:"tenant_id,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+	Since     string             `json:"since,omitempty"`
+	Until     string             `json:"until,omitempty"`
+	Sources   map[string]Payload `json:"sources"`
+}
+
+// Store guarda los payloads originales comprimidos y direccionados por contenido:
+//
+//	<dir>/blobs/ab/abcdef....json.gz
+//	<dir>/runs/<run_id>.json
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) (*Store, error) {
+	for _, sub := range []string{"blobs", "runs"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create landing zone: %v", err)
+		}
+	}
+
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) PutBlob(data []byte) (Payload, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	path := s.blobPath(hash)
+
+	if info, err := os.Stat(path); err == nil {
+		return Payload{SHA256: hash, Size: int64(len(data)), StoredSize: info.Size()}, nil
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(data); err != nil {
+		return Payload{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return Payload{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return Payload{}, fmt.Errorf("failed to create blob directory: %v", err)
+	}
+	if err := writeFileAtomic(path, compressed.Bytes()); err != nil {
+		return Payload{}, fmt.Errorf("failed to write blob %s: %v", hash, err)
+	}
+
+	return Payload{SHA256: hash, Size: int64(len(data)), StoredSize: int64(compressed.Len())}, nil
+}
+
+func (s *Store) GetBlob(hash string) ([]byte, error) {
+	if len(hash) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid blob hash: %q", hash)
+	}
+
+	file, err := os.Open(s.blobPath(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob %s: %v", hash, err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress blob %s: %v", hash, err)
+	}
+	defer gz.Close()
+
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %v", hash, err)
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("blob %s is corrupted", hash)
+	}
+
+	return data, nil
+}
+
+func (s *Store) SaveManifest(manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(s.manifestPath(manifest.RunID), data); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %v", manifest.RunID, err)
+	}
+	return nil
+}
+
+func (s *Store) LoadManifest(runID string) (Manifest, error) {
+	var manifest Manifest
+
+	if runID == "" || strings.ContainsAny(runID, `/\`) {
+		return manifest, fmt.Errorf("invalid run id: %q", runID)
+	}
+
+	data, err := os.ReadFile(s.manifestPath(runID))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("failed to read manifest %s: %v", runID, err)
+	}
+
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to unmarshal manifest %s: %v", runID, err)
+	}
+
+	return manifest, nil
+}
+
+func (s *Store) ListManifests() ([]Manifest, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, "runs"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %v", err)
+	}
+
+	manifests := make([]Manifest, 0, len(entries))
+	for _, entry := range entries {
+		runID, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		manifest, err := s.LoadManifest(runID)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].CreatedAt.Before(manifests[j].CreatedAt)
+	})
+	return manifests, nil
+}
+
+func (s *Store) blobPath(hash string) string {
+	return filepath.Join(s.dir, "blobs", hash[:2], hash+".json.gz")
+}
+
+func (s *Store) manifestPath(runID string) string {
+	return filepath.Join(s.dir, "runs", runID+".json")
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,27 @@
+//go:build !unix
+
+package lock
+
+import (
+	"fmt"
+	"time"
+)
+
+// FileLocker necesita flock, que sólo existe en sistemas unix.
+type FileLocker struct{}
+
+func NewFileLocker(dir string) (*FileLocker, error) {
+	return nil, fmt.Errorf("file locks are not supported on this platform")
+}
+
+func (l *FileLocker) Acquire(key, holder string, ttl time.Duration) error {
+	return fmt.Errorf("file locks are not supported on this platform")
+}
+
+func (l *FileLocker) Renew(key, holder string, ttl time.Duration) error {
+	return fmt.Errorf("file locks are not supported on this platform")
+}
+
+func (l *FileLocker) Release(key, holder string) error {
+	return nil
+}
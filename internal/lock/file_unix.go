@@ -0,0 +1,98 @@
+//go:build unix
+
+package lock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileLocker usa flock sobre un fichero por clave dentro de dir. El sistema suelta el
+// lock si el proceso muere, así que ttl no se usa. Sirve para procesos que comparten
+// disco local; en sistemas de ficheros en red flock puede no ser fiable.
+type FileLocker struct {
+	dir   string
+	mu    sync.Mutex
+	files map[string]*heldFile
+}
+
+type heldFile struct {
+	holder string
+	file   *os.File
+}
+
+func NewFileLocker(dir string) (*FileLocker, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %v", err)
+	}
+	return &FileLocker{dir: dir, files: make(map[string]*heldFile)}, nil
+}
+
+func (l *FileLocker) Acquire(key, holder string, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if held, ok := l.files[key]; ok {
+		return &HeldError{Key: key, Holder: held.holder}
+	}
+
+	file, err := os.OpenFile(l.path(key), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %v", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			// El dueño escribe su nombre en el fichero al tomarlo
+			owner, _ := io.ReadAll(file)
+			return &HeldError{Key: key, Holder: string(owner)}
+		}
+		return fmt.Errorf("failed to lock %s: %v", key, err)
+	}
+
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(holder), 0)
+	}
+	l.files[key] = &heldFile{holder: holder, file: file}
+	return nil
+}
+
+func (l *FileLocker) Renew(key, holder string, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if held, ok := l.files[key]; !ok || held.holder != holder {
+		return fmt.Errorf("lock %s is no longer held by %s", key, holder)
+	}
+	return nil
+}
+
+func (l *FileLocker) Release(key, holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	held, ok := l.files[key]
+	if !ok || held.holder != holder {
+		return nil
+	}
+	delete(l.files, key)
+
+	held.file.Truncate(0)
+	syscall.Flock(int(held.file.Fd()), syscall.LOCK_UN)
+	return held.file.Close()
+}
+
+// path usa el hash de la clave: las claves llevan el tenant, que viene del cliente.
+func (l *FileLocker) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(l.dir, hex.EncodeToString(sum[:16])+".lock")
+}
@@ -0,0 +1,35 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrHeld = errors.New("lock is held")
+
+// HeldError indica quién tiene el lock que se ha intentado tomar.
+type HeldError struct {
+	Key    string
+	Holder string
+}
+
+func (e *HeldError) Error() string {
+	if e.Holder == "" {
+		return fmt.Sprintf("%s: %v", e.Key, ErrHeld)
+	}
+	return fmt.Sprintf("%s: %v by %s", e.Key, ErrHeld, e.Holder)
+}
+
+func (e *HeldError) Unwrap() error {
+	return ErrHeld
+}
+
+// Locker da acceso exclusivo a una clave. Acquire no espera: si otro la tiene devuelve
+// un *HeldError. Un lock sin renovar durante ttl caduca y otro puede tomarlo; holder
+// identifica al dueño y debe ser único por adquisición.
+type Locker interface {
+	Acquire(key, holder string, ttl time.Duration) error
+	Renew(key, holder string, ttl time.Duration) error
+	Release(key, holder string) error
+}
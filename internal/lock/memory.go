@@ -0,0 +1,56 @@
+package lock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryLocker sólo protege dentro del proceso.
+type MemoryLocker struct {
+	mu     sync.Mutex
+	leases map[string]lease
+}
+
+type lease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{leases: make(map[string]lease)}
+}
+
+func (l *MemoryLocker) Acquire(key, holder string, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if current, ok := l.leases[key]; ok && current.holder != holder && current.expiresAt.After(now) {
+		return &HeldError{Key: key, Holder: current.holder}
+	}
+	l.leases[key] = lease{holder: holder, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (l *MemoryLocker) Renew(key, holder string, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, ok := l.leases[key]
+	if !ok || current.holder != holder {
+		return fmt.Errorf("lock %s is no longer held by %s", key, holder)
+	}
+	l.leases[key] = lease{holder: holder, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (l *MemoryLocker) Release(key, holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if current, ok := l.leases[key]; ok && current.holder == holder {
+		delete(l.leases, key)
+	}
+	return nil
+}
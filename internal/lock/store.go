@@ -0,0 +1,37 @@
+package lock
+
+import (
+	"time"
+
+	"github.com/admira-project/backend/internal/storage"
+)
+
+// StoreLocker guarda el lock como un lease en el almacenamiento de métricas, de modo que
+// lo comparten todas las réplicas que usan el mismo almacenamiento. Si el dueño cae,
+// el lease caduca tras ttl.
+type StoreLocker struct {
+	store storage.LeaseStore
+}
+
+func NewStoreLocker(store storage.LeaseStore) *StoreLocker {
+	return &StoreLocker{store: store}
+}
+
+func (l *StoreLocker) Acquire(key, holder string, ttl time.Duration) error {
+	current, acquired, err := l.store.AcquireLease(key, holder, ttl)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return &HeldError{Key: key, Holder: current}
+	}
+	return nil
+}
+
+func (l *StoreLocker) Renew(key, holder string, ttl time.Duration) error {
+	return l.store.RenewLease(key, holder, ttl)
+}
+
+func (l *StoreLocker) Release(key, holder string) error {
+	return l.store.ReleaseLease(key, holder)
+}
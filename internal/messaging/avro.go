@@ -0,0 +1,89 @@
+package messaging
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+var (
+	//go:embed schemas/metric_computed.avsc
+	MetricSchema string
+
+	//go:embed schemas/ingestion_completed.avsc
+	RunSchema string
+)
+
+// AvroCodec usa la codificación single-object de Avro: cada mensaje empieza por la
+// huella del esquema, así que el consumidor sabe con qué esquema leerlo sin registry.
+type AvroCodec struct {
+	metric *goavro.Codec
+	run    *goavro.Codec
+}
+
+func NewAvroCodec() (*AvroCodec, error) {
+	metric, err := goavro.NewCodec(MetricSchema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metric schema: %v", err)
+	}
+	run, err := goavro.NewCodec(RunSchema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid run schema: %v", err)
+	}
+	return &AvroCodec{metric: metric, run: run}, nil
+}
+
+func (c *AvroCodec) ContentType() string {
+	return "application/avro"
+}
+
+func (c *AvroCodec) EncodeMetric(event MetricEvent) ([]byte, error) {
+	metric := event.Metric
+	tags := make([]interface{}, 0, len(metric.Tags))
+	for _, tag := range metric.Tags {
+		tags = append(tags, tag)
+	}
+
+	return c.metric.SingleFromNative(nil, map[string]interface{}{
+		"event":           event.Event,
+		"run_id":          event.RunID,
+		"tenant_id":       event.Tenant,
+		"occurred_at":     event.OccurredAt,
+		"date":            metric.Date,
+		"channel":         metric.Channel,
+		"campaign_id":     metric.CampaignID,
+		"campaign_name":   metric.CampaignName,
+		"owner":           metric.Owner,
+		"objective":       metric.Objective,
+		"business_unit":   metric.BusinessUnit,
+		"tags":            tags,
+		"utm_campaign":    metric.UtmCampaign,
+		"utm_source":      metric.UtmSource,
+		"utm_medium":      metric.UtmMedium,
+		"clicks":          int64(metric.Clicks),
+		"impressions":     int64(metric.Impressions),
+		"cost":            metric.Cost,
+		"leads":           int64(metric.Leads),
+		"opportunities":   int64(metric.Opportunities),
+		"closed_won":      int64(metric.ClosedWon),
+		"revenue":         metric.Revenue,
+		"cpc":             metric.CPC,
+		"cpa":             metric.CPA,
+		"cvr_lead_to_opp": metric.CvrLeadToOpp,
+		"cvr_opp_to_won":  metric.CvrOppToWon,
+		"roas":            metric.Roas,
+	})
+}
+
+func (c *AvroCodec) EncodeRun(event RunEvent) ([]byte, error) {
+	return c.run.SingleFromNative(nil, map[string]interface{}{
+		"event":       event.Event,
+		"run_id":      event.RunID,
+		"tenant_id":   event.Tenant,
+		"count":       int64(event.Count),
+		"since":       event.Since,
+		"until":       event.Until,
+		"occurred_at": event.OccurredAt,
+	})
+}
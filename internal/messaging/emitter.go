@@ -0,0 +1,135 @@
+package messaging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+// Topics indica dónde va cada tipo de evento; un topic vacío desactiva ese tipo.
+type Topics struct {
+	Metrics string
+	Runs    string
+}
+
+// Emitter convierte cada ejecución guardada en mensajes y los deja en el outbox.
+// Implementa etl.RunObserver, que se notifica después de SaveMetrics.
+type Emitter struct {
+	outbox *Outbox
+	relay  *Relay
+	codec  Codec
+	topics Topics
+	logger *logrus.Logger
+}
+
+func NewEmitter(outbox *Outbox, relay *Relay, codec Codec, topics Topics, logger *logrus.Logger) *Emitter {
+	return &Emitter{outbox: outbox, relay: relay, codec: codec, topics: topics, logger: logger}
+}
+
+// Enqueue codifica la ejecución y la encola entera en un solo lote: un evento por
+// métrica y, al final, el evento de la ejecución.
+func (e *Emitter) Enqueue(result *etl.RunResult) (int, error) {
+	tenantID := result.Tenant
+	if tenantID == "" {
+		tenantID = tenant.Default
+	}
+	now := time.Now().UTC()
+
+	var entries []Entry
+	add := func(topic, key, eventType string, value []byte) {
+		id := newEventID()
+		entries = append(entries, Entry{
+			ID:        id,
+			CreatedAt: now,
+			Message: Message{
+				Topic: topic,
+				Key:   key,
+				Value: value,
+				Headers: map[string]string{
+					HeaderEventID:     id,
+					HeaderEventType:   eventType,
+					HeaderContentType: e.codec.ContentType(),
+				},
+			},
+		})
+	}
+
+	if e.topics.Metrics != "" {
+		for _, metric := range result.Metrics {
+			value, err := e.codec.EncodeMetric(MetricEvent{
+				Event:      EventMetricComputed,
+				RunID:      result.RunID,
+				Tenant:     tenantID,
+				OccurredAt: now,
+				Metric:     metric,
+			})
+			if err != nil {
+				return 0, fmt.Errorf("failed to encode metric %s/%s: %v", metric.Date, metric.CampaignID, err)
+			}
+			// La clave mantiene en orden, dentro de una partición, las versiones de una misma métrica
+			add(e.topics.Metrics, tenantID+"|"+metric.Date+"|"+metric.Channel+"|"+metric.CampaignID, EventMetricComputed, value)
+		}
+	}
+
+	if e.topics.Runs != "" {
+		value, err := e.codec.EncodeRun(RunEvent{
+			Event:      EventIngestionCompleted,
+			RunID:      result.RunID,
+			Tenant:     tenantID,
+			Count:      result.Count,
+			Since:      result.Since,
+			Until:      result.Until,
+			OccurredAt: now,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode run %s: %v", result.RunID, err)
+		}
+		add(e.topics.Runs, tenantID, EventIngestionCompleted, value)
+	}
+
+	if err := e.outbox.Add(entries); err != nil {
+		return 0, err
+	}
+	monitoring.MessagesPublished.WithLabelValues("enqueued").Add(float64(len(entries)))
+
+	if e.relay != nil {
+		e.relay.Notify()
+	}
+	return len(entries), nil
+}
+
+// RunFinished encola la ejecución. Si el outbox falla los mensajes se pierden, pero
+// la ingesta ya está guardada y no se deshace.
+func (e *Emitter) RunFinished(ctx context.Context, result *etl.RunResult) {
+	if result == nil || result.Unchanged {
+		return
+	}
+
+	count, err := e.Enqueue(result)
+	if err != nil {
+		tracing.Logger(ctx, e.logger).Errorf("Failed to enqueue messages for run %s: %v", result.RunID, err)
+		return
+	}
+	tracing.Logger(ctx, e.logger).Debugf("Enqueued %d messages for run %s", count, result.RunID)
+}
+
+// El resto de RunObserver no publica nada.
+func (e *Emitter) RunStarted(ctx context.Context, runID string) {}
+
+func (e *Emitter) StageCompleted(ctx context.Context, runID, stage string, duration time.Duration) {}
+
+func (e *Emitter) RunFailed(ctx context.Context, runID string, err error) {}
+
+func newEventID() string {
+	suffix := make([]byte, 12)
+	rand.Read(suffix)
+	return "evt_" + hex.EncodeToString(suffix)
+}
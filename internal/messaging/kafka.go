@@ -0,0 +1,106 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+type KafkaConfig struct {
+	Brokers  []string
+	ClientID string
+	// Timeout limita lo que espera cada lote a ser confirmado por el broker.
+	Timeout time.Duration
+}
+
+// KafkaPublisher produce con acks de todas las réplicas e idempotencia, de modo que
+// los reintentos del propio cliente no duplican mensajes dentro de una sesión.
+type KafkaPublisher struct {
+	options []kgo.Opt
+	timeout time.Duration
+	mu      sync.Mutex
+	client  *kgo.Client
+}
+
+func NewKafkaPublisher(config KafkaConfig) (*KafkaPublisher, error) {
+	if len(config.Brokers) == 0 {
+		return nil, fmt.Errorf("at least one Kafka broker is required")
+	}
+
+	options := []kgo.Opt{
+		kgo.SeedBrokers(config.Brokers...),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+	}
+	if config.ClientID != "" {
+		options = append(options, kgo.ClientID(config.ClientID))
+	}
+	if config.Timeout > 0 {
+		options = append(options, kgo.RecordDeliveryTimeout(config.Timeout))
+	}
+
+	client, err := kgo.NewClient(options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure Kafka client: %v", err)
+	}
+	return &KafkaPublisher{options: options, timeout: config.Timeout, client: client}, nil
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, messages []Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	records := make([]*kgo.Record, 0, len(messages))
+	for _, message := range messages {
+		record := &kgo.Record{Topic: message.Topic, Key: []byte(message.Key), Value: message.Value}
+		keys := make([]string, 0, len(message.Headers))
+		for key := range message.Headers {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			record.Headers = append(record.Headers, kgo.RecordHeader{Key: key, Value: []byte(message.Headers[key])})
+		}
+		records = append(records, record)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.client.ProduceSync(ctx, records...).FirstErr()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// Con idempotencia, los registros ya enviados no caducan mientras el broker no
+		// responda. Se cierra el cliente para descartarlos y se abre otro: el lote entero
+		// sigue en el outbox y se reenviará en el siguiente intento
+		p.client.Close()
+		<-done
+		err = ctx.Err()
+		if client, newErr := kgo.NewClient(p.options...); newErr == nil {
+			p.client = client
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to produce to Kafka: %v", err)
+	}
+	return nil
+}
+
+func (p *KafkaPublisher) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.client.Close()
+}
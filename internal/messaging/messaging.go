@@ -0,0 +1,75 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/admira-project/backend/internal/models"
+)
+
+const (
+	EventMetricComputed     = "metric.computed"
+	EventIngestionCompleted = "ingestion.completed"
+
+	// Cabeceras que acompañan a cada mensaje.
+	HeaderEventID     = "event-id"
+	HeaderEventType   = "event-type"
+	HeaderContentType = "content-type"
+)
+
+// Message es un mensaje listo para el broker: Value ya va codificado.
+type Message struct {
+	Topic   string            `json:"topic"`
+	Key     string            `json:"key"`
+	Value   []byte            `json:"value"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Publisher entrega mensajes a un broker. Publish sólo devuelve nil si el broker
+// ha confirmado todos los mensajes; ante un error el lote entero se reintenta.
+type Publisher interface {
+	Publish(ctx context.Context, messages []Message) error
+	Close()
+}
+
+// MetricEvent es una métrica calculada y guardada por una ejecución.
+type MetricEvent struct {
+	Event      string        `json:"event"`
+	RunID      string        `json:"run_id"`
+	Tenant     string        `json:"tenant_id"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	Metric     models.Metric `json:"metric"`
+}
+
+// RunEvent resume una ejecución guardada.
+type RunEvent struct {
+	Event      string    `json:"event"`
+	RunID      string    `json:"run_id"`
+	Tenant     string    `json:"tenant_id"`
+	Count      int       `json:"count"`
+	Since      string    `json:"since,omitempty"`
+	Until      string    `json:"until,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Codec serializa los eventos con un esquema concreto.
+type Codec interface {
+	ContentType() string
+	EncodeMetric(event MetricEvent) ([]byte, error)
+	EncodeRun(event RunEvent) ([]byte, error)
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+func (JSONCodec) EncodeMetric(event MetricEvent) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+func (JSONCodec) EncodeRun(event RunEvent) ([]byte, error) {
+	return json.Marshal(event)
+}
@@ -0,0 +1,231 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Entry struct {
+	ID        string    `json:"id"`
+	Message   Message   `json:"message"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type batch struct {
+	name    string
+	entries []Entry
+}
+
+// Outbox guarda los mensajes pendientes hasta que el broker los confirma. Cada Add es
+// un lote que se persiste en un único fichero (temporal + rename), así que una ejecución
+// queda encolada entera o no queda. Sin directorio sólo vive en memoria.
+type Outbox struct {
+	dir     string
+	mu      sync.Mutex
+	batches []*batch
+	seq     int64
+}
+
+func NewOutbox(dir string) (*Outbox, error) {
+	outbox := &Outbox{dir: dir}
+	if dir == "" {
+		return outbox, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %v", err)
+	}
+	if err := outbox.load(); err != nil {
+		return nil, err
+	}
+	return outbox, nil
+}
+
+// Add encola las entradas en orden como un único lote.
+func (o *Outbox) Add(entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.seq++
+	// El nombre ordena los lotes por llegada también tras reiniciar
+	b := &batch{name: fmt.Sprintf("%020d-%06d.json", time.Now().UnixNano(), o.seq%1000000), entries: entries}
+	if err := o.write(b); err != nil {
+		return err
+	}
+	o.batches = append(o.batches, b)
+	return nil
+}
+
+// Pending devuelve hasta limit entradas, las más antiguas primero.
+func (o *Outbox) Pending(limit int) []Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var pending []Entry
+	for _, b := range o.batches {
+		for _, entry := range b.entries {
+			if limit > 0 && len(pending) >= limit {
+				return pending
+			}
+			pending = append(pending, entry)
+		}
+	}
+	return pending
+}
+
+// Len devuelve el número de entradas pendientes.
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	count := 0
+	for _, b := range o.batches {
+		count += len(b.entries)
+	}
+	return count
+}
+
+// Ack retira las entradas confirmadas por el broker.
+func (o *Outbox) Ack(ids []string) error {
+	acked := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		acked[id] = true
+	}
+
+	return o.update(func(entry *Entry) bool {
+		return acked[entry.ID]
+	})
+}
+
+// Fail anota el intento fallido en las entradas, que siguen pendientes.
+func (o *Outbox) Fail(ids []string, err error) error {
+	failed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		failed[id] = true
+	}
+
+	return o.update(func(entry *Entry) bool {
+		if failed[entry.ID] {
+			entry.Attempts++
+			entry.LastError = err.Error()
+		}
+		return false
+	})
+}
+
+// update aplica fn a cada entrada y elimina las que devuelvan true. Los lotes que
+// cambian se reescriben y los que quedan vacíos se borran.
+func (o *Outbox) update(fn func(entry *Entry) bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	remaining := o.batches[:0]
+	var firstErr error
+	for _, b := range o.batches {
+		entries := make([]Entry, 0, len(b.entries))
+		changed := false
+		for _, entry := range b.entries {
+			before := entry
+			if fn(&entry) {
+				changed = true
+				continue
+			}
+			if entry.Attempts != before.Attempts {
+				changed = true
+			}
+			entries = append(entries, entry)
+		}
+		b.entries = entries
+
+		if changed {
+			var err error
+			if len(entries) == 0 {
+				err = o.remove(b)
+			} else {
+				err = o.write(b)
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		if len(entries) > 0 {
+			remaining = append(remaining, b)
+		}
+	}
+	o.batches = remaining
+	return firstErr
+}
+
+func (o *Outbox) load() error {
+	files, err := os.ReadDir(o.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read outbox: %v", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(o.dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read outbox batch %s: %v", name, err)
+		}
+		var entries []Entry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("failed to unmarshal outbox batch %s: %v", name, err)
+		}
+		if len(entries) > 0 {
+			o.batches = append(o.batches, &batch{name: name, entries: entries})
+		}
+	}
+	return nil
+}
+
+func (o *Outbox) write(b *batch) error {
+	if o.dir == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(b.entries)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(o.dir, b.name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write outbox batch: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write outbox batch: %v", err)
+	}
+	return nil
+}
+
+func (o *Outbox) remove(b *batch) error {
+	if o.dir == "" {
+		return nil
+	}
+
+	if err := os.Remove(filepath.Join(o.dir, b.name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove outbox batch: %v", err)
+	}
+	return nil
+}
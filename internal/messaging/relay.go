@@ -0,0 +1,149 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// Relay vacía el outbox hacia el publisher. Las entradas sólo se retiran cuando el
+// broker las confirma, así que la entrega es al menos una vez: tras un fallo o un
+// reinicio se reenvía el lote completo y los consumidores deben deduplicar por event-id.
+type Relay struct {
+	outbox    *Outbox
+	publisher Publisher
+	policy    utils.RetryPolicy
+	batchSize int
+	interval  time.Duration
+	logger    *logrus.Logger
+
+	// drainMu evita que Flush y el bucle publiquen el mismo lote a la vez
+	drainMu sync.Mutex
+	wake    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewRelay(outbox *Outbox, publisher Publisher, policy utils.RetryPolicy, batchSize int, interval time.Duration, logger *logrus.Logger) *Relay {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Relay{
+		outbox:    outbox,
+		publisher: publisher,
+		policy:    policy,
+		batchSize: batchSize,
+		interval:  interval,
+		logger:    logger,
+		wake:      make(chan struct{}, 1),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start arranca el bucle en segundo plano. Publica al recibir Notify y, además, cada
+// interval, para recoger lo que quedara pendiente de un arranque anterior.
+func (r *Relay) Start() {
+	r.wg.Add(1)
+	go r.loop()
+	r.Notify()
+}
+
+// Notify avisa de que hay entradas nuevas sin bloquear.
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Flush publica lo pendiente y devuelve el primer error.
+func (r *Relay) Flush(ctx context.Context) error {
+	return r.drain(ctx)
+}
+
+// Close para el bucle. Lo que no se haya confirmado sigue en el outbox.
+func (r *Relay) Close() {
+	r.cancel()
+	r.wg.Wait()
+	r.publisher.Close()
+}
+
+func (r *Relay) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+
+		err := r.drain(r.ctx)
+		if err == nil {
+			failures = 0
+			continue
+		}
+		if r.ctx.Err() != nil {
+			return
+		}
+
+		// Tras un fallo se respeta el backoff aunque lleguen avisos nuevos
+		delay := r.policy.Backoff(failures)
+		failures++
+		r.logger.Warnf("Failed to publish outbox messages (attempt %d), retrying in %v: %v", failures, delay, err)
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(delay):
+			r.Notify()
+		}
+	}
+}
+
+func (r *Relay) drain(ctx context.Context) error {
+	r.drainMu.Lock()
+	defer r.drainMu.Unlock()
+
+	for {
+		entries := r.outbox.Pending(r.batchSize)
+		if len(entries) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(entries))
+		messages := make([]Message, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+			messages[i] = entry.Message
+		}
+
+		if err := r.publisher.Publish(ctx, messages); err != nil {
+			monitoring.MessagesPublished.WithLabelValues("failed").Add(float64(len(messages)))
+			if failErr := r.outbox.Fail(ids, err); failErr != nil {
+				r.logger.Errorf("Failed to record outbox failure: %v", failErr)
+			}
+			return err
+		}
+
+		monitoring.MessagesPublished.WithLabelValues("published").Add(float64(len(messages)))
+		if err := r.outbox.Ack(ids); err != nil {
+			// Los mensajes ya están en el broker; si no se retiran se reenviarán
+			return err
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package models
+
+type Campaign struct {
+	CampaignID   string   `json:"campaign_id"`
+	Name         string   `json:"name"`
+	Owner        string   `json:"owner"`
+	Objective    string   `json:"objective"`
+	BusinessUnit string   `json:"business_unit"`
+	Tags         []string `json:"tags"`
+}
@@ -1,31 +1,77 @@
 package models
 
 type Metric struct {
-	Date          string  `json:"date"`
-	Channel       string  `json:"channel"`
-	CampaignID    string  `json:"campaign_id"`
-	UtmCampaign   string  `json:"utm_campaign"`
-	UtmSource     string  `json:"utm_source"`
-	UtmMedium     string  `json:"utm_medium"`
-	Clicks        int     `json:"clicks"`
-	Impressions   int     `json:"impressions"`
-	Cost          float64 `json:"cost"`
-	Leads         int     `json:"leads"`
-	Opportunities int     `json:"opportunities"`
-	ClosedWon     int     `json:"closed_won"`
-	Revenue       float64 `json:"revenue"`
-	CPC           float64 `json:"cpc"`
-	CPA           float64 `json:"cpa"`
-	CvrLeadToOpp  float64 `json:"cvr_lead_to_opp"`
-	CvrOppToWon   float64 `json:"cvr_opp_to_won"`
-	Roas          float64 `json:"roas"`
+	TenantID      string   `json:"tenant_id,omitempty"`
+	RunID         string   `json:"run_id,omitempty"`
+	Date          string   `json:"date"`
+	Channel       string   `json:"channel"`
+	CampaignID    string   `json:"campaign_id"`
+	CampaignName  string   `json:"campaign_name,omitempty"`
+	Owner         string   `json:"owner,omitempty"`
+	Objective     string   `json:"objective,omitempty"`
+	BusinessUnit  string   `json:"business_unit,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	UtmCampaign   string   `json:"utm_campaign"`
+	UtmSource     string   `json:"utm_source"`
+	UtmMedium     string   `json:"utm_medium"`
+	Clicks        int      `json:"clicks"`
+	Impressions   int      `json:"impressions"`
+	Cost          float64  `json:"cost"`
+	Leads         int      `json:"leads"`
+	Opportunities int      `json:"opportunities"`
+	ClosedWon     int      `json:"closed_won"`
+	Revenue       float64  `json:"revenue"`
+	CPC           float64  `json:"cpc"`
+	CPA           float64  `json:"cpa"`
+	CvrLeadToOpp  float64  `json:"cvr_lead_to_opp"`
+	CvrOppToWon   float64  `json:"cvr_opp_to_won"`
+	Roas          float64  `json:"roas"`
 }
 
 type MetricsRequest struct {
-	From        string `json:"from"`
-	To          string `json:"to"`
-	Channel     string `json:"channel"`
-	UtmCampaign string `json:"utm_campaign"`
+	TenantID     string `json:"tenant_id"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+	Channel      string `json:"channel"`
+	UtmCampaign  string `json:"utm_campaign"`
+	CampaignName string `json:"campaign_name"`
+	Owner        string `json:"owner"`
+	BusinessUnit string `json:"business_unit"`
+	Tag          string `json:"tag"`
+	GroupBy      string `json:"group_by"`
+	// Granularity es day (por defecto), week o month
+	Granularity string `json:"granularity"`
 	Limit       int    `json:"limit"`
 	Offset      int    `json:"offset"`
 }
+
+type MetricGroup struct {
+	GroupBy string `json:"group_by"`
+	Key     string `json:"key"`
+	Metric
+}
+
+// ComputeDerived recalcula los ratios a partir de los contadores acumulados.
+func (m *Metric) ComputeDerived() {
+	m.CPC, m.CPA, m.CvrLeadToOpp, m.CvrOppToWon, m.Roas = 0, 0, 0, 0, 0
+
+	if m.Clicks > 0 {
+		m.CPC = m.Cost / float64(m.Clicks)
+	}
+
+	if m.Leads > 0 {
+		m.CPA = m.Cost / float64(m.Leads)
+	}
+
+	if m.Leads > 0 {
+		m.CvrLeadToOpp = float64(m.Opportunities) / float64(m.Leads)
+	}
+
+	if m.Opportunities > 0 {
+		m.CvrOppToWon = float64(m.ClosedWon) / float64(m.Opportunities)
+	}
+
+	if m.Cost > 0 {
+		m.Roas = m.Revenue / m.Cost
+	}
+}
@@ -0,0 +1,113 @@
+package monitoring
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "admira"
+
+// Registry propio para no mezclar con el registro global de otras librerías.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests served by the API.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	ETLStageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "etl_stage_duration_seconds",
+		Help:      "Duration of each ETL stage.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"stage"})
+
+	ETLRecords = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "etl_records_total",
+		Help:      "Records processed by the ETL pipeline by source and outcome (extracted, rejected, saved).",
+	}, []string{"source", "outcome"})
+
+	ETLRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "etl_runs_total",
+		Help:      "Ingestion runs by result (success, failure, unchanged, conflict).",
+	}, []string{"result"})
+
+	LastSuccessfulIngestion = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "etl_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful ingestion run.",
+	})
+
+	HTTPClientRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_client_retries_total",
+		Help:      "Retry attempts issued by the source HTTP client by host and reason.",
+	}, []string{"host", "reason"})
+
+	WebhookDeliveryAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "Outbound webhook delivery attempts by outcome (delivered, retry, failed).",
+	}, []string{"outcome"})
+
+	LakeExports = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lake_exports_total",
+		Help:      "Ingestion runs exported to the data lake by result (success, failure).",
+	}, []string{"result"})
+
+	MessagesPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_published_total",
+		Help:      "Outbox messages sent to the message broker by outcome (enqueued, published, failed).",
+	}, []string{"outcome"})
+
+	IngestJobs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ingest_jobs_total",
+		Help:      "Queued ingestion jobs by result (enqueued, completed, failed).",
+	}, []string{"result"})
+
+	RetentionRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retention_runs_total",
+		Help:      "Retention policy passes by result (success, failure).",
+	}, []string{"result"})
+
+	RetentionCompacted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retention_compacted_metrics_total",
+		Help:      "Daily metrics rolled up into aggregates and removed by the retention policy.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		ETLStageDuration,
+		ETLRecords,
+		ETLRuns,
+		LastSuccessfulIngestion,
+		HTTPClientRetries,
+		WebhookDeliveryAttempts,
+		LakeExports,
+		MessagesPublished,
+		IngestJobs,
+		RetentionRuns,
+		RetentionCompacted,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,56 @@
+package monitoring
+
+import "github.com/prometheus/client_golang/prometheus"
+
+type SourceState struct {
+	Host       string
+	State      string
+	Tokens     float64
+	HasLimiter bool
+}
+
+var circuitStates = []string{"closed", "half_open", "open"}
+
+type sourceCollector struct {
+	states     func() []SourceState
+	stateDesc  *prometheus.Desc
+	tokensDesc *prometheus.Desc
+}
+
+// RegisterSourceStates expone el estado de los circuit breakers y rate limiters de las fuentes.
+func RegisterSourceStates(states func() []SourceState) error {
+	return Registry.Register(&sourceCollector{
+		states: states,
+		stateDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "source", "circuit_state"),
+			"Circuit breaker state per source host (1 for the current state).",
+			[]string{"host", "state"}, nil,
+		),
+		tokensDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "source", "rate_limit_tokens"),
+			"Tokens currently available in the source host rate limiter.",
+			[]string{"host"}, nil,
+		),
+	})
+}
+
+func (c *sourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.stateDesc
+	ch <- c.tokensDesc
+}
+
+func (c *sourceCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, source := range c.states() {
+		for _, state := range circuitStates {
+			value := 0.0
+			if state == source.State {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.stateDesc, prometheus.GaugeValue, value, source.Host, state)
+		}
+
+		if source.HasLimiter {
+			ch <- prometheus.MustNewConstMetric(c.tokensDesc, prometheus.GaugeValue, source.Tokens, source.Host)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// DefaultMaxBodyBytes es el tamaño máximo de cuerpo que se lee para validar una petición.
+const DefaultMaxBodyBytes = 10 << 20
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+
+	maxBodyBytes int64
+}
+
+// PathItem indexa las operaciones de una ruta por método en minúsculas.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []*Parameter `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Pattern    string             `json:"pattern"`
+	Enum       []interface{}      `json:"enum"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+	MinLength  *int               `json:"minLength"`
+	Required   []string           `json:"required"`
+	Properties map[string]*Schema `json:"properties"`
+	Items      *Schema            `json:"items"`
+	AllOf      []*Schema          `json:"allOf"`
+}
+
+type Components struct {
+	Parameters map[string]*Parameter `json:"parameters"`
+	Schemas    map[string]*Schema    `json:"schemas"`
+}
+
+// Spec devuelve el documento OpenAPI tal y como se publica.
+func Spec() []byte {
+	return spec
+}
+
+func Load() (*Document, error) {
+	return Parse(spec)
+}
+
+// Parse decodifica un documento OpenAPI 3 y resuelve las referencias a parámetros.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %v", err)
+	}
+
+	for path, item := range doc.Paths {
+		for method, operation := range item {
+			for i, param := range operation.Parameters {
+				if param.Ref == "" {
+					continue
+				}
+				resolved, ok := doc.Components.Parameters[strings.TrimPrefix(param.Ref, "#/components/parameters/")]
+				if !ok {
+					return nil, fmt.Errorf("%s %s: unresolved parameter %s", method, path, param.Ref)
+				}
+				operation.Parameters[i] = resolved
+			}
+		}
+	}
+
+	doc.maxBodyBytes = DefaultMaxBodyBytes
+	return &doc, nil
+}
+
+// SetMaxBodyBytes fija el tamaño máximo de los cuerpos; uno mayor se rechaza sin leerlo entero.
+func (d *Document) SetMaxBodyBytes(limit int64) {
+	d.maxBodyBytes = limit
+}
+
+// Operation busca la operación de una plantilla de ruta, sin el prefijo de versión.
+func (d *Document) Operation(method, path string) (*Operation, bool) {
+	operation, ok := d.Paths[path][strings.ToLower(method)]
+	return operation, ok && operation != nil
+}
+
+func (d *Document) schema(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(spec)
+	})
+}
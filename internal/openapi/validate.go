@@ -0,0 +1,324 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FieldError describe un parámetro o campo del cuerpo que no cumple la especificación.
+type FieldError struct {
+	In     string `json:"in"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// ReasonBodyTooLarge es el motivo del error de un cuerpo que supera el máximo.
+const ReasonBodyTooLarge = "exceeds the maximum size"
+
+var (
+	patternsMu sync.Mutex
+	patterns   = make(map[string]*regexp.Regexp)
+)
+
+// ValidateRequest comprueba los parámetros de ruta y query y, si es JSON, el cuerpo.
+// El cuerpo se vuelve a dejar disponible para el handler.
+func (d *Document) ValidateRequest(operation *Operation, r *http.Request, pathParams map[string]string) []FieldError {
+	var errs []FieldError
+
+	query := r.URL.Query()
+	for _, param := range operation.Parameters {
+		var raw string
+		var present bool
+
+		switch param.In {
+		case "path":
+			raw, present = pathParams[param.Name]
+		case "query":
+			if values, ok := query[param.Name]; ok && len(values) > 0 {
+				raw, present = values[0], true
+			}
+		case "header":
+			raw = r.Header.Get(param.Name)
+			present = raw != ""
+		default:
+			continue
+		}
+
+		if !present {
+			if param.Required {
+				errs = append(errs, FieldError{In: param.In, Name: param.Name, Reason: "is required"})
+			}
+			continue
+		}
+
+		value, err := parseParam(d.schema(param.Schema), raw)
+		if err == nil {
+			err = d.validateValue(d.schema(param.Schema), value)
+		}
+		if err != nil {
+			errs = append(errs, FieldError{In: param.In, Name: param.Name, Reason: err.Error()})
+		}
+	}
+
+	// Un parámetro no declarado se rechaza en lugar de ignorarlo: suele ser una errata
+	declared := make(map[string]bool)
+	for _, param := range operation.Parameters {
+		if param.In == "query" {
+			declared[param.Name] = true
+		}
+	}
+	var unknown []string
+	for name := range query {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, FieldError{In: "query", Name: name, Reason: "is not a known parameter"})
+	}
+
+	if operation.RequestBody != nil {
+		errs = append(errs, d.validateBody(operation.RequestBody, r)...)
+	}
+
+	return errs
+}
+
+func (d *Document) validateBody(body *RequestBody, r *http.Request) []FieldError {
+	reader := r.Body
+	if d.maxBodyBytes > 0 {
+		reader = http.MaxBytesReader(nil, r.Body, d.maxBodyBytes)
+	}
+
+	data, err := io.ReadAll(reader)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return []FieldError{{In: "body", Reason: fmt.Sprintf("%s of %d bytes", ReasonBodyTooLarge, tooLarge.Limit)}}
+	}
+	if err != nil {
+		return []FieldError{{In: "body", Reason: "could not be read"}}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			return []FieldError{{In: "body", Reason: "is required"}}
+		}
+		return nil
+	}
+
+	// Sólo se valida el esquema de los cuerpos JSON; CSV y demás pasan tal cual
+	media, ok := body.Content["application/json"]
+	if !ok || media.Schema == nil {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []FieldError{{In: "body", Reason: "is not valid JSON"}}
+	}
+
+	var errs []FieldError
+	d.validateJSON(media.Schema, value, "", &errs)
+	return errs
+}
+
+func (d *Document) validateJSON(s *Schema, value interface{}, path string, errs *[]FieldError) {
+	s = d.schema(s)
+	if s == nil || value == nil {
+		return
+	}
+
+	for _, part := range s.AllOf {
+		d.validateJSON(part, value, path, errs)
+	}
+
+	fail := func(reason string) {
+		*errs = append(*errs, FieldError{In: "body", Name: path, Reason: reason})
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				*errs = append(*errs, FieldError{In: "body", Name: join(path, name), Reason: "is required"})
+			}
+		}
+
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if field, ok := object[name]; ok {
+				d.validateJSON(s.Properties[name], field, join(path, name), errs)
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		for i, item := range items {
+			d.validateJSON(s.Items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			fail("must be " + typeName(s.Type))
+			return
+		}
+		converted, err := parseParam(s, number.String())
+		if err == nil {
+			err = d.validateValue(s, converted)
+		}
+		if err != nil {
+			fail(err.Error())
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if err := d.validateValue(s, text); err != nil {
+			fail(err.Error())
+		}
+	}
+}
+
+// parseParam convierte el texto de un parámetro al tipo declarado en su esquema.
+func parseParam(s *Schema, raw string) (interface{}, error) {
+	if s == nil {
+		return raw, nil
+	}
+
+	switch s.Type {
+	case "integer":
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		return float64(value), nil
+	case "number":
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return value, nil
+	case "boolean":
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("must be a boolean")
+		}
+		return value, nil
+	}
+
+	return raw, nil
+}
+
+func (d *Document) validateValue(s *Schema, value interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		return fmt.Errorf("must be one of %s", enumList(s.Enum))
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Errorf("must be >= %s", strconv.FormatFloat(*s.Minimum, 'f', -1, 64))
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("must be <= %s", strconv.FormatFloat(*s.Maximum, 'f', -1, 64))
+		}
+	case string:
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			return fmt.Errorf("must have at least %d characters", *s.MinLength)
+		}
+		switch s.Format {
+		case "date":
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				return fmt.Errorf("must be a date in YYYY-MM-DD format")
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				return fmt.Errorf("must be an RFC 3339 date-time")
+			}
+		}
+		if s.Pattern != "" && !compile(s.Pattern).MatchString(v) {
+			return fmt.Errorf("must match %s", s.Pattern)
+		}
+	}
+
+	return nil
+}
+
+func compile(pattern string) *regexp.Regexp {
+	patternsMu.Lock()
+	defer patternsMu.Unlock()
+
+	re, ok := patterns[pattern]
+	if !ok {
+		re = regexp.MustCompile(pattern)
+		patterns[pattern] = re
+	}
+	return re
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		values[i] = fmt.Sprint(value)
+	}
+	return fmt.Sprintf("%v", values)
+}
+
+func typeName(t string) string {
+	if t == "integer" {
+		return "an integer"
+	}
+	return "a " + t
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,294 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	pendingDir = "pending"
+	runningDir = "running"
+	doneDir    = "done"
+)
+
+// pollInterval es cada cuánto mira un worker ocioso si hay trabajos nuevos.
+var pollInterval = 250 * time.Millisecond
+
+// FileQueue guarda cada trabajo como un fichero en pending/, running/ o done/ dentro de
+// un directorio compartido, de modo que la API y los workers pueden ser procesos distintos
+// y los trabajos pendientes sobreviven a un reinicio.
+// Un worker toma un trabajo con un rename de pending/ a running/: si dos lo intentan a la
+// vez, sólo uno lo consigue. Si un worker deja de renovar su trabajo durante lease, otro
+// lo devuelve a la cola; tras maxAttempts intentos se da por fallido. Los trabajos de
+// done/ se borran al superar la retención.
+type FileQueue struct {
+	dir         string
+	lease       time.Duration
+	maxAttempts int
+	retention   time.Duration
+}
+
+func NewFileQueue(dir string, lease time.Duration, maxAttempts int) (*FileQueue, error) {
+	if lease <= 0 {
+		lease = 5 * time.Minute
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+
+	for _, sub := range []string{pendingDir, runningDir, doneDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create queue directory: %v", err)
+		}
+	}
+	return &FileQueue{dir: dir, lease: lease, maxAttempts: maxAttempts, retention: defaultRetention}, nil
+}
+
+// SetRetention cambia cuánto se conservan los trabajos terminados; 0 los conserva siempre.
+func (q *FileQueue) SetRetention(retention time.Duration) {
+	q.retention = retention
+}
+
+func (q *FileQueue) Enqueue(job *Job) error {
+	prepare(job)
+	q.pruneDone()
+	return q.write(pendingDir, job)
+}
+
+func (q *FileQueue) Claim(ctx context.Context, worker string) (*Job, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		q.reclaimExpired()
+
+		job, err := q.claimNext(worker)
+		if err != nil || job != nil {
+			return job, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *FileQueue) claimNext(worker string) (*Job, error) {
+	ids, err := q.list(pendingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if err := os.Rename(q.path(pendingDir, id), q.path(runningDir, id)); err != nil {
+			// Otro worker se lo ha llevado antes
+			continue
+		}
+
+		job, err := q.read(runningDir, id)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		leaseUntil := now.Add(q.lease)
+		job.Status = StatusRunning
+		job.Attempts++
+		job.Worker = worker
+		job.StartedAt = &now
+		job.UpdatedAt = now
+		job.LeaseUntil = &leaseUntil
+		if err := q.write(runningDir, job); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+	return nil, nil
+}
+
+// reclaimExpired devuelve a la cola los trabajos de workers que han dejado de renovarlos.
+func (q *FileQueue) reclaimExpired() {
+	ids, err := q.list(runningDir)
+	if err != nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, id := range ids {
+		job, err := q.read(runningDir, id)
+		if err != nil || job.LeaseUntil == nil || job.LeaseUntil.After(now) {
+			continue
+		}
+
+		job.LeaseUntil = nil
+		job.Worker = ""
+		job.UpdatedAt = now
+		if job.Attempts >= q.maxAttempts {
+			job.Status = StatusFailed
+			job.Error = fmt.Sprintf("worker lease expired after %d attempts", job.Attempts)
+			job.FinishedAt = &now
+			q.move(runningDir, doneDir, job)
+			continue
+		}
+		job.Status = StatusPending
+		q.move(runningDir, pendingDir, job)
+	}
+}
+
+func (q *FileQueue) Renew(job *Job) error {
+	current, err := q.read(runningDir, job.ID)
+	if err != nil {
+		return err
+	}
+	if current.Worker != job.Worker {
+		return fmt.Errorf("job %s was reclaimed by another worker", job.ID)
+	}
+
+	leaseUntil := time.Now().UTC().Add(q.lease)
+	job.LeaseUntil = &leaseUntil
+	return q.write(runningDir, job)
+}
+
+func (q *FileQueue) Finish(job *Job) error {
+	job.LeaseUntil = nil
+	job.UpdatedAt = time.Now().UTC()
+	return q.move(runningDir, doneDir, job)
+}
+
+func (q *FileQueue) Requeue(job *Job) error {
+	job.Status = StatusPending
+	job.Worker = ""
+	job.LeaseUntil = nil
+	job.UpdatedAt = time.Now().UTC()
+	return q.move(runningDir, pendingDir, job)
+}
+
+// Get busca en el orden en que avanzan los trabajos para no perder uno que se está moviendo.
+func (q *FileQueue) Get(id string) (*Job, error) {
+	if strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+
+	for _, sub := range []string{pendingDir, runningDir, doneDir} {
+		job, err := q.read(sub, id)
+		if errors.Is(err, ErrJobNotFound) {
+			continue
+		}
+		if err == nil && sub == doneDir && expired(job, q.retention, time.Now().UTC()) {
+			os.Remove(q.path(doneDir, id))
+			break
+		}
+		return job, err
+	}
+	return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+}
+
+// pruneDone borra de done/ los trabajos que han superado la retención. Usa la fecha de
+// modificación del fichero, que es cuando terminó el trabajo, para no tener que leerlos.
+func (q *FileQueue) pruneDone() {
+	if q.retention <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(filepath.Join(q.dir, doneDir))
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if now.Sub(info.ModTime()) > q.retention {
+			os.Remove(filepath.Join(q.dir, doneDir, entry.Name()))
+		}
+	}
+}
+
+// move escribe el estado nuevo en el destino y después retira el fichero de origen.
+func (q *FileQueue) move(from, to string, job *Job) error {
+	if err := q.write(to, job); err != nil {
+		return err
+	}
+	if err := os.Remove(q.path(from, job.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to move job %s: %v", job.ID, err)
+	}
+	return nil
+}
+
+func (q *FileQueue) list(sub string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(q.dir, sub))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue: %v", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasSuffix(name, ".json") {
+			ids = append(ids, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (q *FileQueue) read(sub, id string) (*Job, error) {
+	data, err := os.ReadFile(q.path(sub, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job %s: %v", id, err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job %s: %v", id, err)
+	}
+	return &job, nil
+}
+
+func (q *FileQueue) write(sub string, job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Temporal único: un worker puede renovar mientras otro intenta retomar el trabajo
+	path := q.path(sub, job.ID)
+	tmp, err := os.CreateTemp(filepath.Dir(path), job.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write job %s: %v", job.ID, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write job %s: %v", job.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write job %s: %v", job.ID, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write job %s: %v", job.ID, err)
+	}
+	return nil
+}
+
+func (q *FileQueue) path(sub, id string) string {
+	return filepath.Join(q.dir, sub, id+".json")
+}
@@ -0,0 +1,151 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryQueue vive en el proceso: sirve para workers dentro de la API y para tests.
+// Si el proceso se para, los trabajos pendientes se pierden. Los terminados se borran
+// al superar la retención.
+type MemoryQueue struct {
+	mu        sync.Mutex
+	jobs      map[string]*Job
+	pending   []string
+	ready     chan struct{}
+	retention time.Duration
+}
+
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		jobs:      make(map[string]*Job),
+		ready:     make(chan struct{}, 1),
+		retention: defaultRetention,
+	}
+}
+
+// SetRetention cambia cuánto se conservan los trabajos terminados; 0 los conserva siempre.
+func (q *MemoryQueue) SetRetention(retention time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.retention = retention
+}
+
+func (q *MemoryQueue) Enqueue(job *Job) error {
+	prepare(job)
+
+	q.mu.Lock()
+	q.prune(time.Now().UTC())
+	stored := *job
+	q.jobs[job.ID] = &stored
+	q.pending = append(q.pending, job.ID)
+	q.mu.Unlock()
+
+	q.signal()
+	return nil
+}
+
+func (q *MemoryQueue) Claim(ctx context.Context, worker string) (*Job, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		q.mu.Lock()
+		if len(q.pending) > 0 {
+			id := q.pending[0]
+			q.pending = q.pending[1:]
+			more := len(q.pending) > 0
+
+			job := q.jobs[id]
+			now := time.Now().UTC()
+			job.Status = StatusRunning
+			job.Attempts++
+			job.Worker = worker
+			job.StartedAt = &now
+			job.UpdatedAt = now
+			claimed := *job
+			q.mu.Unlock()
+
+			// Otro worker puede estar esperando al siguiente
+			if more {
+				q.signal()
+			}
+			return &claimed, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.ready:
+		}
+	}
+}
+
+// Renew no hace nada: en memoria un trabajo sólo se abandona si cae el proceso entero.
+func (q *MemoryQueue) Renew(job *Job) error {
+	return nil
+}
+
+func (q *MemoryQueue) Finish(job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.jobs[job.ID]; !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, job.ID)
+	}
+	stored := *job
+	stored.UpdatedAt = time.Now().UTC()
+	q.jobs[job.ID] = &stored
+	return nil
+}
+
+func (q *MemoryQueue) Requeue(job *Job) error {
+	q.mu.Lock()
+	stored, ok := q.jobs[job.ID]
+	if !ok {
+		q.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobNotFound, job.ID)
+	}
+	stored.Status = StatusPending
+	stored.Worker = ""
+	stored.UpdatedAt = time.Now().UTC()
+	q.pending = append([]string{job.ID}, q.pending...)
+	q.mu.Unlock()
+
+	q.signal()
+	return nil
+}
+
+func (q *MemoryQueue) Get(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok || expired(job, q.retention, time.Now().UTC()) {
+		delete(q.jobs, id)
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// prune borra los trabajos terminados que han superado la retención. Se llama al encolar,
+// que es lo único que hace crecer la cola.
+func (q *MemoryQueue) prune(now time.Time) {
+	for id, job := range q.jobs {
+		if expired(job, q.retention, now) {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+func (q *MemoryQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
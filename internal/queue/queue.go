@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+var ErrJobNotFound = errors.New("ingestion job not found")
+
+// defaultRetention es cuánto se conservan los trabajos terminados para consultarlos con Get.
+const defaultRetention = 24 * time.Hour
+
+// Job es una petición de ingesta encolada y, cuando termina, su resultado.
+type Job struct {
+	ID     string `json:"id"`
+	Tenant string `json:"tenant_id"`
+	Since  string `json:"since,omitempty"`
+	Force  bool   `json:"force,omitempty"`
+	Status string `json:"status"`
+	// Attempts cuenta las veces que un worker lo ha tomado
+	Attempts   int        `json:"attempts"`
+	Worker     string     `json:"worker,omitempty"`
+	RunID      string     `json:"run_id,omitempty"`
+	Count      int        `json:"count"`
+	Unchanged  bool       `json:"unchanged,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// LeaseUntil es el plazo que tiene el worker para renovar el trabajo antes de que otro lo retome
+	LeaseUntil *time.Time `json:"lease_until,omitempty"`
+}
+
+// Queue reparte las peticiones de ingesta entre workers. Claim bloquea hasta que hay
+// un trabajo o se cancela ctx; Renew alarga el plazo de un trabajo en curso; Finish
+// guarda el estado final y Requeue lo devuelve a la cola sin contar como fallo.
+type Queue interface {
+	Enqueue(job *Job) error
+	Claim(ctx context.Context, worker string) (*Job, error)
+	Renew(job *Job) error
+	Finish(job *Job) error
+	Requeue(job *Job) error
+	Get(id string) (*Job, error)
+}
+
+// expired indica si un trabajo terminado ya ha superado la retención; 0 los conserva siempre.
+func expired(job *Job, retention time.Duration, now time.Time) bool {
+	if retention <= 0 || (job.Status != StatusCompleted && job.Status != StatusFailed) {
+		return false
+	}
+	return now.Sub(job.UpdatedAt) > retention
+}
+
+// prepare completa un trabajo nuevo antes de encolarlo.
+func prepare(job *Job) {
+	now := time.Now().UTC()
+	job.ID = newJobID(now)
+	job.Status = StatusPending
+	job.Attempts = 0
+	job.CreatedAt = now
+	job.UpdatedAt = now
+}
+
+// newJobID empieza por la hora para que el orden alfabético sea el de llegada.
+func newJobID(now time.Time) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return "ing-" + strings.Replace(now.Format("20060102T150405.000000Z"), ".", "", 1) + "-" + hex.EncodeToString(suffix)
+}
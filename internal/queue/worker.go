@@ -0,0 +1,146 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+type Runner interface {
+	RunRange(ctx context.Context, window etl.DateRange) (*etl.RunResult, error)
+	ForceRunRange(ctx context.Context, window etl.DateRange) (*etl.RunResult, error)
+}
+
+// Worker toma trabajos de la cola y los ejecuta con el pipeline
+// (Extractor → Transformer → Storage) hasta que se cancela su contexto.
+type Worker struct {
+	queue     Queue
+	runner    Runner
+	name      string
+	heartbeat time.Duration
+	logger    *logrus.Logger
+}
+
+func NewWorker(queue Queue, runner Runner, name string, logger *logrus.Logger) *Worker {
+	return &Worker{queue: queue, runner: runner, name: name, heartbeat: time.Minute, logger: logger}
+}
+
+// SetHeartbeat cambia cada cuánto se renueva el trabajo en curso; debe ser menor que el lease de la cola.
+func (w *Worker) SetHeartbeat(interval time.Duration) {
+	w.heartbeat = interval
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	w.logger.Infof("Ingestion worker %s started", w.name)
+	for {
+		job, err := w.queue.Claim(ctx, w.name)
+		if ctx.Err() != nil {
+			w.logger.Infof("Ingestion worker %s stopped", w.name)
+			return
+		}
+		if err != nil {
+			w.logger.Errorf("Worker %s failed to claim a job: %v", w.name, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		w.Process(ctx, job)
+	}
+}
+
+// Process ejecuta un trabajo ya tomado y guarda el resultado en la cola. Si el worker se
+// para a mitad, el trabajo vuelve a la cola para que lo retome otro.
+func (w *Worker) Process(ctx context.Context, job *Job) {
+	logger := w.logger.WithFields(logrus.Fields{"job_id": job.ID, "tenant_id": job.Tenant, "worker": w.name})
+	logger.Infof("Processing ingestion job (attempt %d)", job.Attempts)
+
+	runCtx, cancel := context.WithCancel(tenant.WithID(ctx, job.Tenant))
+	defer cancel()
+	lost := make(chan bool, 1)
+	go func() {
+		lost <- w.keepAlive(runCtx, cancel, job, logger)
+	}()
+
+	result, err := w.run(runCtx, job)
+	cancel()
+
+	// Otro worker lo ha retomado y es quien guardará el resultado
+	if <-lost {
+		return
+	}
+	if ctx.Err() != nil {
+		if err := w.queue.Requeue(job); err != nil {
+			logger.Errorf("Failed to requeue interrupted job: %v", err)
+		}
+		return
+	}
+
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		monitoring.IngestJobs.WithLabelValues("failed").Inc()
+		logger.Errorf("Ingestion job failed: %v", err)
+	} else {
+		job.Status = StatusCompleted
+		job.Error = ""
+		job.RunID = result.RunID
+		job.Count = result.Count
+		job.Unchanged = result.Unchanged
+		monitoring.IngestJobs.WithLabelValues("completed").Inc()
+		logger.Infof("Ingestion job completed: run %s, %d records", result.RunID, result.Count)
+	}
+
+	if err := w.queue.Finish(job); err != nil {
+		logger.Errorf("Failed to store job result: %v", err)
+	}
+}
+
+func (w *Worker) run(ctx context.Context, job *Job) (*etl.RunResult, error) {
+	var window etl.DateRange
+	if job.Since != "" {
+		since, err := time.Parse("2006-01-02", job.Since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid since %q: %v", job.Since, err)
+		}
+		window.From = since
+	}
+
+	if job.Force {
+		return w.runner.ForceRunRange(ctx, window)
+	}
+	return w.runner.RunRange(ctx, window)
+}
+
+// keepAlive renueva el trabajo mientras dura la ejecución. Si otro worker lo ha retomado,
+// cancela la ejecución propia para no guardar dos veces el mismo resultado.
+func (w *Worker) keepAlive(ctx context.Context, cancel context.CancelFunc, job *Job, logger *logrus.Entry) bool {
+	if w.heartbeat <= 0 {
+		return false
+	}
+
+	ticker := time.NewTicker(w.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			if err := w.queue.Renew(job); err != nil {
+				logger.Warnf("Lost ingestion job lease, cancelling: %v", err)
+				cancel()
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package retention
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// Manager aplica la política de retención: las métricas diarias con más de maxAge días
+// se suman a los agregados de cada granularidad y después se borran.
+type Manager struct {
+	store         storage.RollupStore
+	maxAge        int
+	granularities []string
+	logger        *logrus.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewManager(store storage.RollupStore, maxAgeDays int, granularities []string, logger *logrus.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		store:         store,
+		maxAge:        maxAgeDays,
+		granularities: granularities,
+		logger:        logger,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// Cutoff es el primer día que conserva detalle diario.
+func (m *Manager) Cutoff(now time.Time) string {
+	return now.UTC().AddDate(0, 0, -m.maxAge).Format("2006-01-02")
+}
+
+// Apply compacta una vez lo anterior al corte y devuelve cuántas métricas diarias ha borrado.
+func (m *Manager) Apply(now time.Time) (int, error) {
+	cutoff := m.Cutoff(now)
+	removed, err := m.store.Compact(cutoff, m.granularities)
+	if err != nil {
+		monitoring.RetentionRuns.WithLabelValues("failure").Inc()
+		return 0, err
+	}
+
+	monitoring.RetentionRuns.WithLabelValues("success").Inc()
+	monitoring.RetentionCompacted.Add(float64(removed))
+	if removed > 0 {
+		m.logger.Infof("Rolled up and removed %d daily metrics older than %s", removed, cutoff)
+	}
+	return removed, nil
+}
+
+// Start aplica la política al arrancar y después cada interval.
+func (m *Manager) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := m.Apply(time.Now()); err != nil {
+				m.logger.Errorf("Failed to apply retention policy: %v", err)
+			}
+
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close para el bucle; una compactación en curso termina antes.
+func (m *Manager) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
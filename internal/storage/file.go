@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/admira-project/backend/internal/models"
+)
+
+// FileStorage guarda las métricas en un snapshot dentro de dir que comparten todos los
+// procesos que usan el mismo directorio, como la API y los workers de la cola. Cada proceso
+// consulta una copia en memoria y la recarga cuando otro ha reescrito el fichero. Las
+// escrituras se serializan con flock y reescriben el snapshot de forma atómica, así que
+// están pensadas para lotes (una ingesta), no para escrituras de una en una.
+type FileStorage struct {
+	dir string
+
+	mu     sync.Mutex
+	mem    *MemoryStorage
+	loaded os.FileInfo
+}
+
+// snapshot es el contenido de MemoryStorage tal y como se guarda en disco.
+type snapshot struct {
+	Partitions      map[string][]models.Metric            `json:"partitions"`
+	Rollups         map[string]map[string][]models.Metric `json:"rollups,omitempty"`
+	CompactedBefore string                                `json:"compacted_before,omitempty"`
+	CompactedRuns   map[string][]string                   `json:"compacted_runs,omitempty"`
+}
+
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	s := &FileStorage{dir: dir, mem: NewMemoryStorage()}
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStorage) SaveMetrics(metrics []models.Metric) error {
+	return s.update(func(mem *MemoryStorage) error {
+		return mem.SaveMetrics(metrics)
+	})
+}
+
+func (s *FileStorage) ReplaceRun(tenantID, runID string, metrics []models.Metric) ([]models.Metric, error) {
+	var replaced []models.Metric
+	err := s.update(func(mem *MemoryStorage) error {
+		var err error
+		replaced, err = mem.ReplaceRun(tenantID, runID, metrics)
+		return err
+	})
+	return replaced, err
+}
+
+func (s *FileStorage) Compact(before string, granularities []string) (int, error) {
+	var removed int
+	err := s.update(func(mem *MemoryStorage) error {
+		var err error
+		removed, err = mem.Compact(before, granularities)
+		return err
+	})
+	return removed, err
+}
+
+func (s *FileStorage) GetMetricsByChannel(request models.MetricsRequest) ([]models.Metric, error) {
+	mem, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return mem.GetMetricsByChannel(request)
+}
+
+func (s *FileStorage) GetMetricsByFunnel(request models.MetricsRequest) ([]models.Metric, error) {
+	mem, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return mem.GetMetricsByFunnel(request)
+}
+
+func (s *FileStorage) GetMetricsInRange(from, to string) ([]models.Metric, error) {
+	mem, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return mem.GetMetricsInRange(from, to)
+}
+
+func (s *FileStorage) GetMetricsGrouped(request models.MetricsRequest) ([]models.MetricGroup, error) {
+	mem, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return mem.GetMetricsGrouped(request)
+}
+
+// Ping comprueba que el directorio compartido sigue accesible.
+func (s *FileStorage) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := os.Stat(s.dir); err != nil {
+		return fmt.Errorf("storage directory is not accessible: %v", err)
+	}
+	return nil
+}
+
+func (s *FileStorage) path() string {
+	return filepath.Join(s.dir, "metrics.json")
+}
+
+// current devuelve la copia en memoria, recargada si otro proceso ha cambiado el fichero.
+func (s *FileStorage) current() (*MemoryStorage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s.mem, nil
+}
+
+// refresh recarga el snapshot si el fichero ya no es el último que se leyó. Cada escritura
+// lo reemplaza con un rename; como el inodo liberado puede reutilizarse, también se
+// comparan el tamaño y la fecha de modificación.
+func (s *FileStorage) refresh() error {
+	file, err := os.Open(s.path())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read metrics snapshot: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read metrics snapshot: %v", err)
+	}
+	if s.loaded != nil && sameSnapshot(s.loaded, info) {
+		return nil
+	}
+
+	var snap snapshot
+	if err := json.NewDecoder(file).Decode(&snap); err != nil {
+		return fmt.Errorf("failed to read metrics snapshot: %v", err)
+	}
+	s.mem = restoreMemory(snap)
+	s.loaded = info
+	return nil
+}
+
+func sameSnapshot(a, b os.FileInfo) bool {
+	return os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// update aplica write sobre la última versión guardada y reescribe el snapshot. Se trabaja
+// sobre una copia para que, si no se puede guardar, las lecturas no vean el cambio.
+func (s *FileStorage) update(write func(*MemoryStorage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := lockFile(filepath.Join(s.dir, "metrics.lock"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	next := restoreMemory(s.mem.snapshot())
+	if err := write(next); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(next.snapshot())
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write metrics snapshot: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics snapshot: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics snapshot: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics snapshot: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path()); err != nil {
+		return fmt.Errorf("failed to write metrics snapshot: %v", err)
+	}
+
+	info, err := os.Stat(s.path())
+	if err != nil {
+		return fmt.Errorf("failed to write metrics snapshot: %v", err)
+	}
+	s.mem, s.loaded = next, info
+	return nil
+}
+
+func (s *MemoryStorage) snapshot() snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap := snapshot{
+		Partitions: make(map[string][]models.Metric, len(s.partitions)),
+		Rollups:    make(map[string]map[string][]models.Metric, len(s.rollups)),
+	}
+	for tenantID, part := range s.partitions {
+		metrics := make([]models.Metric, len(part.rows))
+		for i, r := range part.rows {
+			metrics[i] = r.metric
+		}
+		snap.Partitions[tenantID] = metrics
+	}
+	for granularity, byTenant := range s.rollups {
+		snap.Rollups[granularity] = make(map[string][]models.Metric, len(byTenant))
+		for tenantID, stored := range byTenant {
+			snap.Rollups[granularity][tenantID] = append([]models.Metric(nil), stored.rows...)
+		}
+	}
+	if len(s.compactedRuns) > 0 {
+		snap.CompactedRuns = make(map[string][]string, len(s.compactedRuns))
+		for key, days := range s.compactedRuns {
+			for day := range days {
+				snap.CompactedRuns[key] = append(snap.CompactedRuns[key], formatDay(day))
+			}
+		}
+	}
+	if s.hasCompacted {
+		snap.CompactedBefore = formatDay(s.compactedBefore)
+	}
+	return snap
+}
+
+func formatDay(day int64) string {
+	return time.Unix(day*86400, 0).UTC().Format("2006-01-02")
+}
+
+func restoreMemory(snap snapshot) *MemoryStorage {
+	s := NewMemoryStorage()
+	for tenantID, metrics := range snap.Partitions {
+		part := newPartition()
+		for _, metric := range metrics {
+			part.add(metric)
+		}
+		s.partitions[tenantID] = part
+	}
+	for granularity, byTenant := range snap.Rollups {
+		for tenantID, metrics := range byTenant {
+			stored := s.rollupOf(granularity, tenantID)
+			for _, metric := range metrics {
+				stored.add(metric, metric.Date)
+			}
+		}
+	}
+	for key, days := range snap.CompactedRuns {
+		s.compactedRuns[key] = make(map[int64]bool, len(days))
+		for _, date := range days {
+			if day, ok := parseDay(date); ok {
+				s.compactedRuns[key][day] = true
+			}
+		}
+	}
+	if day, ok := parseDay(snap.CompactedBefore); ok {
+		s.compactedBefore, s.hasCompacted = day, true
+	}
+	return s
+}
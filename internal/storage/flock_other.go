@@ -0,0 +1,10 @@
+//go:build !unix
+
+package storage
+
+import "fmt"
+
+// FileStorage necesita flock, que sólo existe en sistemas unix.
+func lockFile(path string) (func(), error) {
+	return nil, fmt.Errorf("file storage is not supported on this platform")
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/admira-project/backend/internal/models"
+)
+
+// partition guarda las métricas de un tenant en orden de llegada, con índices por día,
+// canal y utm_campaign. Los índices guardan posiciones en rows en orden creciente, así
+// que recorrerlos devuelve las métricas en el mismo orden que un recorrido completo.
+type partition struct {
+	rows       []row
+	byDay      map[int64][]int
+	days       []int64
+	byChannel  map[string][]int
+	byCampaign map[string][]int
+}
+
+// row lleva la fecha ya parseada (días desde 1970); valid es false si Date no es una fecha.
+type row struct {
+	metric models.Metric
+	day    int64
+	valid  bool
+}
+
+// query son los filtros que resuelven los índices; el resto se comprueba fila a fila.
+type query struct {
+	from, to       int64
+	hasFrom, hasTo bool
+	channel        string
+	utmCampaign    string
+}
+
+func newPartition() *partition {
+	return &partition{
+		byDay:      make(map[int64][]int),
+		byChannel:  make(map[string][]int),
+		byCampaign: make(map[string][]int),
+	}
+}
+
+func (p *partition) add(metric models.Metric) {
+	pos := len(p.rows)
+	day, valid := parseDay(metric.Date)
+	p.rows = append(p.rows, row{metric: metric, day: day, valid: valid})
+
+	if valid {
+		if _, ok := p.byDay[day]; !ok {
+			i := sort.Search(len(p.days), func(i int) bool { return p.days[i] >= day })
+			p.days = append(p.days, 0)
+			copy(p.days[i+1:], p.days[i:])
+			p.days[i] = day
+		}
+		p.byDay[day] = append(p.byDay[day], pos)
+	}
+	p.byChannel[metric.Channel] = append(p.byChannel[metric.Channel], pos)
+	p.byCampaign[metric.UtmCampaign] = append(p.byCampaign[metric.UtmCampaign], pos)
+}
+
+// find devuelve las métricas que cumplen q y keep. Usa el índice que deja menos candidatas.
+func (p *partition) find(q query, keep func(models.Metric) bool) []models.Metric {
+	var result []models.Metric
+	visit := func(r row) {
+		if !r.valid || (q.hasFrom && r.day < q.from) || (q.hasTo && r.day > q.to) {
+			return
+		}
+		if q.channel != "" && r.metric.Channel != q.channel {
+			return
+		}
+		if q.utmCampaign != "" && r.metric.UtmCampaign != q.utmCampaign {
+			return
+		}
+		if keep == nil || keep(r.metric) {
+			result = append(result, r.metric)
+		}
+	}
+
+	candidates, indexed := p.candidates(q)
+	if !indexed {
+		for _, r := range p.rows {
+			visit(r)
+		}
+		return result
+	}
+	for _, pos := range candidates {
+		visit(p.rows[pos])
+	}
+	return result
+}
+
+func (p *partition) candidates(q query) ([]int, bool) {
+	var best []int
+	indexed := false
+	consider := func(positions []int) {
+		if !indexed || len(positions) < len(best) {
+			best, indexed = positions, true
+		}
+	}
+
+	if q.channel != "" {
+		consider(p.byChannel[q.channel])
+	}
+	if q.utmCampaign != "" {
+		consider(p.byCampaign[q.utmCampaign])
+	}
+
+	if q.hasFrom || q.hasTo {
+		lo, hi := 0, len(p.days)
+		if q.hasFrom {
+			lo = sort.Search(len(p.days), func(i int) bool { return p.days[i] >= q.from })
+		}
+		if q.hasTo {
+			hi = sort.Search(len(p.days), func(i int) bool { return p.days[i] > q.to })
+		}
+
+		count := 0
+		for _, day := range p.days[lo:max(lo, hi)] {
+			count += len(p.byDay[day])
+		}
+		if !indexed || count < len(best) {
+			positions := make([]int, 0, count)
+			for _, day := range p.days[lo:max(lo, hi)] {
+				positions = append(positions, p.byDay[day]...)
+			}
+			sort.Ints(positions)
+			best, indexed = positions, true
+		}
+	}
+
+	return best, indexed
+}
+
+// newQuery parsea el rango una sola vez; una fecha inválida deja ese extremo abierto.
+func newQuery(from, to string) query {
+	var q query
+	q.from, q.hasFrom = parseDay(from)
+	q.to, q.hasTo = parseDay(to)
+	return q
+}
+
+func parseDay(value string) (int64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return 0, false
+	}
+	return date.Unix() / 86400, true
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LeaseStore lo implementan los almacenamientos que pueden guardar leases con caducidad,
+// compartidos por todas las réplicas que usan el mismo almacenamiento. AcquireLease
+// devuelve el dueño actual y si el lease es ahora de holder.
+type LeaseStore interface {
+	AcquireLease(key, holder string, ttl time.Duration) (string, bool, error)
+	RenewLease(key, holder string, ttl time.Duration) error
+	ReleaseLease(key, holder string) error
+}
+
+type storedLease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+func (s *MemoryStorage) AcquireLease(key, holder string, ttl time.Duration) (string, bool, error) {
+	s.leaseMu.Lock()
+	defer s.leaseMu.Unlock()
+
+	now := time.Now()
+	if current, ok := s.leases[key]; ok && current.holder != holder && current.expiresAt.After(now) {
+		return current.holder, false, nil
+	}
+	s.leases[key] = storedLease{holder: holder, expiresAt: now.Add(ttl)}
+	return holder, true, nil
+}
+
+func (s *MemoryStorage) RenewLease(key, holder string, ttl time.Duration) error {
+	s.leaseMu.Lock()
+	defer s.leaseMu.Unlock()
+
+	current, ok := s.leases[key]
+	if !ok || current.holder != holder {
+		return fmt.Errorf("lease %s is no longer held by %s", key, holder)
+	}
+	s.leases[key] = storedLease{holder: holder, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStorage) ReleaseLease(key, holder string) error {
+	s.leaseMu.Lock()
+	defer s.leaseMu.Unlock()
+
+	if current, ok := s.leases[key]; ok && current.holder == holder {
+		delete(s.leases, key)
+	}
+	return nil
+}
+
+// fileLease es un lease tal y como lo guarda FileStorage en leases.json.
+type fileLease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// En FileStorage los leases viven en leases.json, con su propio flock para no esperar a
+// que termine la escritura de un snapshot de métricas.
+func (s *FileStorage) AcquireLease(key, holder string, ttl time.Duration) (string, bool, error) {
+	current, acquired := holder, false
+	err := s.updateLeases(func(leases map[string]fileLease) {
+		now := time.Now()
+		if lease, ok := leases[key]; ok && lease.Holder != holder && lease.ExpiresAt.After(now) {
+			current = lease.Holder
+			return
+		}
+		leases[key] = fileLease{Holder: holder, ExpiresAt: now.Add(ttl)}
+		acquired = true
+	})
+	return current, acquired, err
+}
+
+func (s *FileStorage) RenewLease(key, holder string, ttl time.Duration) error {
+	held := false
+	err := s.updateLeases(func(leases map[string]fileLease) {
+		if lease, ok := leases[key]; ok && lease.Holder == holder {
+			leases[key] = fileLease{Holder: holder, ExpiresAt: time.Now().Add(ttl)}
+			held = true
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !held {
+		return fmt.Errorf("lease %s is no longer held by %s", key, holder)
+	}
+	return nil
+}
+
+func (s *FileStorage) ReleaseLease(key, holder string) error {
+	return s.updateLeases(func(leases map[string]fileLease) {
+		if lease, ok := leases[key]; ok && lease.Holder == holder {
+			delete(leases, key)
+		}
+	})
+}
+
+// updateLeases lee leases.json bajo flock, aplica change y lo reescribe de forma atómica.
+func (s *FileStorage) updateLeases(change func(map[string]fileLease)) error {
+	unlock, err := lockFile(filepath.Join(s.dir, "leases.lock"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := filepath.Join(s.dir, "leases.json")
+	leases := make(map[string]fileLease)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read leases: %v", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &leases); err != nil {
+			return fmt.Errorf("failed to read leases: %v", err)
+		}
+	}
+
+	change(leases)
+
+	data, err = json.Marshal(leases)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write leases: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write leases: %v", err)
+	}
+	return nil
+}
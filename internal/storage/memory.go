@@ -1,94 +1,274 @@
 package storage
 
 import (
-	"time"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/tenant"
 )
 
 type Storage interface {
 	SaveMetrics(metrics []models.Metric) error
 	GetMetricsByChannel(request models.MetricsRequest) ([]models.Metric, error)
 	GetMetricsByFunnel(request models.MetricsRequest) ([]models.Metric, error)
+	GetMetricsInRange(from, to string) ([]models.Metric, error)
+	GetMetricsGrouped(request models.MetricsRequest) ([]models.MetricGroup, error)
+	Ping(ctx context.Context) error
 }
 
+// MemoryStorage guarda las métricas particionadas por tenant. Las lecturas pueden ir en
+// paralelo entre sí; SaveMetrics y Compact las bloquean mientras escriben.
 type MemoryStorage struct {
-	metrics []models.Metric
+	mu         sync.RWMutex
+	partitions map[string]*partition
+	// rollups guarda, por granularidad y tenant, los agregados de las métricas ya compactadas
+	rollups map[string]map[string]*rollup
+	// compactedBefore es el corte más reciente de Compact: antes de él no queda detalle diario
+	compactedBefore int64
+	hasCompacted    bool
+	// compactedRuns guarda, por tenant y RunID, los días de esa ejecución ya agregados
+	compactedRuns map[string]map[int64]bool
+
+	leaseMu sync.Mutex
+	leases  map[string]storedLease
 }
 
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		metrics: make([]models.Metric, 0),
+		partitions:    make(map[string]*partition),
+		rollups:       make(map[string]map[string]*rollup),
+		compactedRuns: make(map[string]map[int64]bool),
+		leases:        make(map[string]storedLease),
 	}
 }
 
 func (s *MemoryStorage) SaveMetrics(metrics []models.Metric) error {
-	s.metrics = append(s.metrics, metrics...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, metric := range metrics {
+		tenantID := metric.TenantID
+		if tenantID == "" {
+			tenantID = tenant.Default
+		}
+		part, ok := s.partitions[tenantID]
+		if !ok {
+			part = newPartition()
+			s.partitions[tenantID] = part
+		}
+		part.add(metric)
+	}
 	return nil
 }
 
-func (s *MemoryStorage) GetMetricsByChannel(request models.MetricsRequest) ([]models.Metric, error) {
-	var filtered []models.Metric
+// find busca en un tenant; con tenantID vacío, en todos por orden de tenant. Con
+// granularidad week o month devuelve una fila por periodo.
+func (s *MemoryStorage) find(tenantID, granularity string, q query, keep func(models.Metric) bool) ([]models.Metric, error) {
+	if !ValidGranularity(granularity) {
+		return nil, fmt.Errorf("unsupported granularity: %s", granularity)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	for _, metric := range s.metrics {
-		if !s.filterByDate(metric, request.From, request.To) {
-			continue
+	tenants := []string{tenantID}
+	if tenantID == "" {
+		tenants = s.tenants()
+	}
+
+	var all []models.Metric
+	for _, id := range tenants {
+		if granularity == GranularityWeek || granularity == GranularityMonth {
+			all = append(all, s.findRolledUp(id, granularity, q, keep)...)
+		} else if part, ok := s.partitions[id]; ok {
+			all = append(all, part.find(q, keep)...)
 		}
+	}
+	return all, nil
+}
 
-		if request.Channel != "" && metric.Channel != request.Channel {
-			continue
+// tenants devuelve, ordenados, los tenants con métricas diarias o agregadas.
+func (s *MemoryStorage) tenants() []string {
+	seen := make(map[string]bool)
+	for id := range s.partitions {
+		seen[id] = true
+	}
+	for _, byTenant := range s.rollups {
+		for id := range byTenant {
+			seen[id] = true
 		}
+	}
 
-		filtered = append(filtered, metric)
+	tenants := make([]string, 0, len(seen))
+	for id := range seen {
+		tenants = append(tenants, id)
 	}
+	sort.Strings(tenants)
+	return tenants
+}
 
-	start, end := s.applyPagination(filtered, request.Limit, request.Offset)
+// Ping siempre responde: el almacenamiento en memoria no tiene conexión que comprobar.
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (s *MemoryStorage) GetMetricsByChannel(request models.MetricsRequest) ([]models.Metric, error) {
+	q := newQuery(request.From, request.To)
+	q.channel = request.Channel
+
+	filtered, err := s.find(request.TenantID, request.Granularity, q, func(metric models.Metric) bool {
+		return s.filterByCampaign(metric, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := s.applyPagination(len(filtered), request.Limit, request.Offset)
 	return filtered[start:end], nil
 }
 
 func (s *MemoryStorage) GetMetricsByFunnel(request models.MetricsRequest) ([]models.Metric, error) {
-	var filtered []models.Metric
+	q := newQuery(request.From, request.To)
+	q.utmCampaign = request.UtmCampaign
 
-	for _, metric := range s.metrics {
-		if !s.filterByDate(metric, request.From, request.To) {
-			continue
-		}
+	filtered, err := s.find(request.TenantID, request.Granularity, q, func(metric models.Metric) bool {
+		return s.filterByCampaign(metric, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := s.applyPagination(len(filtered), request.Limit, request.Offset)
+	return filtered[start:end], nil
+}
+
+func (s *MemoryStorage) GetMetricsInRange(from, to string) ([]models.Metric, error) {
+	return s.find("", GranularityDay, newQuery(from, to), nil)
+}
+
+func (s *MemoryStorage) GetMetricsGrouped(request models.MetricsRequest) ([]models.MetricGroup, error) {
+	q := newQuery(request.From, request.To)
+	q.channel = request.Channel
+	q.utmCampaign = request.UtmCampaign
 
-		if request.UtmCampaign != "" && metric.UtmCampaign != request.UtmCampaign {
-			continue
+	filtered, err := s.find(request.TenantID, request.Granularity, q, func(metric models.Metric) bool {
+		return s.filterByCampaign(metric, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := Aggregate(filtered, request.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := s.applyPagination(len(result), request.Limit, request.Offset)
+	return result[start:end], nil
+}
+
+// ValidGroupBy acepta las dimensiones de agrupación; vacía equivale a campaign_name.
+func ValidGroupBy(groupBy string) bool {
+	if groupBy == "" {
+		return true
+	}
+	_, ok := groupKeys[groupBy]
+	return ok
+}
+
+// Aggregate agrupa las métricas por una dimensión (campaign_name por defecto), ordenadas por clave.
+func Aggregate(metrics []models.Metric, groupBy string) ([]models.MetricGroup, error) {
+	if groupBy == "" {
+		groupBy = "campaign_name"
+	}
+
+	keysOf, ok := groupKeys[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported group_by: %s", groupBy)
+	}
+
+	groups := make(map[string]*models.MetricGroup)
+
+	for _, metric := range metrics {
+		for _, key := range keysOf(metric) {
+			if key == "" {
+				key = "unknown"
+			}
+
+			group, exists := groups[key]
+			if !exists {
+				group = &models.MetricGroup{GroupBy: groupBy, Key: key}
+				groups[key] = group
+			}
+
+			group.Clicks += metric.Clicks
+			group.Impressions += metric.Impressions
+			group.Cost += metric.Cost
+			group.Leads += metric.Leads
+			group.Opportunities += metric.Opportunities
+			group.ClosedWon += metric.ClosedWon
+			group.Revenue += metric.Revenue
 		}
+	}
 
-		filtered = append(filtered, metric)
+	result := make([]models.MetricGroup, 0, len(groups))
+	for _, group := range groups {
+		group.ComputeDerived()
+		result = append(result, *group)
 	}
 
-	start, end := s.applyPagination(filtered, request.Limit, request.Offset)
-	return filtered[start:end], nil
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+
+	return result, nil
 }
 
-func (s *MemoryStorage) filterByDate(metric models.Metric, from, to string) bool {
-	metricDate, err := time.Parse("2006-01-02", metric.Date)
-	if err != nil {
+var groupKeys = map[string]func(models.Metric) []string{
+	"channel":       func(m models.Metric) []string { return []string{m.Channel} },
+	"campaign_id":   func(m models.Metric) []string { return []string{m.CampaignID} },
+	"campaign_name": func(m models.Metric) []string { return []string{m.CampaignName} },
+	"owner":         func(m models.Metric) []string { return []string{m.Owner} },
+	"objective":     func(m models.Metric) []string { return []string{m.Objective} },
+	"business_unit": func(m models.Metric) []string { return []string{m.BusinessUnit} },
+	"utm_campaign":  func(m models.Metric) []string { return []string{m.UtmCampaign} },
+	"tag": func(m models.Metric) []string {
+		if len(m.Tags) == 0 {
+			return []string{""}
+		}
+		return m.Tags
+	},
+}
+
+func (s *MemoryStorage) filterByCampaign(metric models.Metric, request models.MetricsRequest) bool {
+	if request.CampaignName != "" && metric.CampaignName != request.CampaignName {
 		return false
 	}
 
-	if from != "" {
-		fromDate, err := time.Parse("2006-01-02", from)
-		if err == nil && metricDate.Before(fromDate) {
-			return false
-		}
+	if request.Owner != "" && metric.Owner != request.Owner {
+		return false
 	}
 
-	if to != "" {
-		toDate, err := time.Parse("2006-01-02", to)
-		if err == nil && metricDate.After(toDate) {
-			return false
+	if request.BusinessUnit != "" && metric.BusinessUnit != request.BusinessUnit {
+		return false
+	}
+
+	if request.Tag != "" {
+		for _, tag := range metric.Tags {
+			if tag == request.Tag {
+				return true
+			}
 		}
+		return false
 	}
 
 	return true
 }
 
-func (s *MemoryStorage) applyPagination(metrics []models.Metric, limit, offset int) (int, int) {
+func (s *MemoryStorage) applyPagination(total, limit, offset int) (int, int) {
 	if limit <= 0 {
 		limit = 50 // Valor por defecto
 	}
@@ -98,13 +278,13 @@ func (s *MemoryStorage) applyPagination(metrics []models.Metric, limit, offset i
 	}
 
 	start := offset
-	if start > len(metrics) {
-		start = len(metrics)
+	if start > total {
+		start = total
 	}
 
 	end := start + limit
-	if end > len(metrics) {
-		end = len(metrics)
+	if end > total {
+		end = total
 	}
 
 	return start, end
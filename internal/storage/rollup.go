@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/admira-project/backend/internal/models"
+)
+
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// RollupStore lo implementan los almacenamientos que guardan agregados semanales o
+// mensuales de las métricas diarias que salen de la retención.
+type RollupStore interface {
+	// Compact borra las métricas diarias anteriores a before (YYYY-MM-DD) después de
+	// sumarlas a los agregados de cada granularidad, y devuelve cuántas ha borrado.
+	Compact(before string, granularities []string) (int, error)
+}
+
+// ValidGranularity acepta las granularidades de consulta; vacía equivale a day.
+func ValidGranularity(granularity string) bool {
+	switch granularity {
+	case "", GranularityDay, GranularityWeek, GranularityMonth:
+		return true
+	}
+	return false
+}
+
+// PeriodStart devuelve el primer día del periodo que contiene date: el lunes de su
+// semana ISO o el día 1 de su mes.
+func PeriodStart(granularity string, date time.Time) time.Time {
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case GranularityWeek:
+		return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+	case GranularityMonth:
+		return date.AddDate(0, 0, 1-date.Day())
+	}
+	return date
+}
+
+// periodBounds devuelve el primer y el último día del periodo que contiene day.
+func periodBounds(granularity string, day int64) (int64, int64) {
+	start := PeriodStart(granularity, time.Unix(day*86400, 0).UTC())
+	end := start.AddDate(0, 0, 7)
+	if granularity == GranularityMonth {
+		end = start.AddDate(0, 1, 0)
+	}
+	return start.Unix() / 86400, end.Unix()/86400 - 1
+}
+
+func periodOf(granularity string, day int64) string {
+	start, _ := periodBounds(granularity, day)
+	return time.Unix(start*86400, 0).UTC().Format("2006-01-02")
+}
+
+// rollup suma métricas por periodo, canal, campaña y UTMs. Date pasa a ser el primer día
+// del periodo y los ratios se recalculan con los contadores sumados.
+type rollup struct {
+	rows  []models.Metric
+	index map[string]int
+}
+
+func newRollup() *rollup {
+	return &rollup{index: make(map[string]int)}
+}
+
+func (r *rollup) add(metric models.Metric, period string) {
+	key := period + "|" + metric.Channel + "|" + metric.CampaignID + "|" + metric.UtmCampaign + "|" + metric.UtmSource + "|" + metric.UtmMedium
+
+	i, ok := r.index[key]
+	if !ok {
+		metric.Date = period
+		metric.RunID = ""
+		metric.ComputeDerived()
+		r.index[key] = len(r.rows)
+		r.rows = append(r.rows, metric)
+		return
+	}
+
+	row := &r.rows[i]
+	row.Clicks += metric.Clicks
+	row.Impressions += metric.Impressions
+	row.Cost += metric.Cost
+	row.Leads += metric.Leads
+	row.Opportunities += metric.Opportunities
+	row.ClosedWon += metric.ClosedWon
+	row.Revenue += metric.Revenue
+	row.ComputeDerived()
+}
+
+func (s *MemoryStorage) Compact(before string, granularities []string) (int, error) {
+	cutoff, ok := parseDay(before)
+	if !ok {
+		return 0, fmt.Errorf("invalid cutoff date: %s", before)
+	}
+	for _, granularity := range granularities {
+		if granularity != GranularityWeek && granularity != GranularityMonth {
+			return 0, fmt.Errorf("unsupported rollup granularity: %s", granularity)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hasCompacted || cutoff > s.compactedBefore {
+		s.compactedBefore, s.hasCompacted = cutoff, true
+	}
+
+	removed := 0
+	for tenantID, part := range s.partitions {
+		kept := newPartition()
+		expired := 0
+		for _, r := range part.rows {
+			// Las fechas inválidas no tienen periodo: se quedan como están
+			if !r.valid || r.day >= cutoff {
+				kept.add(r.metric)
+				continue
+			}
+			expired++
+			for _, granularity := range granularities {
+				s.rollupOf(granularity, tenantID).add(r.metric, periodOf(granularity, r.day))
+			}
+			if r.metric.RunID != "" {
+				s.markCompacted(tenantID, r.metric.RunID, r.day)
+			}
+		}
+
+		if expired > 0 {
+			s.partitions[tenantID] = kept
+			removed += expired
+		}
+	}
+	return removed, nil
+}
+
+func (s *MemoryStorage) markCompacted(tenantID, runID string, day int64) {
+	key := tenantID + "|" + runID
+	days, ok := s.compactedRuns[key]
+	if !ok {
+		days = make(map[int64]bool)
+		s.compactedRuns[key] = days
+	}
+	days[day] = true
+}
+
+func (s *MemoryStorage) rollupOf(granularity, tenantID string) *rollup {
+	tenants, ok := s.rollups[granularity]
+	if !ok {
+		tenants = make(map[string]*rollup)
+		s.rollups[granularity] = tenants
+	}
+	stored, ok := tenants[tenantID]
+	if !ok {
+		stored = newRollup()
+		tenants[tenantID] = stored
+	}
+	return stored
+}
+
+// findRolledUp agrega por periodo las métricas diarias de un tenant y les suma los
+// agregados ya compactados, de modo que el resultado no depende de qué parte se ha
+// borrado. El rango se amplía a periodos completos.
+func (s *MemoryStorage) findRolledUp(tenantID, granularity string, q query, keep func(models.Metric) bool) []models.Metric {
+	if q.hasFrom {
+		q.from, _ = periodBounds(granularity, q.from)
+	}
+	if q.hasTo {
+		_, q.to = periodBounds(granularity, q.to)
+	}
+
+	combined := newRollup()
+	if stored, ok := s.rollups[granularity][tenantID]; ok {
+		for _, metric := range stored.rows {
+			day, _ := parseDay(metric.Date)
+			if (q.hasFrom && day < q.from) || (q.hasTo && day > q.to) {
+				continue
+			}
+			if q.channel != "" && metric.Channel != q.channel {
+				continue
+			}
+			if q.utmCampaign != "" && metric.UtmCampaign != q.utmCampaign {
+				continue
+			}
+			if keep == nil || keep(metric) {
+				combined.add(metric, metric.Date)
+			}
+		}
+	}
+
+	if part, ok := s.partitions[tenantID]; ok {
+		for _, metric := range part.find(q, keep) {
+			day, _ := parseDay(metric.Date)
+			combined.add(metric, periodOf(granularity, day))
+		}
+	}
+
+	sort.SliceStable(combined.rows, func(i, j int) bool {
+		return combined.rows[i].Date < combined.rows[j].Date
+	})
+	return combined.rows
+}
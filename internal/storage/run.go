@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/tenant"
+)
+
+// RunStore lo implementan los almacenamientos que pueden sustituir las métricas guardadas
+// por una ejecución, de modo que repetirla no las duplica.
+type RunStore interface {
+	// ReplaceRun borra las métricas del tenant con el RunID indicado, guarda metrics en su
+	// lugar y devuelve las que ha borrado. Si la ejecución ya tenía un día compactado, sus
+	// métricas de ese día se descartan porque los agregados ya las incluyen; las de días
+	// anteriores al corte que nunca se guardaron quedan como diarias hasta el próximo Compact.
+	ReplaceRun(tenantID, runID string, metrics []models.Metric) ([]models.Metric, error)
+}
+
+func (s *MemoryStorage) ReplaceRun(tenantID, runID string, metrics []models.Metric) ([]models.Metric, error) {
+	if tenantID == "" {
+		tenantID = tenant.Default
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var replaced []models.Metric
+	part, ok := s.partitions[tenantID]
+	if !ok {
+		part = newPartition()
+		s.partitions[tenantID] = part
+	} else if runID != "" {
+		kept := newPartition()
+		for _, r := range part.rows {
+			if r.metric.RunID == runID {
+				replaced = append(replaced, r.metric)
+				continue
+			}
+			kept.add(r.metric)
+		}
+		if len(replaced) > 0 {
+			part = kept
+			s.partitions[tenantID] = part
+		}
+	}
+
+	compacted := s.compactedRuns[tenantID+"|"+runID]
+	for _, metric := range metrics {
+		if day, valid := parseDay(metric.Date); valid && runID != "" && compacted[day] {
+			continue
+		}
+		part.add(metric)
+	}
+	return replaced, nil
+}
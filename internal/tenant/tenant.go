@@ -0,0 +1,62 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// Default es el tenant implícito de las instalaciones con un único cliente.
+const Default = "default"
+
+var (
+	ErrInvalidTenant   = errors.New("invalid tenant")
+	ErrForbiddenTenant = errors.New("tenant not allowed for these credentials")
+)
+
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+type tenantKey struct{}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext devuelve el tenant del contexto o Default si no hay ninguno.
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantKey{}).(string); ok && id != "" {
+		return id
+	}
+	return Default
+}
+
+// Valid comprueba que el ID se puede usar con seguridad en rutas y claves.
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// Scoped devuelve el tenant sólo si se fijó explícitamente en el contexto.
+func Scoped(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Resolve elige el tenant de una petición. Si la credencial está limitada a un tenant
+// (bound no vacío), sólo puede operar sobre él; si no, se usa el pedido o Default.
+func Resolve(requested, bound string) (string, error) {
+	if requested != "" && !Valid(requested) {
+		return "", ErrInvalidTenant
+	}
+
+	if bound != "" {
+		if requested != "" && requested != bound {
+			return "", ErrForbiddenTenant
+		}
+		return bound, nil
+	}
+
+	if requested == "" {
+		return Default, nil
+	}
+	return requested, nil
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator añade credenciales a una petición saliente.
+type Authenticator interface {
+	Apply(ctx context.Context, req *http.Request) error
+}
+
+// TokenInvalidator lo implementan los authenticators con credenciales cacheadas,
+// para forzar su renovación cuando la fuente responde 401.
+type TokenInvalidator interface {
+	Invalidate()
+}
+
+type BearerAuth struct {
+	Token string
+}
+
+func (a BearerAuth) Apply(ctx context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+type APIKeyAuth struct {
+	Header string
+	Key    string
+}
+
+func (a APIKeyAuth) Apply(ctx context.Context, req *http.Request) error {
+	header := a.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+	req.Header.Set(header, a.Key)
+	return nil
+}
+
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuth) Apply(ctx context.Context, req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// OAuth2ClientCredentials obtiene y cachea un access token con el grant client_credentials.
+type OAuth2ClientCredentials struct {
+	client       HTTPClient
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewOAuth2ClientCredentials(client HTTPClient, tokenURL, clientID, clientSecret string, scopes []string) *OAuth2ClientCredentials {
+	return &OAuth2ClientCredentials{
+		client:       client,
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+	}
+}
+
+func (a *OAuth2ClientCredentials) Apply(ctx context.Context, req *http.Request) error {
+	token, err := a.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (a *OAuth2ClientCredentials) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+}
+
+func (a *OAuth2ClientCredentials) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Margen para no usar un token que caduca en vuelo
+	if a.token != "" && time.Now().Add(30*time.Second).Before(a.expiresAt) {
+		return a.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.scopes) > 0 {
+		form.Set("scope", strings.Join(a.scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(a.clientID), url.QueryEscape(a.clientSecret))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request oauth2 token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oauth2 token endpoint returned status code: %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("failed to unmarshal oauth2 token: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("oauth2 token response has no access_token")
+	}
+
+	a.token = token.AccessToken
+	a.expiresAt = time.Now().Add(time.Hour)
+	if token.ExpiresIn > 0 {
+		a.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+
+	return a.token, nil
+}
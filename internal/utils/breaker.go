@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type BreakerConfig struct {
+	// FailureThreshold fallos consecutivos que abren el circuito.
+	FailureThreshold int
+	// OpenTimeout tiempo en abierto antes de dejar pasar peticiones de prueba.
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests peticiones de prueba simultáneas en semiabierto;
+	// si todas tienen éxito el circuito se cierra.
+	HalfOpenMaxRequests int
+}
+
+type CircuitBreaker struct {
+	config BreakerConfig
+
+	mu          sync.Mutex
+	state       string
+	failures    int
+	successes   int
+	inFlight    int
+	openedAt    time.Time
+	lastFailure time.Time
+}
+
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenMaxRequests <= 0 {
+		config.HalfOpenMaxRequests = 1
+	}
+
+	return &CircuitBreaker{config: config, state: StateClosed}
+}
+
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen {
+		if time.Since(b.openedAt) < b.config.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = StateHalfOpen
+		b.successes = 0
+		b.inFlight = 0
+	}
+
+	if b.state == StateHalfOpen {
+		if b.inFlight >= b.config.HalfOpenMaxRequests {
+			return ErrCircuitOpen
+		}
+		b.inFlight++
+	}
+
+	return nil
+}
+
+func (b *CircuitBreaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.inFlight > 0 {
+		b.inFlight--
+	}
+
+	if !success {
+		b.failures++
+		b.lastFailure = time.Now()
+
+		if b.state == StateHalfOpen || b.failures >= b.config.FailureThreshold {
+			b.state = StateOpen
+			b.openedAt = time.Now()
+		}
+		return
+	}
+
+	b.failures = 0
+	if b.state == StateHalfOpen {
+		b.successes++
+		if b.successes >= b.config.HalfOpenMaxRequests {
+			b.state = StateClosed
+		}
+	}
+}
+
+// Release libera una petición admitida sin registrar su resultado.
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.inFlight > 0 {
+		b.inFlight--
+	}
+}
+
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.config.OpenTimeout {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+func (b *CircuitBreaker) snapshot() (string, int, time.Time) {
+	state := b.State()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return state, b.failures, b.lastFailure
+}
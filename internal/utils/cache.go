@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type CacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	StoredAt     time.Time `json:"stored_at"`
+}
+
+// ResponseCache guarda en disco la última respuesta de cada URL junto con sus
+// validadores (ETag / Last-Modified) para hacer peticiones condicionales.
+type ResponseCache struct {
+	dir string
+}
+
+func NewResponseCache(dir string) (*ResponseCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create response cache: %v", err)
+	}
+
+	return &ResponseCache{dir: dir}, nil
+}
+
+func (c *ResponseCache) Get(url string) (CacheEntry, []byte, bool) {
+	var entry CacheEntry
+
+	key := cacheKey(url)
+	meta, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+	if err != nil {
+		return entry, nil, false
+	}
+
+	if err := json.Unmarshal(meta, &entry); err != nil || entry.URL != url {
+		return entry, nil, false
+	}
+
+	body, err := os.ReadFile(filepath.Join(c.dir, key+".body"))
+	if err != nil {
+		return entry, nil, false
+	}
+
+	return entry, body, true
+}
+
+func (c *ResponseCache) Put(entry CacheEntry, body []byte) error {
+	if entry.ETag == "" && entry.LastModified == "" {
+		return nil
+	}
+	entry.StoredAt = time.Now().UTC()
+
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// El cuerpo se escribe antes que los metadatos: una entrada a medias nunca se usa
+	key := cacheKey(entry.URL)
+	if err := writeCacheFile(filepath.Join(c.dir, key+".body"), body); err != nil {
+		return err
+	}
+	return writeCacheFile(filepath.Join(c.dir, key+".json"), meta)
+}
+
+func cacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeCacheFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write response cache: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write response cache: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write response cache: %v", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
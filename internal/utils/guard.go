@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+type HostState struct {
+	Host        string     `json:"host"`
+	State       string     `json:"state"`
+	Failures    int        `json:"consecutive_failures"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	RateLimit   float64    `json:"rate_limit_rps,omitempty"`
+	Tokens      *float64   `json:"tokens_available,omitempty"`
+}
+
+type hostGuard struct {
+	breaker *CircuitBreaker
+	limiter *TokenBucket
+	limit   RateLimit
+}
+
+// GuardedHTTPClient aplica por host un rate limiter y un circuit breaker antes de delegar
+// en el cliente subyacente. Va por debajo de RetryableHTTPClient, de forma que cada intento
+// consume cuota y un circuito abierto corta los reintentos.
+type GuardedHTTPClient struct {
+	client        HTTPClient
+	breakerConfig BreakerConfig
+	defaultLimit  RateLimit
+	hostLimits    map[string]RateLimit
+
+	mu    sync.Mutex
+	hosts map[string]*hostGuard
+}
+
+func NewGuardedHTTPClient(client HTTPClient, breakerConfig BreakerConfig, defaultLimit RateLimit) *GuardedHTTPClient {
+	return &GuardedHTTPClient{
+		client:        client,
+		breakerConfig: breakerConfig,
+		defaultLimit:  defaultLimit,
+		hostLimits:    make(map[string]RateLimit),
+		hosts:         make(map[string]*hostGuard),
+	}
+}
+
+func (c *GuardedHTTPClient) SetHostLimit(host string, limit RateLimit) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hostLimits[host] = limit
+	delete(c.hosts, host)
+}
+
+func (c *GuardedHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	guard := c.guardFor(req.URL.Host)
+
+	if guard.limiter != nil {
+		if err := guard.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := guard.breaker.Allow(); err != nil {
+		return nil, fmt.Errorf("%s: %w", req.URL.Host, err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil && req.Context().Err() != nil {
+		// Una cancelación del llamante no dice nada de la salud del host
+		guard.breaker.Release()
+		return nil, err
+	}
+	guard.breaker.Record(err == nil && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests)
+
+	return resp, err
+}
+
+func (c *GuardedHTTPClient) States() []HostState {
+	c.mu.Lock()
+	hosts := make(map[string]*hostGuard, len(c.hosts))
+	for host, guard := range c.hosts {
+		hosts[host] = guard
+	}
+	c.mu.Unlock()
+
+	states := make([]HostState, 0, len(hosts))
+	for host, guard := range hosts {
+		state, failures, lastFailure := guard.breaker.snapshot()
+		hostState := HostState{Host: host, State: state, Failures: failures}
+		if !lastFailure.IsZero() {
+			hostState.LastFailure = &lastFailure
+		}
+		if guard.limiter != nil {
+			tokens := guard.limiter.Tokens()
+			hostState.RateLimit = guard.limit.RequestsPerSecond
+			hostState.Tokens = &tokens
+		}
+		states = append(states, hostState)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Host < states[j].Host
+	})
+	return states
+}
+
+func (c *GuardedHTTPClient) guardFor(host string) *hostGuard {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if guard, ok := c.hosts[host]; ok {
+		return guard
+	}
+
+	limit, ok := c.hostLimits[host]
+	if !ok {
+		limit = c.defaultLimit
+	}
+
+	guard := &hostGuard{
+		breaker: NewCircuitBreaker(c.breakerConfig),
+		limit:   limit,
+	}
+	if limit.RequestsPerSecond > 0 {
+		guard.limiter = NewTokenBucket(limit.RequestsPerSecond, limit.Burst)
+	}
+
+	c.hosts[host] = guard
+	return guard
+}
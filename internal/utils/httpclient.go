@@ -1,14 +1,23 @@
 package utils
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"fmt"
 	"io"
-	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type HTTPClient interface {
@@ -16,70 +25,264 @@ type HTTPClient interface {
 }
 
 type RetryableHTTPClient struct {
-	client         HTTPClient
-	maxRetries     int
-	retryBackoffMs int
-	logger         *logrus.Logger
+	client HTTPClient
+	policy RetryPolicy
+	logger *logrus.Logger
 }
 
 func NewRetryableHTTPClient(logger *logrus.Logger, maxRetries, retryBackoffMs int) *RetryableHTTPClient {
-	return &RetryableHTTPClient{
-		client: &http.Client{
+	return NewRetryableHTTPClientWithPolicy(
+		logger,
+		&http.Client{
 			Timeout: 30 * time.Second,
 		},
-		maxRetries:     maxRetries,
-		retryBackoffMs: retryBackoffMs,
-		logger:         logger,
+		DefaultRetryPolicy(maxRetries, retryBackoffMs),
+	)
+}
+
+func NewRetryableHTTPClientWithPolicy(logger *logrus.Logger, client HTTPClient, policy RetryPolicy) *RetryableHTTPClient {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+
+	return &RetryableHTTPClient{
+		client: client,
+		policy: policy,
+		logger: logger,
 	}
 }
 
 func (c *RetryableHTTPClient) Do(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var err error
+	ctx := req.Context()
+	start := time.Now()
+	log := tracing.Logger(ctx, c.logger)
+
+	var lastErr error
 
-	for i := 0; i < c.maxRetries; i++ {
-		resp, err = c.client.Do(req)
-		if err == nil && resp.StatusCode < 500 {
+	for i := 0; i < c.policy.MaxAttempts; i++ {
+		attemptReq := req
+		if i > 0 && req.Body != nil && req.Body != http.NoBody {
+			// El cuerpo se consumió en el intento anterior
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %v", err)
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		resp, err := c.client.Do(attemptReq)
+		if err == nil && !c.policy.ShouldRetryStatus(resp.StatusCode) {
 			return resp, nil
 		}
 
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		wait := c.policy.Backoff(i)
+		reason := "error"
+
 		if err != nil {
-			c.logger.Warnf("Attempt %d failed: %v", i+1, err)
+			if !c.policy.ShouldRetryError(err) {
+				return nil, err
+			}
+			log.Warnf("Attempt %d failed: %v", i+1, err)
+			lastErr = err
 		} else {
-			c.logger.Warnf("Attempt %d failed with status: %d", i+1, resp.StatusCode)
+			log.Warnf("Attempt %d failed with status: %d", i+1, resp.StatusCode)
+			lastErr = fmt.Errorf("received status code: %d", resp.StatusCode)
+			reason = strconv.Itoa(resp.StatusCode)
+			if retryAfter, ok := RetryAfter(resp); ok {
+				wait = retryAfter
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
 			resp.Body.Close()
 		}
 
-		if i < c.maxRetries-1 {
-			backoff := time.Duration(math.Pow(2, float64(i))) * time.Duration(c.retryBackoffMs) * time.Millisecond
-			c.logger.Debugf("Waiting %v before retry", backoff)
-			time.Sleep(backoff)
+		if i == c.policy.MaxAttempts-1 {
+			break
+		}
+
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return nil, fmt.Errorf("request body cannot be rewound for retry: %v", lastErr)
+		}
+
+		if c.policy.MaxElapsedTime > 0 && time.Since(start)+wait > c.policy.MaxElapsedTime {
+			return nil, fmt.Errorf("request failed after %d attempts, retry budget of %v exhausted: %v", i+1, c.policy.MaxElapsedTime, lastErr)
+		}
+
+		monitoring.HTTPClientRetries.WithLabelValues(req.URL.Host, reason).Inc()
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("http.retry.attempt", i+1),
+			attribute.String("http.retry.reason", reason),
+			attribute.String("http.retry.wait", wait.String()),
+		))
+		log.Debugf("Waiting %v before retry", wait)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
 		}
 	}
 
-	return nil, fmt.Errorf("request failed after %d attempts: %v", c.maxRetries, err)
+	return nil, fmt.Errorf("request failed after %d attempts: %v", c.policy.MaxAttempts, lastErr)
+}
+
+type RequestOptions struct {
+	Headers http.Header
+	Auth    Authenticator
+	Cache   *ResponseCache
+	// CacheKey separa en la caché respuestas de la misma URL (p. ej. por tenant); por defecto la URL
+	CacheKey string
+}
+
+type FetchResult struct {
+	Body []byte
+	// NotModified indica que la fuente respondió 304 y Body viene de la caché.
+	NotModified bool
 }
 
 func FetchData(ctx context.Context, client HTTPClient, url string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	return FetchDataWithOptions(ctx, client, url, RequestOptions{})
+}
+
+func FetchDataWithOptions(ctx context.Context, client HTTPClient, url string, options RequestOptions) ([]byte, error) {
+	result, err := Fetch(ctx, client, url, options)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := client.Do(req)
+	return result.Body, nil
+}
+
+func Fetch(ctx context.Context, client HTTPClient, url string, options RequestOptions) (*FetchResult, error) {
+	var cached CacheEntry
+	var cachedBody []byte
+	var hasCache bool
+	cacheKey := url
+	if options.CacheKey != "" {
+		cacheKey = options.CacheKey
+	}
+	if options.Cache != nil {
+		cached, cachedBody, hasCache = options.Cache.Get(cacheKey)
+	}
+
+	resp, err := doFetch(ctx, client, url, options, cached, hasCache)
 	if err != nil {
 		return nil, err
 	}
+
+	// Token caducado o revocado: se renueva una vez y se reintenta
+	if invalidator, ok := options.Auth.(TokenInvalidator); ok && resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		invalidator.Invalidate()
+
+		resp, err = doFetch(ctx, client, url, options, cached, hasCache)
+		if err != nil {
+			return nil, err
+		}
+	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && hasCache {
+		return &FetchResult{Body: cachedBody, NotModified: true}, nil
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("received non-200 status code: %d", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readBody(resp)
 	if err != nil {
 		return nil, err
 	}
 
+	if options.Cache != nil {
+		entry := CacheEntry{
+			URL:          cacheKey,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		}
+		if err := options.Cache.Put(entry, body); err != nil {
+			return nil, err
+		}
+	}
+
+	return &FetchResult{Body: body}, nil
+}
+
+func doFetch(ctx context.Context, client HTTPClient, url string, options RequestOptions, cached CacheEntry, hasCache bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Al fijarla a mano, net/http deja de descomprimir y lo hace readBody
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	tracing.Inject(ctx, req.Header)
+
+	for key, values := range options.Headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	if hasCache {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	if options.Auth != nil {
+		if err := options.Auth.Apply(ctx, req); err != nil {
+			return nil, fmt.Errorf("failed to authenticate request: %v", err)
+		}
+	}
+
+	return client.Do(req)
+}
+
+func readBody(resp *http.Response) ([]byte, error) {
+	var reader io.Reader = resp.Body
+
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress gzip response: %v", err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "deflate":
+		// "deflate" debería ser zlib, pero hay servidores que envían deflate sin cabecera
+		raw, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if zr, err := zlib.NewReader(bytes.NewReader(raw)); err == nil {
+			defer zr.Close()
+			reader = zr
+		} else {
+			reader = flate.NewReader(bytes.NewReader(raw))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", resp.Header.Get("Content-Encoding"))
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
 	return body, nil
 }
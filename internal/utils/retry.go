@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxAttempts    int
+	BaseBackoff    time.Duration
+	MaxBackoff     time.Duration
+	MaxElapsedTime time.Duration
+	// FullJitter espera un tiempo aleatorio entre 0 y el backoff exponencial.
+	FullJitter      bool
+	RetryableStatus map[int]bool
+}
+
+func DefaultRetryPolicy(maxAttempts, backoffMs int) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		BaseBackoff:    time.Duration(backoffMs) * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		MaxElapsedTime: 2 * time.Minute,
+		FullJitter:     true,
+		RetryableStatus: map[int]bool{
+			http.StatusRequestTimeout:      true,
+			http.StatusTooManyRequests:     true,
+			http.StatusInternalServerError: true,
+			http.StatusBadGateway:          true,
+			http.StatusServiceUnavailable:  true,
+			http.StatusGatewayTimeout:      true,
+		},
+	}
+}
+
+// Backoff devuelve la espera antes del reintento número attempt (empezando en 0).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := time.Duration(math.Pow(2, float64(attempt))) * p.BaseBackoff
+	if p.MaxBackoff > 0 && (backoff > p.MaxBackoff || backoff <= 0) {
+		backoff = p.MaxBackoff
+	}
+
+	if p.FullJitter && backoff > 0 {
+		backoff = time.Duration(rand.Int63n(int64(backoff) + 1))
+	}
+
+	return backoff
+}
+
+func (p RetryPolicy) ShouldRetryStatus(code int) bool {
+	return p.RetryableStatus[code]
+}
+
+// ShouldRetryError distingue fallos transitorios de red de errores definitivos
+// como una cancelación del contexto.
+func (p RetryPolicy) ShouldRetryError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// RetryAfter interpreta la cabecera Retry-After en segundos o como fecha HTTP.
+func RetryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
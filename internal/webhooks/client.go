@@ -0,0 +1,74 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("webhook destination address not allowed")
+
+// NewHTTPClient devuelve el cliente de las entregas. No sigue redirecciones y, salvo con
+// allowPrivate, no conecta con direcciones de loopback, link-local ni privadas (RFC 1918),
+// para que una suscripción no sirva para alcanzar servicios internos. La comprobación se hace
+// al conectar, con la IP ya resuelta, así que tampoco se puede esquivar con DNS.
+func NewHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Con un proxy la conexión iría al proxy y la IP de destino no se comprobaría
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	if !allowPrivate {
+		transport.DialContext = dialPublic(dialer)
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// dialPublic resuelve el host y sólo conecta si ninguna de sus direcciones es interna.
+func dialPublic(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("no addresses found for %s", host)
+		}
+		for _, ip := range addrs {
+			if internalIP(ip.IP) {
+				return nil, fmt.Errorf("%w: %s resolves to %s", ErrForbiddenAddress, host, ip.IP)
+			}
+		}
+
+		var lastErr error
+		for _, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
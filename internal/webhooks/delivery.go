@@ -0,0 +1,170 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/utils"
+)
+
+const (
+	SignatureHeader = "X-Admira-Signature"
+	EventHeader     = "X-Admira-Event"
+	DeliveryHeader  = "X-Admira-Delivery"
+)
+
+// Sign firma "timestamp.body" con HMAC-SHA256 y devuelve el valor de X-Admira-Signature,
+// "t=<unix>,v1=<hex>". El timestamp permite al receptor rechazar reenvíos antiguos.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Verify comprueba una firma generada con Sign y que no tenga más de tolerance de antigüedad.
+func Verify(secret, signature string, body []byte, tolerance time.Duration) bool {
+	var timestamp int64
+	var expected string
+	for _, part := range strings.Split(signature, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			expected = value
+		}
+	}
+	if timestamp == 0 || expected == "" {
+		return false
+	}
+
+	if tolerance > 0 && time.Since(time.Unix(timestamp, 0)).Abs() > tolerance {
+		return false
+	}
+
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte("t="+strconv.FormatInt(timestamp, 10)+",v1="+expected))
+}
+
+// deliver envía la entrega hasta que el destino responde 2xx, falla de forma definitiva
+// o se agotan los intentos.
+func (d *Dispatcher) deliver(delivery *Delivery) {
+	defer d.wg.Done()
+
+	for attempt := 0; ; attempt++ {
+		d.mu.Lock()
+		subscription, ok := d.subscriptions[delivery.SubscriptionID]
+		if !ok {
+			d.finish(delivery, StatusFailed, 0, "subscription was deleted")
+			d.mu.Unlock()
+			return
+		}
+		target, secret := subscription.URL, subscription.Secret
+		delivery.Attempts++
+		payload := delivery.Payload
+		d.mu.Unlock()
+
+		status, wait, err := d.post(target, secret, delivery, payload)
+
+		d.mu.Lock()
+		if err == nil && status < 300 {
+			d.finish(delivery, StatusDelivered, status, "")
+			d.mu.Unlock()
+			monitoring.WebhookDeliveryAttempts.WithLabelValues("delivered").Inc()
+			return
+		}
+
+		message := fmt.Sprintf("endpoint returned status code: %d", status)
+		retryable := d.policy.ShouldRetryStatus(status)
+		if err != nil {
+			message = err.Error()
+			retryable = d.policy.ShouldRetryError(err)
+		}
+
+		if !retryable || attempt+1 >= d.policy.MaxAttempts || d.ctx.Err() != nil {
+			attempts := delivery.Attempts
+			d.finish(delivery, StatusFailed, status, message)
+			d.mu.Unlock()
+			monitoring.WebhookDeliveryAttempts.WithLabelValues("failed").Inc()
+			d.logger.Warnf("Webhook delivery %s to %s failed after %d attempts: %s", delivery.ID, target, attempts, message)
+			return
+		}
+
+		delivery.ResponseStatus = status
+		delivery.Error = message
+		delivery.UpdatedAt = time.Now().UTC()
+		if err := d.persist(); err != nil {
+			d.logger.Errorf("Failed to persist webhook state: %v", err)
+		}
+		d.mu.Unlock()
+		monitoring.WebhookDeliveryAttempts.WithLabelValues("retry").Inc()
+
+		if wait <= 0 {
+			wait = d.policy.Backoff(attempt)
+		}
+		if d.policy.MaxBackoff > 0 && wait > d.policy.MaxBackoff {
+			wait = d.policy.MaxBackoff
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-d.ctx.Done():
+			timer.Stop()
+			d.mu.Lock()
+			d.finish(delivery, StatusFailed, status, "interrupted before completion")
+			d.mu.Unlock()
+			return
+		}
+	}
+}
+
+// post devuelve el código de respuesta y, si el destino lo pide con Retry-After, cuánto esperar.
+func (d *Dispatcher) post(target, secret string, delivery *Delivery, payload []byte) (int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(d.ctx, "POST", target, bytes.NewReader(payload))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "admira-webhooks/1")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now().Unix(), payload))
+
+	// El error se devuelve tal cual para que ShouldRetryError pueda clasificarlo
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	wait, _ := utils.RetryAfter(resp)
+	return resp.StatusCode, wait, nil
+}
+
+// finish cierra la entrega y la persiste. Debe llamarse con d.mu tomado.
+func (d *Dispatcher) finish(delivery *Delivery, status string, responseStatus int, message string) {
+	now := time.Now().UTC()
+	delivery.Status = status
+	delivery.ResponseStatus = responseStatus
+	delivery.Error = message
+	delivery.UpdatedAt = now
+	if status == StatusDelivered {
+		delivery.DeliveredAt = &now
+	}
+	delete(d.active, delivery.ID)
+
+	if err := d.persist(); err != nil {
+		d.logger.Errorf("Failed to persist webhook state: %v", err)
+	}
+}
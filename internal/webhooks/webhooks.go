@@ -0,0 +1,533 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+
+	EventIngestionCompleted = "ingestion.completed"
+
+	minSecretLength = 16
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrDeliveryActive       = errors.New("webhook delivery is in progress")
+	ErrInvalidSubscription  = errors.New("invalid webhook subscription")
+)
+
+type Subscription struct {
+	ID          string `json:"id"`
+	Tenant      string `json:"tenant_id"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	// Secret sólo se devuelve al crear la suscripción o al rotarlo
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SubscriptionUpdate cambia sólo los campos presentes.
+type SubscriptionUpdate struct {
+	URL         *string `json:"url"`
+	Description *string `json:"description"`
+	Secret      *string `json:"secret"`
+	Active      *bool   `json:"active"`
+}
+
+// Payload es el cuerpo que recibe cada suscripción tras una ingesta.
+type Payload struct {
+	Event      string    `json:"event"`
+	RunID      string    `json:"run_id"`
+	Tenant     string    `json:"tenant_id"`
+	Count      int       `json:"count"`
+	Since      string    `json:"since,omitempty"`
+	Until      string    `json:"until,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	Tenant         string          `json:"tenant_id"`
+	Event          string          `json:"event"`
+	RunID          string          `json:"run_id"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+type state struct {
+	Subscriptions []*Subscription `json:"subscriptions"`
+	Deliveries    []*Delivery     `json:"deliveries"`
+}
+
+// Dispatcher guarda las suscripciones y envía a cada una el resultado de las ingestas
+// que terminan con datos nuevos. Si stateDir no está vacío, suscripciones y registro
+// de entregas se persisten en stateDir/webhooks.json.
+type Dispatcher struct {
+	client   utils.HTTPClient
+	policy   utils.RetryPolicy
+	stateDir string
+	logLimit int
+	logger   *logrus.Logger
+
+	mu            sync.Mutex
+	subscriptions map[string]*Subscription
+	deliveries    []*Delivery
+	active        map[string]bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher reintenta cada entrega según policy.MaxAttempts y policy.Backoff. El registro
+// conserva las últimas logLimit entregas (0 = sin límite).
+func NewDispatcher(client utils.HTTPClient, policy utils.RetryPolicy, stateDir string, logLimit int, logger *logrus.Logger) (*Dispatcher, error) {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		client:        client,
+		policy:        policy,
+		stateDir:      stateDir,
+		logLimit:      logLimit,
+		logger:        logger,
+		subscriptions: make(map[string]*Subscription),
+		active:        make(map[string]bool),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+
+	if stateDir != "" {
+		if err := os.MkdirAll(stateDir, 0o755); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to create webhook state directory: %v", err)
+		}
+		if err := d.load(); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+func (d *Dispatcher) Create(tenantID string, subscription Subscription) (*Subscription, error) {
+	if err := validateURL(subscription.URL); err != nil {
+		return nil, err
+	}
+
+	if subscription.Secret == "" {
+		subscription.Secret = newID("whsec_", 24)
+	} else if err := validateSecret(subscription.Secret); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	subscription.ID = newID("wh-", 8)
+	subscription.Tenant = tenantID
+	subscription.CreatedAt = now
+	subscription.UpdatedAt = now
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.subscriptions[subscription.ID] = &subscription
+	if err := d.persist(); err != nil {
+		delete(d.subscriptions, subscription.ID)
+		return nil, err
+	}
+
+	created := subscription
+	return &created, nil
+}
+
+// Get devuelve la suscripción sin el secreto. Las de otro tenant se tratan como inexistentes.
+func (d *Dispatcher) Get(tenantID, id string) (*Subscription, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	subscription, ok := d.subscriptions[id]
+	if !ok || subscription.Tenant != tenantID {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	return redact(subscription), nil
+}
+
+func (d *Dispatcher) List(tenantID string) []Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	subscriptions := make([]Subscription, 0)
+	for _, subscription := range d.subscriptions {
+		if subscription.Tenant == tenantID {
+			subscriptions = append(subscriptions, *redact(subscription))
+		}
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+	return subscriptions
+}
+
+// Update aplica los cambios y devuelve la suscripción; el secreto sólo se incluye si se ha cambiado.
+func (d *Dispatcher) Update(tenantID, id string, update SubscriptionUpdate) (*Subscription, error) {
+	if update.URL != nil {
+		if err := validateURL(*update.URL); err != nil {
+			return nil, err
+		}
+	}
+	if update.Secret != nil {
+		if err := validateSecret(*update.Secret); err != nil {
+			return nil, err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	subscription, ok := d.subscriptions[id]
+	if !ok || subscription.Tenant != tenantID {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	previous := *subscription
+	if update.URL != nil {
+		subscription.URL = *update.URL
+	}
+	if update.Description != nil {
+		subscription.Description = *update.Description
+	}
+	if update.Secret != nil {
+		subscription.Secret = *update.Secret
+	}
+	if update.Active != nil {
+		subscription.Active = *update.Active
+	}
+	subscription.UpdatedAt = time.Now().UTC()
+
+	if err := d.persist(); err != nil {
+		*subscription = previous
+		return nil, err
+	}
+
+	if update.Secret != nil {
+		updated := *subscription
+		return &updated, nil
+	}
+	return redact(subscription), nil
+}
+
+// Delete borra la suscripción; su registro de entregas se conserva.
+func (d *Dispatcher) Delete(tenantID, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	subscription, ok := d.subscriptions[id]
+	if !ok || subscription.Tenant != tenantID {
+		return ErrSubscriptionNotFound
+	}
+
+	delete(d.subscriptions, id)
+	if err := d.persist(); err != nil {
+		d.subscriptions[id] = subscription
+		return err
+	}
+	return nil
+}
+
+// Deliveries devuelve el registro de entregas de una suscripción, de la más reciente a la más antigua.
+// Con status vacío no se filtra por estado.
+func (d *Dispatcher) Deliveries(tenantID, subscriptionID, status string) ([]Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if subscription, ok := d.subscriptions[subscriptionID]; !ok || subscription.Tenant != tenantID {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	deliveries := make([]Delivery, 0)
+	for i := len(d.deliveries) - 1; i >= 0; i-- {
+		delivery := d.deliveries[i]
+		if delivery.SubscriptionID == subscriptionID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (d *Dispatcher) GetDelivery(tenantID, deliveryID string) (*Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delivery := d.findDelivery(tenantID, deliveryID)
+	if delivery == nil {
+		return nil, ErrDeliveryNotFound
+	}
+
+	snapshot := *delivery
+	return &snapshot, nil
+}
+
+// Replay vuelve a enviar una entrega con el mismo payload y los reintentos completos.
+func (d *Dispatcher) Replay(tenantID, deliveryID string) (*Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delivery := d.findDelivery(tenantID, deliveryID)
+	if delivery == nil {
+		return nil, ErrDeliveryNotFound
+	}
+	if d.active[delivery.ID] {
+		return nil, ErrDeliveryActive
+	}
+	if _, ok := d.subscriptions[delivery.SubscriptionID]; !ok {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	d.start(delivery)
+	snapshot := *delivery
+	return &snapshot, nil
+}
+
+// ReplayFailed reenvía todas las entregas fallidas de una suscripción.
+func (d *Dispatcher) ReplayFailed(tenantID, subscriptionID string) ([]Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if subscription, ok := d.subscriptions[subscriptionID]; !ok || subscription.Tenant != tenantID {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	replayed := make([]Delivery, 0)
+	for _, delivery := range d.deliveries {
+		if delivery.SubscriptionID == subscriptionID && delivery.Status == StatusFailed && !d.active[delivery.ID] {
+			d.start(delivery)
+			replayed = append(replayed, *delivery)
+		}
+	}
+	return replayed, nil
+}
+
+// RunFinished encola una entrega por cada suscripción activa del tenant. Las ejecuciones
+// sin cambios en las fuentes no generan entregas.
+func (d *Dispatcher) RunFinished(ctx context.Context, result *etl.RunResult) {
+	if result == nil || result.Unchanged {
+		return
+	}
+
+	payload, err := json.Marshal(Payload{
+		Event:      EventIngestionCompleted,
+		RunID:      result.RunID,
+		Tenant:     result.Tenant,
+		Count:      result.Count,
+		Since:      result.Since,
+		Until:      result.Until,
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		d.logger.Errorf("Failed to marshal webhook payload: %v", err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, subscription := range d.subscriptions {
+		if !subscription.Active || subscription.Tenant != result.Tenant {
+			continue
+		}
+
+		delivery := &Delivery{
+			ID:             newID("whd-", 8),
+			SubscriptionID: subscription.ID,
+			Tenant:         subscription.Tenant,
+			Event:          EventIngestionCompleted,
+			RunID:          result.RunID,
+			Payload:        payload,
+			CreatedAt:      now,
+		}
+		d.deliveries = append(d.deliveries, delivery)
+		d.start(delivery)
+	}
+	d.trim()
+}
+
+// El resto de RunObserver no genera entregas.
+func (d *Dispatcher) RunStarted(ctx context.Context, runID string) {}
+
+func (d *Dispatcher) StageCompleted(ctx context.Context, runID, stage string, duration time.Duration) {
+}
+
+func (d *Dispatcher) RunFailed(ctx context.Context, runID string, err error) {}
+
+// Close cancela los reintentos pendientes y espera a que terminen los envíos en curso.
+// Las entregas interrumpidas quedan como fallidas para poder reenviarlas.
+func (d *Dispatcher) Close() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// start marca la entrega como pendiente y la envía en segundo plano. Debe llamarse con d.mu tomado.
+func (d *Dispatcher) start(delivery *Delivery) {
+	delivery.Status = StatusPending
+	delivery.Attempts = 0
+	delivery.ResponseStatus = 0
+	delivery.Error = ""
+	delivery.DeliveredAt = nil
+	delivery.UpdatedAt = time.Now().UTC()
+	d.active[delivery.ID] = true
+	if err := d.persist(); err != nil {
+		d.logger.Errorf("Failed to persist webhook state: %v", err)
+	}
+
+	d.wg.Add(1)
+	go d.deliver(delivery)
+}
+
+func (d *Dispatcher) findDelivery(tenantID, deliveryID string) *Delivery {
+	for _, delivery := range d.deliveries {
+		if delivery.ID == deliveryID && delivery.Tenant == tenantID {
+			return delivery
+		}
+	}
+	return nil
+}
+
+// trim descarta las entregas más antiguas que no estén en curso. Debe llamarse con d.mu tomado.
+func (d *Dispatcher) trim() {
+	if d.logLimit <= 0 || len(d.deliveries) <= d.logLimit {
+		return
+	}
+
+	excess := len(d.deliveries) - d.logLimit
+	kept := d.deliveries[:0]
+	for _, delivery := range d.deliveries {
+		if excess > 0 && !d.active[delivery.ID] {
+			excess--
+			continue
+		}
+		kept = append(kept, delivery)
+	}
+	d.deliveries = kept
+}
+
+func (d *Dispatcher) load() error {
+	data, err := os.ReadFile(filepath.Join(d.stateDir, "webhooks.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read webhook state: %v", err)
+	}
+
+	var saved state
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to unmarshal webhook state: %v", err)
+	}
+
+	for _, subscription := range saved.Subscriptions {
+		d.subscriptions[subscription.ID] = subscription
+	}
+
+	// Una entrega que quedó pendiente en disco pertenece a un proceso interrumpido
+	for _, delivery := range saved.Deliveries {
+		if delivery.Status == StatusPending {
+			delivery.Status = StatusFailed
+			delivery.Error = "interrupted before completion"
+		}
+	}
+	d.deliveries = saved.Deliveries
+
+	return nil
+}
+
+// persist debe llamarse con d.mu tomado.
+func (d *Dispatcher) persist() error {
+	if d.stateDir == "" {
+		return nil
+	}
+
+	saved := state{Subscriptions: make([]*Subscription, 0, len(d.subscriptions)), Deliveries: d.deliveries}
+	for _, subscription := range d.subscriptions {
+		saved.Subscriptions = append(saved.Subscriptions, subscription)
+	}
+	sort.Slice(saved.Subscriptions, func(i, j int) bool {
+		return saved.Subscriptions[i].CreatedAt.Before(saved.Subscriptions[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(d.stateDir, "webhooks.json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write webhook state: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write webhook state: %v", err)
+	}
+	return nil
+}
+
+func validateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+	return nil
+}
+
+func validateSecret(secret string) error {
+	if len(secret) < minSecretLength {
+		return fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidSubscription, minSecretLength)
+	}
+	return nil
+}
+
+func redact(subscription *Subscription) *Subscription {
+	redacted := *subscription
+	redacted.Secret = ""
+	return &redacted
+}
+
+func newID(prefix string, size int) string {
+	suffix := make([]byte, size)
+	rand.Read(suffix)
+	return prefix + hex.EncodeToString(suffix)
+}
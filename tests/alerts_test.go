@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/admira-project/backend/internal/alerts"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAlertExpression(t *testing.T) {
+	rule, err := alerts.ParseExpression("roas < 1.5 for channel=google_ads")
+	assert.NoError(t, err)
+	assert.Equal(t, "roas", rule.Field)
+	assert.Equal(t, "<", rule.Operator)
+	assert.Equal(t, 1.5, rule.Value)
+	assert.Equal(t, map[string]string{"channel": "google_ads"}, rule.Filters)
+
+	_, err = alerts.ParseExpression("roas <")
+	assert.Error(t, err)
+}
+
+func TestThresholdAlertNotifiesWebhook(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var received map[string]interface{}
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer stub.Close()
+
+	rules := []alerts.Rule{{Expr: "roas < 1.5 for channel=google_ads"}}
+	notifier := alerts.NewWebhookNotifier(http.DefaultClient, []string{stub.URL})
+	engine, err := alerts.NewEngine(rules, storage.NewMemoryStorage(), notifier, logger)
+	assert.NoError(t, err)
+
+	engine.AfterIngest(context.Background(), []models.Metric{
+		{Date: "2023-01-01", Channel: "google_ads", Roas: 1.2},
+		{Date: "2023-01-01", Channel: "facebook_ads", Roas: 0.5},
+		{Date: "2023-01-01", Channel: "google_ads", Roas: 3.0},
+	})
+
+	assert.NotNil(t, received)
+	assert.Equal(t, "roas < 1.5 for channel=google_ads", received["rule"])
+	assert.Len(t, received["alerts"], 1)
+}
+
+func TestAnomalyAlertAgainstTrailingDays(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store := storage.NewMemoryStorage()
+	store.SaveMetrics([]models.Metric{
+		{Date: "2023-01-01", Channel: "google_ads", CampaignID: "C-1", CPA: 10},
+		{Date: "2023-01-02", Channel: "google_ads", CampaignID: "C-1", CPA: 11},
+		{Date: "2023-01-03", Channel: "google_ads", CampaignID: "C-1", CPA: 9},
+		{Date: "2023-01-04", Channel: "google_ads", CampaignID: "C-1", CPA: 10},
+	})
+
+	for _, method := range []string{alerts.MethodZScore, alerts.MethodEWMA} {
+		rules := []alerts.Rule{{Name: "cpa_spike", Field: "cpa", Anomaly: &alerts.AnomalyConfig{Method: method}}}
+		engine, err := alerts.NewEngine(rules, store, nil, logger)
+		assert.NoError(t, err)
+
+		triggered, err := engine.Evaluate([]models.Metric{
+			{Date: "2023-01-05", Channel: "google_ads", CampaignID: "C-1", CPA: 20},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, triggered, 1, method)
+
+		triggered, err = engine.Evaluate([]models.Metric{
+			{Date: "2023-01-05", Channel: "google_ads", CampaignID: "C-1", CPA: 10.5},
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, triggered, method)
+	}
+}
+
+func TestAlertRuleValidation(t *testing.T) {
+	logger := logrus.New()
+
+	_, err := alerts.NewEngine([]alerts.Rule{{Field: "unknown_field", Operator: ">", Value: 1}}, storage.NewMemoryStorage(), nil, logger)
+	assert.Error(t, err)
+
+	_, err = alerts.NewEngine([]alerts.Rule{{Field: "cpa", Operator: "~", Value: 1}}, storage.NewMemoryStorage(), nil, logger)
+	assert.Error(t, err)
+}
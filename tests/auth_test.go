@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func authTestRouter(authn *auth.Authenticator) http.Handler {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+			w.Header().Set("X-Subject", principal.Subject)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics/channel", authn.Require(auth.RoleViewer, ok))
+	mux.Handle("/ingest/run", authn.Require(auth.RoleOperator, ok))
+	return mux
+}
+
+func authRequest(handler http.Handler, method, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestAPIKeyRoles(t *testing.T) {
+	authn := auth.NewAuthenticator()
+	authn.AddAPIKey("dash-key", "dashboard", []string{auth.RoleViewer})
+	authn.AddAPIKey("ops-key", "scheduler", []string{auth.RoleOperator})
+	router := authTestRouter(authn)
+
+	viewer := http.Header{"X-Api-Key": {"dash-key"}}
+	operator := http.Header{"X-Api-Key": {"ops-key"}}
+
+	assert.Equal(t, http.StatusOK, authRequest(router, "GET", "/metrics/channel", viewer).Code)
+	assert.Equal(t, http.StatusForbidden, authRequest(router, "POST", "/ingest/run", viewer).Code)
+
+	recorder := authRequest(router, "POST", "/ingest/run", operator)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "scheduler", recorder.Header().Get("X-Subject"))
+	assert.Equal(t, http.StatusOK, authRequest(router, "GET", "/metrics/channel", operator).Code)
+
+	recorder = authRequest(router, "GET", "/metrics/channel", nil)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, authRequest(router, "GET", "/metrics/channel", http.Header{"X-Api-Key": {"nope"}}).Code)
+}
+
+func TestJWTAgainstJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	encode := func(value *big.Int) string { return base64.RawURLEncoding.EncodeToString(value.Bytes()) }
+	jwks := fmt.Sprintf(`{"keys":[
+		{"kty":"RSA","kid":"rsa-1","use":"sig","n":%q,"e":%q},
+		{"kty":"EC","kid":"ec-1","crv":"P-256","x":%q,"y":%q}
+	]}`, encode(rsaKey.N), encode(big.NewInt(int64(rsaKey.E))), encode(ecKey.X), encode(ecKey.Y))
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, []byte(jwks), 0o644))
+	keys, err := auth.LoadJWKS(path)
+	assert.NoError(t, err)
+
+	authn := auth.NewAuthenticator()
+	authn.SetJWT(auth.JWTConfig{Keys: keys, Issuer: "https://idp.example.com", Audience: "admira"})
+	router := authTestRouter(authn)
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) http.Header {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		assert.NoError(t, err)
+		return http.Header{"Authorization": {"Bearer " + signed}}
+	}
+	claims := func(roles interface{}, issuer string, expires time.Time) jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":       "ana",
+			"iss":       issuer,
+			"aud":       "admira",
+			"exp":       expires.Unix(),
+			"roles":     roles,
+			"tenant_id": "default",
+		}
+	}
+	valid := time.Now().Add(time.Hour)
+
+	viewer := sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, claims([]string{"viewer"}, "https://idp.example.com", valid))
+	recorder := authRequest(router, "GET", "/metrics/channel", viewer)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ana", recorder.Header().Get("X-Subject"))
+	assert.Equal(t, http.StatusForbidden, authRequest(router, "POST", "/ingest/run", viewer).Code)
+
+	operator := sign(jwt.SigningMethodES256, "ec-1", ecKey, claims("operator", "https://idp.example.com", valid))
+	assert.Equal(t, http.StatusOK, authRequest(router, "POST", "/ingest/run", operator).Code)
+
+	expired := sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, claims([]string{"operator"}, "https://idp.example.com", time.Now().Add(-time.Minute)))
+	assert.Equal(t, http.StatusUnauthorized, authRequest(router, "POST", "/ingest/run", expired).Code)
+
+	wrongIssuer := sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, claims([]string{"operator"}, "https://evil.example.com", valid))
+	assert.Equal(t, http.StatusUnauthorized, authRequest(router, "POST", "/ingest/run", wrongIssuer).Code)
+
+	unknownKid := sign(jwt.SigningMethodRS256, "rsa-2", rsaKey, claims([]string{"operator"}, "https://idp.example.com", valid))
+	assert.Equal(t, http.StatusUnauthorized, authRequest(router, "POST", "/ingest/run", unknownKid).Code)
+
+	// HS256 con la clave pública como secreto no debe aceptarse
+	hmacToken := sign(jwt.SigningMethodHS256, "rsa-1", []byte("secret"), claims([]string{"operator"}, "https://idp.example.com", valid))
+	assert.Equal(t, http.StatusUnauthorized, authRequest(router, "POST", "/ingest/run", hmacToken).Code)
+
+	// Sin claim de tenant el token se rechaza; "*" da acceso a todos de forma explícita
+	untenanted := claims([]string{"operator"}, "https://idp.example.com", valid)
+	delete(untenanted, "tenant_id")
+	assert.Equal(t, http.StatusUnauthorized, authRequest(router, "POST", "/ingest/run", sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, untenanted)).Code)
+
+	principal, err := authn.AuthenticateCredentials("", sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, claims("viewer", "https://idp.example.com", valid)).Get("Authorization"))
+	assert.NoError(t, err)
+	assert.Equal(t, "default", principal.BoundTenant())
+
+	platform := claims("viewer", "https://idp.example.com", valid)
+	platform["tenant_id"] = auth.AllTenants
+	principal, err = authn.AuthenticateCredentials("", sign(jwt.SigningMethodRS256, "rsa-1", rsaKey, platform).Get("Authorization"))
+	assert.NoError(t, err)
+	assert.Empty(t, principal.BoundTenant())
+}
+
+func TestAuthFailsClosedUnlessDisabled(t *testing.T) {
+	// Sin claves ni JWKS no se deja pasar a nadie
+	authn := auth.NewAuthenticator()
+	router := authTestRouter(authn)
+	assert.Equal(t, http.StatusUnauthorized, authRequest(router, "GET", "/metrics/channel", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, authRequest(router, "POST", "/ingest/run", http.Header{"X-Api-Key": {"anything"}}).Code)
+
+	authn.Disable()
+	assert.Equal(t, http.StatusOK, authRequest(router, "POST", "/ingest/run", nil).Code)
+}
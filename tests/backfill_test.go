@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/backfill"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackfillPassesDateRangeToSources(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var mu sync.Mutex
+	var ranges []string
+	adsStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.URL.Query().Get("from")+".."+r.URL.Query().Get("to"))
+		mu.Unlock()
+		w.Write([]byte(adsPayload))
+	}))
+	defer adsStub.Close()
+	var crmCalls int32
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	store := storage.NewMemoryStorage()
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), store, logger)
+
+	manager, err := backfill.NewManager(pipeline, "", 2, logger)
+	assert.NoError(t, err)
+
+	from, _ := time.Parse("2006-01-02", "2022-12-25")
+	to, _ := time.Parse("2006-01-02", "2023-01-10")
+	job, err := manager.Create(from, to, 7)
+	assert.NoError(t, err)
+	assert.Len(t, job.Chunks, 3)
+
+	job, err = manager.Run(context.Background(), job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, backfill.StatusCompleted, job.Status)
+
+	sort.Strings(ranges)
+	assert.Equal(t, []string{"2022-12-25..2022-12-31", "2023-01-01..2023-01-07", "2023-01-08..2023-01-10"}, ranges)
+
+	// El registro del 2023-01-01 sólo cae en un tramo
+	metrics, err := store.GetMetricsInRange("", "")
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+}
+
+type flakyRunner struct {
+	mu    sync.Mutex
+	fail  map[string]bool
+	calls []string
+}
+
+func (r *flakyRunner) RunRange(ctx context.Context, window etl.DateRange) (*etl.RunResult, error) {
+	from := window.From.Format("2006-01-02")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, from)
+
+	if r.fail[from] {
+		return nil, fmt.Errorf("source unavailable")
+	}
+	return &etl.RunResult{RunID: "run-" + from, Count: 1}, nil
+}
+
+func TestBackfillResumesFromPersistedState(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	stateDir := t.TempDir()
+
+	runner := &flakyRunner{fail: map[string]bool{"2023-01-04": true}}
+	manager, err := backfill.NewManager(runner, stateDir, 1, logger)
+	assert.NoError(t, err)
+
+	from, _ := time.Parse("2006-01-02", "2023-01-01")
+	to, _ := time.Parse("2006-01-02", "2023-01-09")
+	job, err := manager.Create(from, to, 3)
+	assert.NoError(t, err)
+
+	job, err = manager.Run(context.Background(), job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, backfill.StatusFailed, job.Status)
+	assert.Equal(t, "source unavailable", job.Chunks[1].Error)
+
+	// Un proceso nuevo retoma sólo el tramo fallido
+	runner = &flakyRunner{}
+	manager, err = backfill.NewManager(runner, stateDir, 1, logger)
+	assert.NoError(t, err)
+
+	job, err = manager.Run(context.Background(), job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, backfill.StatusCompleted, job.Status)
+	assert.Equal(t, []string{"2023-01-04"}, runner.calls)
+
+	_, err = manager.Run(context.Background(), "bf-missing")
+	assert.ErrorIs(t, err, backfill.ErrJobNotFound)
+}
+
+func TestBackfillDoesNotStartWhenStateCannotBeSaved(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	stateDir := t.TempDir()
+
+	runner := &flakyRunner{}
+	manager, err := backfill.NewManager(runner, stateDir, 1, logger)
+	assert.NoError(t, err)
+
+	from, _ := time.Parse("2006-01-02", "2023-01-01")
+	to, _ := time.Parse("2006-01-02", "2023-01-03")
+	job, err := manager.Create(from, to, 3)
+	assert.NoError(t, err)
+
+	// Un directorio en la ruta del temporal hace fallar la escritura del estado
+	blocker := filepath.Join(stateDir, job.ID+".json.tmp")
+	assert.NoError(t, os.Mkdir(blocker, 0o755))
+
+	_, err = manager.Run(context.Background(), job.ID)
+	assert.Error(t, err)
+	assert.Empty(t, runner.calls)
+
+	current, err := manager.Get(job.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, backfill.StatusPending, current.Status)
+	}
+
+	// El job no queda marcado como activo y se puede lanzar en cuanto se arregla el disco
+	assert.NoError(t, os.Remove(blocker))
+	job, err = manager.Run(context.Background(), job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, backfill.StatusCompleted, job.Status)
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditionalFetchShortCircuitsPipeline(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var adsFull, adsNotModified int32
+	adsStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&adsNotModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&adsFull, 1)
+
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		gz.Write([]byte(adsPayload))
+		gz.Close()
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer adsStub.Close()
+
+	lastModified := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+	crmStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write([]byte(crmPayload))
+		zw.Close()
+
+		w.Header().Set("Last-Modified", lastModified)
+		w.Header().Set("Content-Encoding", "deflate")
+		w.Write(compressed.Bytes())
+	}))
+	defer crmStub.Close()
+
+	cache, err := utils.NewResponseCache(t.TempDir())
+	assert.NoError(t, err)
+
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	extractor.SetCache(cache)
+	store := storage.NewMemoryStorage()
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), store, logger)
+
+	first, err := pipeline.Run(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.False(t, first.Unchanged)
+	assert.Equal(t, 1, first.Count)
+	assert.Equal(t, 4.0, first.Metrics[0].Roas)
+
+	second, err := pipeline.Run(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.True(t, second.Unchanged)
+	assert.Equal(t, int32(1), adsFull)
+	assert.Equal(t, int32(1), adsNotModified)
+
+	// Con force se reprocesa el contenido cacheado
+	forced, err := pipeline.ForceRunRange(context.Background(), etl.DateRange{})
+	assert.NoError(t, err)
+	assert.False(t, forced.Unchanged)
+	assert.Equal(t, 1, forced.Count)
+
+	saved, err := store.GetMetricsInRange("", "")
+	assert.NoError(t, err)
+	assert.Len(t, saved, 2)
+
+	// Un proceso nuevo con la caché en disco sí ingesta aunque reciba 304
+	fresh := etl.NewPipeline(extractor, etl.NewTransformer(logger), storage.NewMemoryStorage(), logger)
+	result, err := fresh.Run(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.False(t, result.Unchanged)
+	assert.Equal(t, 1, result.Count)
+}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/catalog"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCampaignCatalogEnrichment(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	campaigns := catalog.NewCatalog()
+	count, err := campaigns.LoadCSV(tenant.Default, strings.NewReader(
+		"campaign_id,name,owner,objective,business_unit,tags\n"+
+			"C-1,Back to School,ana,conversion,retail,seasonal;search\n"+
+			"C-2,Brand Awareness,luis,awareness,corporate,brand\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	// Una campaña de otro tenant con el mismo ID no se usa para enriquecer
+	assert.NoError(t, campaigns.Upsert("acme", models.Campaign{CampaignID: "C-3", Name: "Acme Secret"}))
+
+	transformer := etl.NewTransformer(logger)
+	transformer.SetCatalog(campaigns)
+
+	adsData := &models.AdsData{}
+	adsData.External.Ads.Performance = []models.AdsPerformance{
+		{Date: "2023-01-01", CampaignID: "C-1", Channel: "google_ads", Clicks: 10, Cost: 20, UtmCampaign: "bts"},
+		{Date: "2023-01-01", CampaignID: "C-2", Channel: "facebook_ads", Clicks: 5, Cost: 10, UtmCampaign: "brand"},
+		{Date: "2023-01-01", CampaignID: "C-3", Channel: "google_ads", Clicks: 1, Cost: 2, UtmCampaign: "other"},
+	}
+
+	metrics, err := transformer.Transform(adsData, &models.CrmData{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 3)
+
+	byCampaign := make(map[string]models.Metric)
+	for _, metric := range metrics {
+		byCampaign[metric.CampaignID] = metric
+	}
+	assert.Equal(t, "Back to School", byCampaign["C-1"].CampaignName)
+	assert.Equal(t, "ana", byCampaign["C-1"].Owner)
+	assert.Equal(t, []string{"seasonal", "search"}, byCampaign["C-1"].Tags)
+	assert.Empty(t, byCampaign["C-3"].CampaignName)
+
+	store := storage.NewMemoryStorage()
+	assert.NoError(t, store.SaveMetrics(metrics))
+
+	filtered, err := store.GetMetricsByChannel(models.MetricsRequest{Owner: "luis"})
+	assert.NoError(t, err)
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, "C-2", filtered[0].CampaignID)
+
+	groups, err := store.GetMetricsGrouped(models.MetricsRequest{GroupBy: "business_unit"})
+	assert.NoError(t, err)
+	assert.Len(t, groups, 3)
+	assert.Equal(t, "corporate", groups[0].Key)
+	assert.Equal(t, "retail", groups[1].Key)
+	assert.Equal(t, 2.0, groups[1].CPC)
+	assert.Equal(t, "unknown", groups[2].Key)
+
+	_, err = store.GetMetricsGrouped(models.MetricsRequest{GroupBy: "nope"})
+	assert.Error(t, err)
+}
+
+func TestCampaignCatalogIsScopedByTenant(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	campaigns := catalog.NewCatalog()
+	assert.NoError(t, campaigns.Upsert(tenant.Default, models.Campaign{CampaignID: "C-1", Name: "Default Launch", Owner: "ana"}))
+
+	authn := auth.NewAuthenticator()
+	authn.AddTenantAPIKey("globex-viewer", "globex-dashboard", "globex", []string{auth.RoleViewer})
+	authn.AddTenantAPIKey("globex-operator", "globex-ops", "globex", []string{auth.RoleOperator})
+
+	handler := api.NewCampaignHandler(campaigns, logger)
+	router := mux.NewRouter()
+	router.Handle("/campaigns", authn.Require(auth.RoleViewer, handler.ListHandler)).Methods("GET")
+	router.Handle("/campaigns/import", authn.Require(auth.RoleOperator, handler.ImportHandler)).Methods("POST")
+	router.Handle("/campaigns/{id}", authn.Require(auth.RoleViewer, handler.GetHandler)).Methods("GET")
+	router.Handle("/campaigns/{id}", authn.Require(auth.RoleOperator, handler.UpsertHandler)).Methods("PUT")
+	router.Handle("/campaigns/{id}", authn.Require(auth.RoleOperator, handler.DeleteHandler)).Methods("DELETE")
+
+	call := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// globex no ve las campañas del tenant por defecto ni puede pedirlas
+	var listed []models.Campaign
+	recorder := call("GET", "/campaigns", "globex-viewer", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&listed))
+	assert.Empty(t, listed)
+	assert.Equal(t, http.StatusNotFound, call("GET", "/campaigns/C-1", "globex-viewer", "").Code)
+	assert.Equal(t, http.StatusForbidden, call("GET", "/campaigns?tenant=default", "globex-viewer", "").Code)
+
+	// Sus escrituras van a su propio catálogo
+	assert.Equal(t, http.StatusOK, call("PUT", "/campaigns/C-1", "globex-operator", `{"name":"Globex Launch"}`).Code)
+	assert.Equal(t, http.StatusOK, call("POST", "/campaigns/import", "globex-operator", "campaign_id,name\nC-2,Globex Brand\n").Code)
+	assert.Equal(t, http.StatusForbidden, call("DELETE", "/campaigns/C-1?tenant=default", "globex-operator", "").Code)
+
+	original, ok := campaigns.Lookup(tenant.Default, "C-1")
+	assert.True(t, ok)
+	assert.Equal(t, "Default Launch", original.Name)
+	_, ok = campaigns.Lookup(tenant.Default, "C-2")
+	assert.False(t, ok)
+
+	globex := campaigns.List("globex")
+	assert.Len(t, globex, 2)
+	assert.Equal(t, "Globex Launch", globex[0].Name)
+
+	assert.Equal(t, http.StatusNoContent, call("DELETE", "/campaigns/C-1", "globex-operator", "").Code)
+	_, ok = campaigns.Lookup(tenant.Default, "C-1")
+	assert.True(t, ok)
+}
@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/events"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func drain(stream <-chan events.Event) []events.Event {
+	var received []events.Event
+	for {
+		select {
+		case event := <-stream:
+			received = append(received, event)
+		default:
+			return received
+		}
+	}
+}
+
+func TestPipelineEventsLifecycle(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	broker := events.NewBroker(16)
+	observer := events.NewPipelineEvents(broker, logger)
+
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), storage.NewMemoryStorage(), logger)
+	pipeline.AddObserver(observer)
+	pipeline.AddHook(observer)
+
+	stream, _, cancel := broker.Subscribe(tenant.Default, 0)
+	defer cancel()
+	other, _, cancelOther := broker.Subscribe("acme", 0)
+	defer cancelOther()
+
+	result, err := pipeline.Run(context.Background(), time.Time{})
+	assert.NoError(t, err)
+
+	received := drain(stream)
+	var types, stages []string
+	for _, event := range received {
+		types = append(types, event.Type)
+		if event.Type == events.IngestionStageCompleted {
+			var stage events.StageEvent
+			json.Unmarshal(event.Data, &stage)
+			assert.Equal(t, result.RunID, stage.RunID)
+			stages = append(stages, stage.Stage)
+		}
+	}
+
+	assert.Equal(t, events.IngestionStarted, types[0])
+	assert.Equal(t, events.IngestionFinished, types[len(types)-1])
+	assert.Contains(t, types, events.MetricsSaved)
+	assert.Equal(t, []string{"extract_ads", "extract_crm", "transform", "save"}, stages)
+	assert.Empty(t, drain(other))
+
+	var finished etl.RunResult
+	json.Unmarshal(received[len(received)-1].Data, &finished)
+	assert.Equal(t, result.RunID, finished.RunID)
+	assert.Equal(t, result.Count, finished.Count)
+
+	// Una fuente caída termina en ingestion.failed
+	failing := etl.NewPipeline(etl.NewExtractor(http.DefaultClient, "http://127.0.0.1:1", crmStub.URL, logger), etl.NewTransformer(logger), storage.NewMemoryStorage(), logger)
+	failing.AddObserver(observer)
+	_, err = failing.Run(context.Background(), time.Time{})
+	assert.Error(t, err)
+
+	received = drain(stream)
+	last := received[len(received)-1]
+	assert.Equal(t, events.IngestionFailed, last.Type)
+	var failed events.FailedEvent
+	json.Unmarshal(last.Data, &failed)
+	assert.Contains(t, failed.Error, "extract ads data")
+}
+
+func TestMetricsSavedBatches(t *testing.T) {
+	broker := events.NewBroker(0)
+	observer := events.NewPipelineEvents(broker, logrus.New())
+
+	stream, _, cancel := broker.Subscribe("acme", 0)
+	defer cancel()
+
+	observer.AfterIngest(tenant.WithID(context.Background(), "acme"), make([]models.Metric, 1200))
+
+	var counts []int
+	for _, event := range drain(stream) {
+		var batch events.MetricsEvent
+		json.Unmarshal(event.Data, &batch)
+		assert.Len(t, batch.Metrics, batch.Count)
+		counts = append(counts, batch.Count)
+	}
+	assert.Equal(t, []int{500, 500, 200}, counts)
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	broker := events.NewBroker(0)
+	stream, _, cancel := broker.Subscribe(tenant.Default, 0)
+	defer cancel()
+
+	for i := 0; i < 100; i++ {
+		broker.Publish(events.MetricsSaved, tenant.Default, i)
+	}
+
+	assert.Equal(t, 0, broker.Subscribers())
+	received := 0
+	for range stream {
+		received++
+	}
+	assert.Less(t, received, 100)
+}
+
+type sseEvent struct {
+	id, event, data string
+}
+
+func readEvents(t *testing.T, reader *bufio.Reader, n int) []sseEvent {
+	var received []sseEvent
+	var current sseEvent
+	for len(received) < n {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		line = strings.TrimRight(line, "\n")
+
+		switch {
+		case line == "":
+			if current.event != "" {
+				received = append(received, current)
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			current.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			current.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return received
+}
+
+func TestEventsStreamHandler(t *testing.T) {
+	broker := events.NewBroker(16)
+	handler := api.NewEventsHandler(broker, logrus.New())
+	handler.SetHeartbeat(20 * time.Millisecond)
+
+	server := httptest.NewServer(http.HandlerFunc(handler.StreamHandler))
+	defer server.Close()
+
+	first, _ := broker.Publish(events.IngestionStarted, tenant.Default, events.RunEvent{RunID: "r1"})
+	broker.Publish(events.IngestionStarted, "acme", events.RunEvent{RunID: "a1"})
+	broker.Publish(events.MetricsSaved, tenant.Default, events.MetricsEvent{Count: 1})
+	broker.Publish(events.IngestionFinished, tenant.Default, events.RunEvent{RunID: "r1"})
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"?types=ingestion.", nil)
+	request.Header.Set("Last-Event-ID", first.IDString())
+	resp, err := http.DefaultClient.Do(request)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, _ := reader.ReadString('\n')
+	assert.Equal(t, "retry: 3000\n", line)
+
+	// Del histórico sólo llega lo posterior al id, del mismo tenant y del tipo pedido
+	replayed := readEvents(t, reader, 1)
+	assert.Equal(t, events.IngestionFinished, replayed[0].event)
+	assert.JSONEq(t, `{"run_id":"r1"}`, replayed[0].data)
+
+	broker.Publish(events.MetricsSaved, tenant.Default, events.MetricsEvent{Count: 2})
+	failed, _ := broker.Publish(events.IngestionFailed, tenant.Default, events.FailedEvent{RunID: "r2", Error: "boom"})
+
+	live := readEvents(t, reader, 1)
+	assert.Equal(t, events.IngestionFailed, live[0].event)
+	assert.Equal(t, failed.IDString(), live[0].id)
+
+	line, _ = reader.ReadString('\n')
+	assert.Equal(t, ": ping\n", line)
+}
+
+func TestEventsStreamRejectsInvalidRequests(t *testing.T) {
+	handler := api.NewEventsHandler(events.NewBroker(0), logrus.New())
+
+	request := httptest.NewRequest(http.MethodGet, "/events", nil)
+	request.Header.Set("Last-Event-ID", "abc")
+	recorder := httptest.NewRecorder()
+	handler.StreamHandler(recorder, request)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.StreamHandler(recorder, httptest.NewRequest(http.MethodGet, "/events?tenant=Bad%20Tenant", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/gql"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/graphql-go/graphql/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func graphqlStore() storage.Storage {
+	store := storage.NewMemoryStorage()
+	store.SaveMetrics([]models.Metric{
+		{Date: "2024-01-01", Channel: "google", CampaignID: "c1", CampaignName: "Spring", Clicks: 100, Cost: 50, Leads: 5, Revenue: 400},
+		{Date: "2024-01-02", Channel: "google", CampaignID: "c1", CampaignName: "Spring", Clicks: 80, Cost: 120, Leads: 4, Revenue: 100},
+		{Date: "2024-01-02", Channel: "facebook", CampaignID: "c2", CampaignName: "Summer", Clicks: 40, Cost: 80, Leads: 8, Revenue: 900},
+		{TenantID: "acme", Date: "2024-01-01", Channel: "google", CampaignID: "a1", Clicks: 7, Cost: 7},
+	})
+	return store
+}
+
+func execute(t *testing.T, executor *gql.Executor, ctx context.Context, query string, variables map[string]interface{}) map[string]interface{} {
+	result, err := executor.Execute(ctx, gql.Request{Query: query, Variables: variables})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Empty(t, result.Errors)
+
+	data, _ := json.Marshal(result.Data)
+	var decoded map[string]interface{}
+	json.Unmarshal(data, &decoded)
+	return decoded
+}
+
+func TestGraphQLMetricsFilterSortAndPaginate(t *testing.T) {
+	executor, err := gql.NewExecutor(graphqlStore(), gql.Limits{MaxDepth: 15, MaxComplexity: 20000})
+	assert.NoError(t, err)
+
+	data := execute(t, executor, context.Background(), `{
+		metrics(filter: {channel: "google", from: "2024-01-01"}, sort: [{field: COST, direction: DESC}], limit: 1) {
+			total limit offset
+			items { date campaignId cost cpc }
+		}
+	}`, nil)
+
+	page := data["metrics"].(map[string]interface{})
+	assert.Equal(t, float64(2), page["total"])
+	items := page["items"].([]interface{})
+	assert.Len(t, items, 1)
+	assert.Equal(t, "2024-01-02", items[0].(map[string]interface{})["date"])
+	assert.Equal(t, float64(120), items[0].(map[string]interface{})["cost"])
+
+	data = execute(t, executor, context.Background(), `query Groups($by: GroupBy) {
+		metricGroups(groupBy: $by, sort: [{field: REVENUE, direction: DESC}]) {
+			total
+			items { groupBy key clicks revenue roas }
+		}
+	}`, map[string]interface{}{"by": "CHANNEL"})
+
+	groups := data["metricGroups"].(map[string]interface{})["items"].([]interface{})
+	assert.Len(t, groups, 2)
+	first := groups[0].(map[string]interface{})
+	assert.Equal(t, "facebook", first["key"])
+	assert.Equal(t, "channel", first["groupBy"])
+	second := groups[1].(map[string]interface{})
+	assert.Equal(t, float64(180), second["clicks"])
+	assert.InDelta(t, 500.0/170.0, second["roas"], 0.0001)
+}
+
+func TestGraphQLScopesToContextTenant(t *testing.T) {
+	executor, err := gql.NewExecutor(graphqlStore(), gql.Limits{})
+	assert.NoError(t, err)
+
+	data := execute(t, executor, tenant.WithID(context.Background(), "acme"), `{ metrics { total items { tenantId campaignId } } }`, nil)
+	page := data["metrics"].(map[string]interface{})
+	assert.Equal(t, float64(1), page["total"])
+	assert.Equal(t, "a1", page["items"].([]interface{})[0].(map[string]interface{})["campaignId"])
+
+	result, err := executor.Execute(context.Background(), gql.Request{Query: `{ metrics(limit: 5000) { total } }`})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.Errors)
+	assert.Contains(t, result.Errors[0].Message, "limit must be between 1 and 1000")
+}
+
+func TestGraphQLDepthAndComplexityLimits(t *testing.T) {
+	executor, err := gql.NewExecutor(graphqlStore(), gql.Limits{MaxDepth: 2, MaxComplexity: 100})
+	assert.NoError(t, err)
+
+	_, err = executor.Execute(context.Background(), gql.Request{Query: `{ metricGroups(limit: 1) { items { key } } }`})
+	assert.True(t, errors.Is(err, gql.ErrQueryTooDeep))
+
+	executor, err = gql.NewExecutor(graphqlStore(), gql.Limits{MaxDepth: 5, MaxComplexity: 100})
+	assert.NoError(t, err)
+
+	_, err = executor.Execute(context.Background(), gql.Request{Query: `{ metrics(limit: 10) { items { date clicks } } }`})
+	assert.NoError(t, err)
+
+	// El coste se multiplica por el limit, también si llega como variable o vía fragmento
+	_, err = executor.Execute(context.Background(), gql.Request{
+		Query:     `query Q($n: Int) { ...Page } fragment Page on Query { metrics(limit: $n) { items { date clicks } } }`,
+		Variables: map[string]interface{}{"n": float64(500)},
+	})
+	assert.True(t, errors.Is(err, gql.ErrQueryTooComplex))
+}
+
+func TestGraphQLIntrospectionWithinDefaultLimits(t *testing.T) {
+	executor, err := gql.NewExecutor(graphqlStore(), gql.Limits{MaxDepth: 15, MaxComplexity: 20000})
+	assert.NoError(t, err)
+
+	result, err := executor.Execute(context.Background(), gql.Request{Query: testutil.IntrospectionQuery})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Errors)
+}
+
+func TestGraphQLHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	executor, err := gql.NewExecutor(graphqlStore(), gql.Limits{MaxDepth: 2})
+	assert.NoError(t, err)
+	handler := api.NewGraphQLHandler(executor, logger)
+
+	body := `{"query": "query($c: String) { metrics(filter: {channel: $c}) { total } }", "variables": {"c": "facebook"}}`
+	recorder := httptest.NewRecorder()
+	handler.QueryHandler(recorder, httptest.NewRequest("POST", "/graphql", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data": {"metrics": {"total": 1}}}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	handler.QueryHandler(recorder, httptest.NewRequest("GET", "/graphql?tenant=acme&query="+url.QueryEscape("{ metrics { total } }"), nil))
+	assert.JSONEq(t, `{"data": {"metrics": {"total": 1}}}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	handler.QueryHandler(recorder, httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape("{ metrics { items { date } } }"), nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "query exceeds maximum depth")
+}
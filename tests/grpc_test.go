@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/backfill"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/grpcapi"
+	"github.com/admira-project/backend/internal/grpcapi/admirav1"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/queue"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func grpcTestClient(t *testing.T, store storage.Storage, pipeline *etl.Pipeline, ingestQueue queue.Queue, authn *auth.Authenticator, interceptors ...grpc.UnaryServerInterceptor) *grpc.ClientConn {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	manager, err := backfill.NewManager(pipeline, "", 1, logger)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpcapi.NewServer(store, pipeline, manager, ingestQueue, authn, logger, interceptors...)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func withAPIKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func TestGRPCMetricsQueries(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	store.SaveMetrics([]models.Metric{
+		{Date: "2024-01-01", Channel: "google", CampaignID: "c1", CampaignName: "Spring", Clicks: 10, Cost: 5},
+		{Date: "2024-01-02", Channel: "facebook", CampaignID: "c2", CampaignName: "Summer", Clicks: 20, Cost: 8},
+		{TenantID: "acme", Date: "2024-01-01", Channel: "google", CampaignID: "a1", Clicks: 3},
+	})
+
+	authn := auth.NewAuthenticator()
+	authn.AddAPIKey("viewer-key", "dashboard", []string{auth.RoleViewer})
+	authn.AddTenantAPIKey("acme-key", "acme-dashboard", "acme", []string{auth.RoleViewer})
+
+	client := admirav1.NewMetricsServiceClient(grpcTestClient(t, store, nil, nil, authn))
+
+	var header metadata.MD
+	response, err := client.GetMetricsByChannel(withAPIKey("viewer-key"), &admirav1.MetricsRequest{Channel: "google"}, grpc.Header(&header))
+	assert.NoError(t, err)
+	if assert.Len(t, response.Metrics, 1) {
+		assert.Equal(t, "c1", response.Metrics[0].CampaignId)
+		assert.Equal(t, int64(10), response.Metrics[0].Clicks)
+	}
+	assert.Len(t, header.Get("x-request-id"), 1)
+
+	groups, err := client.GetMetricsGrouped(withAPIKey("viewer-key"), &admirav1.MetricsRequest{GroupBy: "channel"})
+	assert.NoError(t, err)
+	assert.Len(t, groups.Groups, 2)
+
+	// La credencial con tenant sólo ve el suyo y no puede pedir otro
+	response, err = client.GetMetricsByChannel(withAPIKey("acme-key"), &admirav1.MetricsRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, response.Metrics, 1) {
+		assert.Equal(t, "a1", response.Metrics[0].CampaignId)
+	}
+	_, err = client.GetMetricsByChannel(withAPIKey("acme-key"), &admirav1.MetricsRequest{TenantId: "default"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetMetricsByChannel(context.Background(), &admirav1.MetricsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.GetMetricsByChannel(withAPIKey("viewer-key"), &admirav1.MetricsRequest{From: "01/02/2024"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetMetricsGrouped(withAPIKey("viewer-key"), &admirav1.MetricsRequest{GroupBy: "color"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPCIngestionControl(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	store := storage.NewMemoryStorage()
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), store, logger)
+
+	authn := auth.NewAuthenticator()
+	authn.AddAPIKey("viewer-key", "dashboard", []string{auth.RoleViewer})
+	authn.AddAPIKey("operator-key", "scheduler", []string{auth.RoleOperator})
+
+	client := admirav1.NewIngestionServiceClient(grpcTestClient(t, store, pipeline, nil, authn))
+
+	_, err := client.RunIngestion(withAPIKey("viewer-key"), &admirav1.RunIngestionRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	run, err := client.RunIngestion(withAPIKey("operator-key"), &admirav1.RunIngestionRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), run.Count)
+	assert.Equal(t, "default", run.TenantId)
+	assert.NotEmpty(t, run.RunId)
+
+	job, err := client.CreateIngestionJob(withAPIKey("operator-key"), &admirav1.CreateIngestionJobRequest{From: "2023-01-01", To: "2023-01-10", ChunkDays: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), job.ChunkDays)
+
+	assert.Eventually(t, func() bool {
+		current, err := client.GetIngestionJob(withAPIKey("viewer-key"), &admirav1.IngestionJobRequest{Id: job.Id})
+		return err == nil && current.Status == backfill.StatusCompleted && len(current.Chunks) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	_, err = client.GetIngestionJob(withAPIKey("viewer-key"), &admirav1.IngestionJobRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.ListRuns(withAPIKey("viewer-key"), &admirav1.ListRunsRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestGRPCRateLimitAndQuota(t *testing.T) {
+	logger := quietLogger()
+
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	store := storage.NewMemoryStorage()
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), store, logger)
+
+	authn := auth.NewAuthenticator()
+	authn.AddAPIKey("operator-key", "scheduler", []string{auth.RoleOperator})
+	authn.AddAPIKey("other-key", "backup", []string{auth.RoleOperator})
+
+	limiter := api.NewClientLimiter(utils.RateLimit{RequestsPerSecond: 1000, Burst: 100}, false)
+	limiter.SetRouteLimit("/metrics/channel", utils.RateLimit{RequestsPerSecond: 0.001, Burst: 1})
+	quota := api.NewDailyQuota(1, false)
+
+	conn := grpcTestClient(t, store, pipeline, nil, authn, grpcapi.RateLimitInterceptor(limiter), grpcapi.QuotaInterceptor(quota))
+	ingestion := admirav1.NewIngestionServiceClient(conn)
+	metrics := admirav1.NewMetricsServiceClient(conn)
+
+	// La cuota cuenta por credencial y la comparten los métodos que lanzan ingestas
+	_, err := ingestion.RunIngestion(withAPIKey("operator-key"), &admirav1.RunIngestionRequest{})
+	assert.NoError(t, err)
+	_, err = ingestion.RunIngestion(withAPIKey("operator-key"), &admirav1.RunIngestionRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = ingestion.CreateIngestionJob(withAPIKey("operator-key"), &admirav1.CreateIngestionJobRequest{From: "2023-01-01", To: "2023-01-02"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = ingestion.RunIngestion(withAPIKey("other-key"), &admirav1.RunIngestionRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&adsCalls))
+
+	// Los límites por ruta de API_RATE_LIMIT_ROUTES se aplican al método equivalente
+	_, err = metrics.GetMetricsByChannel(withAPIKey("operator-key"), &admirav1.MetricsRequest{})
+	assert.NoError(t, err)
+	var header metadata.MD
+	_, err = metrics.GetMetricsByChannel(withAPIKey("operator-key"), &admirav1.MetricsRequest{}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+	_, err = metrics.GetMetricsByFunnel(withAPIKey("operator-key"), &admirav1.MetricsRequest{})
+	assert.NoError(t, err)
+}
+
+func TestGRPCRunIngestionEnqueuesInQueueMode(t *testing.T) {
+	logger := quietLogger()
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	store := storage.NewMemoryStorage()
+	pipeline := etl.NewPipeline(etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger), etl.NewTransformer(logger), store, logger)
+	ingestQueue := queue.NewMemoryQueue()
+
+	authn := auth.NewAuthenticator()
+	authn.AddAPIKey("operator-key", "scheduler", []string{auth.RoleOperator})
+	client := admirav1.NewIngestionServiceClient(grpcTestClient(t, store, pipeline, ingestQueue, authn))
+
+	// La llamada sólo encola: no se llama a las fuentes hasta que un worker toma el trabajo
+	run, err := client.RunIngestion(withAPIKey("operator-key"), &admirav1.RunIngestionRequest{Since: "2023-01-01", Force: true})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, run.JobId)
+	assert.Empty(t, run.RunId)
+	assert.Equal(t, queue.StatusPending, run.Status)
+	assert.Equal(t, "default", run.TenantId)
+	assert.Equal(t, int32(0), adsCalls)
+
+	job, err := ingestQueue.Get(run.JobId)
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-01-01", job.Since)
+	assert.True(t, job.Force)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.NewWorker(ingestQueue, pipeline, "worker-1", logger).Run(ctx)
+
+	assert.Eventually(t, func() bool {
+		current, _ := ingestQueue.Get(run.JobId)
+		return current.Status == queue.StatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestGRPCReflection(t *testing.T) {
+	authn := auth.NewAuthenticator()
+	authn.AddAPIKey("viewer-key", "dashboard", []string{auth.RoleViewer})
+
+	conn := grpcTestClient(t, storage.NewMemoryStorage(), nil, nil, authn)
+	stream, err := grpc_reflection_v1alpha.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	assert.NoError(t, err)
+
+	assert.NoError(t, stream.Send(&grpc_reflection_v1alpha.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection_v1alpha.ServerReflectionRequest_ListServices{},
+	}))
+	response, err := stream.Recv()
+	assert.NoError(t, err)
+
+	var services []string
+	for _, service := range response.GetListServicesResponse().GetService() {
+		services = append(services, service.Name)
+	}
+	assert.Contains(t, services, "admira.v1.MetricsService")
+	assert.Contains(t, services, "admira.v1.IngestionService")
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerFailsFastAndRecovers(t *testing.T) {
+	var calls int32
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	guard := utils.NewGuardedHTTPClient(http.DefaultClient,
+		utils.BreakerConfig{FailureThreshold: 2, OpenTimeout: 100 * time.Millisecond}, utils.RateLimit{})
+
+	do := func() (*http.Response, error) {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := guard.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	do()
+	do()
+	assert.Equal(t, utils.StateOpen, guard.States()[0].State)
+
+	_, err := do()
+	assert.ErrorIs(t, err, utils.ErrCircuitOpen)
+	assert.Equal(t, int32(2), calls)
+
+	time.Sleep(120 * time.Millisecond)
+	assert.Equal(t, utils.StateHalfOpen, guard.States()[0].State)
+
+	healthy.Store(true)
+	resp, err := do()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, utils.StateClosed, guard.States()[0].State)
+}
+
+func TestCircuitBreakerStopsRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	guard := utils.NewGuardedHTTPClient(http.DefaultClient,
+		utils.BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}, utils.RateLimit{})
+	client := utils.NewRetryableHTTPClientWithPolicy(logger, guard, utils.DefaultRetryPolicy(10, 1))
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	_, err := client.Do(req)
+	assert.ErrorIs(t, err, utils.ErrCircuitOpen)
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestRateLimiterPerHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	guard := utils.NewGuardedHTTPClient(http.DefaultClient, utils.BreakerConfig{}, utils.RateLimit{})
+	host, _ := url.Parse(server.URL)
+	guard.SetHostLimit(host.Host, utils.RateLimit{RequestsPerSecond: 20, Burst: 2})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := guard.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+	// 2 de ráfaga + 2 a 20 rps
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	_, err := guard.Do(req)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestReadyReportsSourceStates(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	guard := utils.NewGuardedHTTPClient(http.DefaultClient,
+		utils.BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}, utils.RateLimit{})
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, _ := guard.Do(req)
+	resp.Body.Close()
+
+	handler := api.NewHandler(nil, nil, logger)
+	handler.SetSourceGuard(guard)
+
+	type readyBody struct {
+		Status  string            `json:"status"`
+		Sources []utils.HostState `json:"sources"`
+	}
+
+	// Sin identificarse sólo se ve el estado, no los hosts ni los breakers
+	recorder := httptest.NewRecorder()
+	handler.ReadyHandler(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	assert.NotContains(t, recorder.Body.String(), server.URL)
+	var body readyBody
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&body))
+	assert.Equal(t, "degraded", body.Status)
+	assert.Empty(t, body.Sources)
+
+	req = httptest.NewRequest("GET", "/readyz", nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Subject: "ops", Roles: []string{auth.RoleViewer}}))
+	recorder = httptest.NewRecorder()
+	handler.ReadyHandler(recorder, req)
+	body = readyBody{}
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&body))
+	assert.Equal(t, "degraded", body.Status)
+	if assert.Len(t, body.Sources, 1) {
+		assert.Equal(t, utils.StateOpen, body.Sources[0].State)
+	}
+}
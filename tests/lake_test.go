@@ -0,0 +1,328 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/lake"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/parquet-go/parquet-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 implementa lo justo de la API S3 (PUT, GET, DELETE y listado de objetos, rutas path-style)
+// para probar el export sin un MinIO real.
+type fakeS3 struct {
+	*httptest.Server
+	mu      sync.Mutex
+	objects map[string][]byte
+	// deny devuelve AccessDenied para las claves que cumplan la condición
+	deny func(key string) bool
+}
+
+func newFakeS3() *fakeS3 {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	fake.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			if fake.deny != nil && fake.deny(key) {
+				s3Error(w, http.StatusForbidden, "AccessDenied")
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+				body = decodeAWSChunked(body)
+			}
+			fake.objects[key] = body
+			w.Header().Set("ETag", `"etag"`)
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet, http.MethodHead:
+			if r.URL.Query().Get("list-type") == "2" {
+				fake.list(w, strings.TrimSuffix(key, "/"), r.URL.Query().Get("prefix"))
+				return
+			}
+			body, ok := fake.objects[key]
+			if !ok {
+				s3Error(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			w.Header().Set("ETag", `"etag"`)
+			w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.WriteHeader(http.StatusOK)
+			if r.Method == http.MethodGet {
+				w.Write(body)
+			}
+		case http.MethodDelete:
+			delete(fake.objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	return fake
+}
+
+// list responde un ListObjectsV2 sin paginar. Se llama con mu tomado.
+func (f *fakeS3) list(w http.ResponseWriter, bucket, prefix string) {
+	var contents strings.Builder
+	count := 0
+	for key, body := range f.objects {
+		name, ok := strings.CutPrefix(key, bucket+"/")
+		if !ok || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		count++
+		fmt.Fprintf(&contents, `<Contents><Key>%s</Key><Size>%d</Size><ETag>"etag"</ETag><LastModified>%s</LastModified></Contents>`,
+			name, len(body), time.Now().UTC().Format(time.RFC3339))
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>%s</Name><Prefix>%s</Prefix><KeyCount>%d</KeyCount><MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated>%s</ListBucketResult>`,
+		bucket, prefix, count, contents.String())
+}
+
+func (f *fakeS3) keys(prefix string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// decodeAWSChunked quita el marco "<tamaño hex>;chunk-signature=...\r\n<datos>\r\n" de las subidas firmadas por bloques.
+func decodeAWSChunked(body []byte) []byte {
+	var decoded []byte
+	for len(body) > 0 {
+		header, rest, ok := bytes.Cut(body, []byte("\r\n"))
+		if !ok {
+			break
+		}
+		sizeHex, _, _ := bytes.Cut(header, []byte(";"))
+		size, err := strconv.ParseInt(string(sizeHex), 16, 64)
+		if err != nil || size == 0 || int64(len(rest)) < size {
+			break
+		}
+		decoded = append(decoded, rest[:size]...)
+		body = bytes.TrimPrefix(rest[size:], []byte("\r\n"))
+	}
+	return decoded
+}
+
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>`+code+`</Code><Message>`+code+`</Message></Error>`)
+}
+
+func TestLakeExportLocal(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	dir := t.TempDir()
+	store, err := lake.NewLocalStore(dir)
+	assert.NoError(t, err)
+	sink := lake.NewSink(store, logger)
+
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), storage.NewMemoryStorage(), logger)
+	pipeline.AddObserver(sink)
+
+	result, err := pipeline.Run(context.Background(), time.Time{})
+	if !assert.NoError(t, err) || !assert.NotZero(t, result.Count) {
+		t.FailNow()
+	}
+
+	manifest, err := sink.Manifest(context.Background(), "default", result.RunID)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, result.Count, manifest.Rows)
+	assert.Equal(t, lake.Table, manifest.Table)
+
+	rows := 0
+	for _, file := range manifest.Files {
+		assert.Equal(t, "metrics/tenant=default/date="+file.Date+"/channel="+file.Channel+"/part-"+result.RunID+".parquet", file.Path)
+
+		data, err := os.ReadFile(filepath.Join(dir, file.Path))
+		assert.NoError(t, err)
+		checksum := sha256.Sum256(data)
+		assert.Equal(t, hex.EncodeToString(checksum[:]), file.SHA256)
+
+		records, err := parquet.Read[lake.MetricRow](bytes.NewReader(data), int64(len(data)))
+		assert.NoError(t, err)
+		assert.Len(t, records, file.Rows)
+		for _, record := range records {
+			assert.Equal(t, result.RunID, record.RunID)
+		}
+		rows += len(records)
+	}
+	assert.Equal(t, result.Count, rows)
+
+	// Reexportar la misma ejecución sobrescribe sus ficheros en lugar de duplicarlos
+	_, err = sink.Export(context.Background(), result)
+	assert.NoError(t, err)
+	var parts []string
+	filepath.WalkDir(filepath.Join(dir, lake.Table), func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			parts = append(parts, path)
+		}
+		return nil
+	})
+	assert.Len(t, parts, len(manifest.Files))
+}
+
+func TestLakeExportS3CommitAndRollback(t *testing.T) {
+	fake := newFakeS3()
+	defer fake.Close()
+
+	store, err := lake.NewS3Store(lake.S3Config{
+		Endpoint:  strings.TrimPrefix(fake.URL, "http://"),
+		Bucket:    "lake",
+		Prefix:    "admira",
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "s3://lake/admira", store.Location())
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	sink := lake.NewSink(store, logger)
+
+	result := &etl.RunResult{RunID: "run-1", Tenant: "acme", Metrics: []models.Metric{
+		{Date: "2024-01-01", Channel: "google", CampaignID: "c1", Clicks: 10, Tags: []string{"brand"}},
+		{Date: "2024-01-01", Channel: "google", CampaignID: "c2", Clicks: 5},
+		{Date: "2024-01-02", Channel: "paid/social", CampaignID: "c3", Clicks: 1},
+		{Date: "2024-01-02", CampaignID: "c4"},
+	}}
+
+	manifest, err := sink.Export(context.Background(), result)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, 4, manifest.Rows)
+	assert.ElementsMatch(t, []string{
+		"lake/admira/metrics/tenant=acme/date=2024-01-01/channel=google/part-run-1.parquet",
+		"lake/admira/metrics/tenant=acme/date=2024-01-02/channel=paid%2Fsocial/part-run-1.parquet",
+		"lake/admira/metrics/tenant=acme/date=2024-01-02/channel=__HIVE_DEFAULT_PARTITION__/part-run-1.parquet",
+		"lake/admira/_manifests/metrics/tenant=acme/run-1.json",
+	}, fake.keys("lake/"))
+
+	data := fake.objects["lake/admira/metrics/tenant=acme/date=2024-01-01/channel=google/part-run-1.parquet"]
+	records, err := parquet.Read[lake.MetricRow](bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, []string{"brand"}, records[0].Tags)
+
+	// Si falla el staging no queda nada de la ejecución
+	fake.mu.Lock()
+	fake.deny = func(key string) bool { return strings.HasSuffix(key, "_staging/run-2/manifest.json") }
+	fake.mu.Unlock()
+
+	result.RunID = "run-2"
+	_, err = sink.Export(context.Background(), result)
+	assert.Error(t, err)
+	for _, key := range fake.keys("lake/") {
+		assert.NotContains(t, key, "run-2")
+	}
+
+	_, err = sink.Manifest(context.Background(), "acme", "run-2")
+	assert.ErrorIs(t, err, lake.ErrObjectNotFound)
+
+	// Si falla la promoción, el staging queda y Recover la termina
+	fake.mu.Lock()
+	fake.deny = func(key string) bool { return strings.HasSuffix(key, "_manifests/metrics/tenant=acme/run-3.json") }
+	fake.mu.Unlock()
+
+	result.RunID = "run-3"
+	_, err = sink.Export(context.Background(), result)
+	assert.Error(t, err)
+	assert.Contains(t, fake.keys("lake/admira/_staging/"), "lake/admira/_staging/run-3/manifest.json")
+
+	fake.mu.Lock()
+	fake.deny = nil
+	fake.mu.Unlock()
+
+	assert.NoError(t, sink.Recover(context.Background()))
+	manifest, err = sink.Manifest(context.Background(), "acme", "run-3")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, manifest.Rows)
+	}
+	assert.Empty(t, fake.keys("lake/admira/_staging/"))
+}
+
+func TestLakeExportReplaceAndRecover(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	dir := t.TempDir()
+	store, err := lake.NewLocalStore(dir)
+	assert.NoError(t, err)
+	sink := lake.NewSink(store, logger)
+
+	result := &etl.RunResult{RunID: "run-1", Metrics: []models.Metric{
+		{Date: "2024-01-01", Channel: "google", CampaignID: "c1", Clicks: 10},
+		{Date: "2024-01-02", Channel: "facebook", CampaignID: "c2", Clicks: 5},
+	}}
+	_, err = sink.Export(context.Background(), result)
+	assert.NoError(t, err)
+
+	// Un replay que ya no produce una partición borra su fichero
+	result.Metrics = result.Metrics[:1]
+	manifest, err := sink.Export(context.Background(), result)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Len(t, manifest.Files, 1)
+
+	keys, err := store.List(context.Background(), "")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"metrics/tenant=default/date=2024-01-01/channel=google/part-run-1.parquet",
+		"_manifests/metrics/tenant=default/run-1.json",
+	}, keys)
+
+	// Los restos de un export que se cortó antes de completar el staging se descartan
+	assert.NoError(t, store.Put(context.Background(), "_staging/run-2/metrics/tenant=default/date=2024-01-01/channel=google/part-run-2.parquet", []byte("partial"), "application/vnd.apache.parquet"))
+	assert.NoError(t, sink.Recover(context.Background()))
+
+	keys, err = store.List(context.Background(), "_staging/")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	_, err = os.Stat(filepath.Join(dir, "metrics/tenant=default/date=2024-01-01/channel=google/part-run-2.parquet"))
+	assert.True(t, os.IsNotExist(err))
+}
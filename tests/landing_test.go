@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/landing"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const adsPayload = `{"external":{"ads":{"performance":[
+	{"date":"2023-01-01","campaign_id":"C-1","channel":"google_ads","clicks":100,"impressions":1000,"cost":50,
+	 "utm_campaign":"spring","utm_source":"google","utm_medium":"cpc"}]}}}`
+
+const crmPayload = `{"external":{"crm":{"opportunities":[
+	{"opportunity_id":"O-1","stage":"closed_won","amount":200,"created_at":"2023-01-02T10:00:00Z",
+	 "utm_campaign":"spring","utm_source":"google","utm_medium":"cpc"}]}}}`
+
+func newSourceStub(payload string, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(payload))
+	}))
+}
+
+func TestPipelineLandsRawPayloadsAndReplays(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	store, err := landing.NewStore(t.TempDir())
+	assert.NoError(t, err)
+
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), storage.NewMemoryStorage(), logger)
+	pipeline.SetLanding(store)
+
+	first, err := pipeline.Run(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, first.Count)
+
+	second, err := pipeline.Run(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), adsCalls)
+
+	manifests, err := store.ListManifests()
+	assert.NoError(t, err)
+	assert.Len(t, manifests, 2)
+
+	// Mismo contenido, mismo blob
+	firstManifest, err := store.LoadManifest(first.RunID)
+	assert.NoError(t, err)
+	secondManifest, err := store.LoadManifest(second.RunID)
+	assert.NoError(t, err)
+	assert.Equal(t, firstManifest.Sources[etl.SourceAds].SHA256, secondManifest.Sources[etl.SourceAds].SHA256)
+
+	replayed, err := pipeline.Replay(context.Background(), first.RunID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), adsCalls)
+	assert.Equal(t, int32(2), crmCalls)
+	assert.Equal(t, first.Metrics, replayed.Metrics)
+	assert.Equal(t, 4.0, replayed.Metrics[0].Roas)
+
+	_, err = pipeline.Replay(context.Background(), "missing-run", false)
+	assert.ErrorIs(t, err, landing.ErrRunNotFound)
+}
+
+// countingHook cuenta las veces que se anuncian métricas nuevas tras guardar.
+type countingHook struct{ calls int32 }
+
+func (h *countingHook) AfterIngest(ctx context.Context, metrics []models.Metric) {
+	atomic.AddInt32(&h.calls, 1)
+}
+
+func TestReplaySavingReplacesOriginalRun(t *testing.T) {
+	logger := quietLogger()
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	landingStore, err := landing.NewStore(t.TempDir())
+	assert.NoError(t, err)
+	store := storage.NewMemoryStorage()
+	pipeline := etl.NewPipeline(etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger), etl.NewTransformer(logger), store, logger)
+	pipeline.SetLanding(landingStore)
+	hook := &countingHook{}
+	pipeline.AddHook(hook)
+
+	run, err := pipeline.Run(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, run.RunID, run.Metrics[0].RunID)
+	assert.Equal(t, int32(1), hook.calls)
+
+	// Las mismas métricas sustituyen a las originales y no se vuelven a anunciar
+	replayed, err := pipeline.Replay(context.Background(), run.RunID, true)
+	assert.NoError(t, err)
+	assert.True(t, replayed.Unchanged)
+	assert.Equal(t, int32(1), hook.calls)
+
+	all, err := store.GetMetricsInRange("", "")
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+
+	// Los días ya compactados no vuelven al detalle diario: sus agregados ya los incluyen
+	_, err = store.Compact("2023-02-01", []string{storage.GranularityMonth})
+	assert.NoError(t, err)
+	_, err = pipeline.Replay(context.Background(), run.RunID, true)
+	assert.NoError(t, err)
+
+	all, err = store.GetMetricsInRange("", "")
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+	monthly, err := store.GetMetricsByChannel(models.MetricsRequest{Granularity: storage.GranularityMonth})
+	assert.NoError(t, err)
+	if assert.Len(t, monthly, 1) {
+		assert.Equal(t, 100, monthly[0].Clicks)
+	}
+}
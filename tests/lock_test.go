@@ -0,0 +1,271 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/backfill"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/landing"
+	"github.com/admira-project/backend/internal/lock"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockersRejectSecondHolder(t *testing.T) {
+	fileLocker, err := lock.NewFileLocker(t.TempDir())
+	assert.NoError(t, err)
+
+	fileStorage, err := storage.NewFileStorage(t.TempDir())
+	assert.NoError(t, err)
+
+	lockers := map[string]lock.Locker{
+		"memory":       lock.NewMemoryLocker(),
+		"file":         fileLocker,
+		"storage":      lock.NewStoreLocker(storage.NewMemoryStorage()),
+		"file-storage": lock.NewStoreLocker(fileStorage),
+	}
+	for name, locker := range lockers {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, locker.Acquire("ingest|acme", "run-1", time.Minute))
+
+			err := locker.Acquire("ingest|acme", "run-2", time.Minute)
+			var held *lock.HeldError
+			if assert.True(t, errors.As(err, &held)) {
+				assert.Equal(t, "run-1", held.Holder)
+			}
+			assert.ErrorIs(t, err, lock.ErrHeld)
+
+			// Otra clave no se ve afectada
+			assert.NoError(t, locker.Acquire("ingest|globex", "run-3", time.Minute))
+
+			assert.NoError(t, locker.Renew("ingest|acme", "run-1", time.Minute))
+			assert.Error(t, locker.Renew("ingest|acme", "run-2", time.Minute))
+
+			// Soltar un lock ajeno no hace nada
+			assert.NoError(t, locker.Release("ingest|acme", "run-2"))
+			assert.Error(t, locker.Acquire("ingest|acme", "run-2", time.Minute))
+
+			assert.NoError(t, locker.Release("ingest|acme", "run-1"))
+			assert.NoError(t, locker.Acquire("ingest|acme", "run-2", time.Minute))
+		})
+	}
+}
+
+func TestLeaseExpiresWithoutRenewal(t *testing.T) {
+	fileStorage, err := storage.NewFileStorage(t.TempDir())
+	assert.NoError(t, err)
+
+	for name, locker := range map[string]lock.Locker{
+		"memory":       lock.NewMemoryLocker(),
+		"storage":      lock.NewStoreLocker(storage.NewMemoryStorage()),
+		"file-storage": lock.NewStoreLocker(fileStorage),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, locker.Acquire("ingest|acme", "run-1", 20*time.Millisecond))
+			assert.Error(t, locker.Acquire("ingest|acme", "run-2", time.Minute))
+
+			time.Sleep(40 * time.Millisecond)
+			assert.NoError(t, locker.Acquire("ingest|acme", "run-2", time.Minute))
+			assert.Error(t, locker.Renew("ingest|acme", "run-1", time.Minute))
+		})
+	}
+}
+
+func TestStorageLeaseSharedBetweenReplicas(t *testing.T) {
+	dir := t.TempDir()
+	first, err := storage.NewFileStorage(dir)
+	assert.NoError(t, err)
+	second, err := storage.NewFileStorage(dir)
+	assert.NoError(t, err)
+
+	// Cada réplica abre su propio almacenamiento sobre el mismo directorio
+	api, worker := lock.NewStoreLocker(first), lock.NewStoreLocker(second)
+	assert.NoError(t, api.Acquire("ingest|acme", "run-1", time.Minute))
+
+	var held *lock.HeldError
+	if assert.True(t, errors.As(worker.Acquire("ingest|acme", "run-2", time.Minute), &held)) {
+		assert.Equal(t, "run-1", held.Holder)
+	}
+
+	assert.NoError(t, api.Release("ingest|acme", "run-1"))
+	assert.NoError(t, worker.Acquire("ingest|acme", "run-2", time.Minute))
+}
+
+func TestFileLockSharedBetweenProcesses(t *testing.T) {
+	dir := t.TempDir()
+	first, err := lock.NewFileLocker(dir)
+	assert.NoError(t, err)
+	second, err := lock.NewFileLocker(dir)
+	assert.NoError(t, err)
+
+	// Cada FileLocker abre su propio fichero, como haría otro proceso
+	assert.NoError(t, first.Acquire("ingest|acme", "run-1", time.Minute))
+	err = second.Acquire("ingest|acme", "run-2", time.Minute)
+	var held *lock.HeldError
+	if assert.True(t, errors.As(err, &held)) {
+		assert.Equal(t, "run-1", held.Holder)
+	}
+
+	assert.NoError(t, first.Release("ingest|acme", "run-1"))
+	assert.NoError(t, second.Acquire("ingest|acme", "run-2", time.Minute))
+}
+
+func TestOverlappingIngestionReturnsConflict(t *testing.T) {
+	logger := quietLogger()
+
+	// La fuente de ads no responde hasta que se libera, para que la primera ingesta siga en curso
+	release := make(chan struct{})
+	var once sync.Once
+	requested := make(chan struct{})
+	adsStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(requested) })
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(adsPayload))
+	}))
+	defer adsStub.Close()
+	var crmCalls int32
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	store := storage.NewMemoryStorage()
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), store, logger)
+	pipeline.SetLocker(lock.NewMemoryLocker(), time.Minute)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/ingest/run", api.NewHandler(pipeline, store, logger).IngestHandler).Methods("POST")
+
+	first := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		first <- serve(router, "POST", "/v1/ingest/run?since=2023-01-01", "")
+	}()
+	<-requested
+
+	// El lock es del tenant: otra ventana también choca con la ingesta en curso
+	recorder := serve(router, "POST", "/v1/ingest/run?since=2022-06-01", "")
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	var conflict map[string]string
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&conflict))
+	assert.NotEmpty(t, conflict["run_id"])
+
+	close(release)
+	recorder = <-first
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var result map[string]interface{}
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&result))
+	assert.Equal(t, conflict["run_id"], result["run_id"])
+
+	// Con el lock liberado se puede volver a ingerir
+	assert.Equal(t, http.StatusOK, serve(router, "POST", "/v1/ingest/run?since=2023-01-01&force=true", "").Code)
+}
+
+func TestBackfillHoldsIngestionLockForWholeJob(t *testing.T) {
+	logger := quietLogger()
+	locker := lock.NewMemoryLocker()
+
+	// La fuente de ads tarda un poco para que los tramos coincidan en el tiempo
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	var outsideErrs []error
+	adsStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		// Mientras corre el job, otra ingesta del tenant encuentra el lock ocupado
+		outsideErrs = append(outsideErrs, locker.Acquire("ingest|default", "manual-run-2", time.Minute))
+		mu.Unlock()
+
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(adsPayload))
+	}))
+	defer adsStub.Close()
+	var crmCalls int32
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), storage.NewMemoryStorage(), logger)
+	pipeline.SetLocker(locker, time.Minute)
+
+	// Una ingesta externa tiene el lock del tenant mientras arranca el backfill
+	assert.NoError(t, locker.Acquire("ingest|default", "manual-run", time.Minute))
+	time.AfterFunc(300*time.Millisecond, func() { locker.Release("ingest|default", "manual-run") })
+
+	manager, err := backfill.NewManager(pipeline, "", 2, logger)
+	assert.NoError(t, err)
+	from, _ := time.Parse("2006-01-02", "2023-01-01")
+	to, _ := time.Parse("2006-01-02", "2023-01-20")
+	job, err := manager.Create(from, to, 5)
+	assert.NoError(t, err)
+
+	// El job espera al lock en lugar de fallar y después sus tramos corren en paralelo
+	job, err = manager.Run(context.Background(), job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, backfill.StatusCompleted, job.Status)
+	for _, chunk := range job.Chunks {
+		assert.Equal(t, backfill.StatusCompleted, chunk.Status)
+		assert.Empty(t, chunk.Error)
+	}
+
+	mu.Lock()
+	assert.Equal(t, 2, peak)
+	for _, err := range outsideErrs {
+		assert.ErrorIs(t, err, lock.ErrHeld)
+	}
+	mu.Unlock()
+
+	// Al terminar el job el lock queda libre
+	assert.NoError(t, locker.Acquire("ingest|default", "manual-run-3", time.Minute))
+}
+
+func TestReplayTakesLockWithItsOwnHolder(t *testing.T) {
+	logger := quietLogger()
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	landingStore, err := landing.NewStore(t.TempDir())
+	assert.NoError(t, err)
+	locker := lock.NewMemoryLocker()
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), storage.NewMemoryStorage(), logger)
+	pipeline.SetLanding(landingStore)
+	pipeline.SetLocker(locker, time.Minute)
+
+	run, err := pipeline.Run(context.Background(), time.Time{})
+	assert.NoError(t, err)
+
+	// Si el lock sigue a nombre de la ejecución original, el replay no puede heredarlo
+	assert.NoError(t, locker.Acquire("ingest|default", run.RunID, time.Minute))
+	_, err = pipeline.Replay(context.Background(), run.RunID, true)
+	var held *lock.HeldError
+	if assert.True(t, errors.As(err, &held)) {
+		assert.Equal(t, run.RunID, held.Holder)
+	}
+
+	// Sin guardar no hace falta el lock
+	_, err = pipeline.Replay(context.Background(), run.RunID, false)
+	assert.NoError(t, err)
+
+	assert.NoError(t, locker.Release("ingest|default", run.RunID))
+	_, err = pipeline.Replay(context.Background(), run.RunID, true)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,255 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/messaging"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/linkedin/goavro/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// flakyPublisher falla las primeras failures llamadas y guarda lo publicado después.
+type flakyPublisher struct {
+	mu        sync.Mutex
+	failures  int
+	calls     int
+	published []messaging.Message
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, messages []messaging.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls++
+	if p.calls <= p.failures {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, messages...)
+	return nil
+}
+
+func (p *flakyPublisher) Close() {}
+
+func (p *flakyPublisher) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.published)
+}
+
+func testRetryPolicy() utils.RetryPolicy {
+	policy := utils.DefaultRetryPolicy(3, 1)
+	policy.FullJitter = false
+	return policy
+}
+
+func TestMessagingKafkaPublishing(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "admira.metrics", "admira.ingestion-runs"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer cluster.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	publisher, err := messaging.NewKafkaPublisher(messaging.KafkaConfig{Brokers: cluster.ListenAddrs(), ClientID: "admira-test", Timeout: 2 * time.Second})
+	assert.NoError(t, err)
+	outbox, _ := messaging.NewOutbox("")
+	relay := messaging.NewRelay(outbox, publisher, testRetryPolicy(), 2, time.Hour, logger)
+	defer relay.Close()
+	emitter := messaging.NewEmitter(outbox, relay, messaging.JSONCodec{}, messaging.Topics{Metrics: "admira.metrics", Runs: "admira.ingestion-runs"}, logger)
+
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), storage.NewMemoryStorage(), logger)
+	pipeline.AddObserver(emitter)
+
+	result, err := pipeline.Run(context.Background(), time.Time{})
+	if !assert.NoError(t, err) || !assert.NotZero(t, result.Count) {
+		t.FailNow()
+	}
+	assert.Equal(t, result.Count+1, outbox.Len())
+
+	// Lotes de 2 para cubrir varias vueltas del relay
+	assert.NoError(t, relay.Flush(context.Background()))
+	assert.Zero(t, outbox.Len())
+
+	consumer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.ConsumeTopics("admira.metrics", "admira.ingestion-runs"))
+	assert.NoError(t, err)
+	defer consumer.Close()
+
+	var records []*kgo.Record
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for len(records) < result.Count+1 && ctx.Err() == nil {
+		consumer.PollFetches(ctx).EachRecord(func(record *kgo.Record) {
+			records = append(records, record)
+		})
+	}
+	if !assert.Len(t, records, result.Count+1) {
+		t.FailNow()
+	}
+
+	seen := make(map[string]bool)
+	metrics := 0
+	for _, record := range records {
+		headers := make(map[string]string)
+		for _, header := range record.Headers {
+			headers[header.Key] = string(header.Value)
+		}
+		assert.Equal(t, "application/json", headers[messaging.HeaderContentType])
+		assert.False(t, seen[headers[messaging.HeaderEventID]])
+		seen[headers[messaging.HeaderEventID]] = true
+
+		switch record.Topic {
+		case "admira.metrics":
+			var event messaging.MetricEvent
+			assert.NoError(t, json.Unmarshal(record.Value, &event))
+			assert.Equal(t, messaging.EventMetricComputed, headers[messaging.HeaderEventType])
+			assert.Equal(t, result.RunID, event.RunID)
+			assert.Equal(t, "default|"+event.Metric.Date+"|"+event.Metric.Channel+"|"+event.Metric.CampaignID, string(record.Key))
+			metrics++
+		case "admira.ingestion-runs":
+			var event messaging.RunEvent
+			assert.NoError(t, json.Unmarshal(record.Value, &event))
+			assert.Equal(t, result.RunID, event.RunID)
+			assert.Equal(t, result.Count, event.Count)
+			assert.Equal(t, "default", string(record.Key))
+		}
+	}
+	assert.Equal(t, result.Count, metrics)
+
+	// Con el broker caído los mensajes se quedan en el outbox
+	cluster.Close()
+	_, err = emitter.Enqueue(&etl.RunResult{RunID: "r2", Metrics: []models.Metric{{Date: "2024-01-01", CampaignID: "c1"}}, Count: 1})
+	assert.NoError(t, err)
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer flushCancel()
+	assert.Error(t, relay.Flush(flushCtx))
+	assert.Equal(t, 2, outbox.Len())
+	assert.Equal(t, 1, outbox.Pending(1)[0].Attempts)
+}
+
+func TestMessagingOutboxAtLeastOnce(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	dir := t.TempDir()
+
+	outbox, err := messaging.NewOutbox(dir)
+	assert.NoError(t, err)
+	publisher := &flakyPublisher{failures: 1}
+	relay := messaging.NewRelay(outbox, publisher, testRetryPolicy(), 100, time.Hour, logger)
+	emitter := messaging.NewEmitter(outbox, nil, messaging.JSONCodec{}, messaging.Topics{Metrics: "metrics", Runs: "runs"}, logger)
+
+	// Una ejecución sin cambios no genera mensajes
+	emitter.RunFinished(context.Background(), &etl.RunResult{RunID: "r0", Unchanged: true})
+	assert.Zero(t, outbox.Len())
+
+	emitter.RunFinished(context.Background(), &etl.RunResult{RunID: "r1", Tenant: "acme", Count: 2, Metrics: []models.Metric{
+		{Date: "2024-01-01", Channel: "google", CampaignID: "c1"},
+		{Date: "2024-01-02", Channel: "google", CampaignID: "c1"},
+	}})
+	assert.Equal(t, 3, outbox.Len())
+
+	assert.Error(t, relay.Flush(context.Background()))
+	assert.Zero(t, publisher.count())
+
+	// Lo no confirmado sobrevive a un reinicio, con el intento anotado
+	reloaded, err := messaging.NewOutbox(dir)
+	assert.NoError(t, err)
+	pending := reloaded.Pending(0)
+	if !assert.Len(t, pending, 3) {
+		t.FailNow()
+	}
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, "broker unavailable", pending[0].LastError)
+	assert.Equal(t, "acme|2024-01-01|google|c1", pending[0].Message.Key)
+	assert.Equal(t, "runs", pending[2].Message.Topic)
+
+	relay = messaging.NewRelay(reloaded, publisher, testRetryPolicy(), 100, time.Hour, logger)
+	relay.Start()
+	defer relay.Close()
+	assert.Eventually(t, func() bool { return publisher.count() == 3 }, 2*time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return reloaded.Len() == 0 }, 2*time.Second, 5*time.Millisecond)
+
+	again, err := messaging.NewOutbox(dir)
+	assert.NoError(t, err)
+	assert.Zero(t, again.Len())
+}
+
+func TestMessagingRelayRetriesInBackground(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	outbox, _ := messaging.NewOutbox("")
+	publisher := &flakyPublisher{failures: 2}
+	relay := messaging.NewRelay(outbox, publisher, testRetryPolicy(), 100, time.Hour, logger)
+	relay.Start()
+	defer relay.Close()
+
+	emitter := messaging.NewEmitter(outbox, relay, messaging.JSONCodec{}, messaging.Topics{Runs: "runs"}, logger)
+	count, err := emitter.Enqueue(&etl.RunResult{RunID: "r1", Count: 1, Metrics: []models.Metric{{CampaignID: "c1"}}})
+	assert.NoError(t, err)
+	// Sin topic de métricas sólo se publica el evento de la ejecución
+	assert.Equal(t, 1, count)
+
+	assert.Eventually(t, func() bool { return publisher.count() == 1 }, 2*time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return outbox.Len() == 0 }, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestMessagingAvroCodec(t *testing.T) {
+	codec, err := messaging.NewAvroCodec()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "application/avro", codec.ContentType())
+
+	occurredAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	data, err := codec.EncodeMetric(messaging.MetricEvent{
+		Event:      messaging.EventMetricComputed,
+		RunID:      "r1",
+		Tenant:     "acme",
+		OccurredAt: occurredAt,
+		Metric:     models.Metric{Date: "2024-01-01", Channel: "google", CampaignID: "c1", Tags: []string{"brand"}, Clicks: 10, Cost: 2.5},
+	})
+	assert.NoError(t, err)
+
+	// El consumidor puede leerlo sólo con el esquema publicado
+	reader, err := goavro.NewCodec(messaging.MetricSchema)
+	assert.NoError(t, err)
+	native, _, err := reader.NativeFromSingle(data)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	record := native.(map[string]interface{})
+	assert.Equal(t, "acme", record["tenant_id"])
+	assert.Equal(t, int64(10), record["clicks"])
+	assert.Equal(t, 2.5, record["cost"])
+	assert.Equal(t, []interface{}{"brand"}, record["tags"])
+	assert.True(t, occurredAt.Equal(record["occurred_at"].(time.Time)))
+
+	data, err = codec.EncodeRun(messaging.RunEvent{Event: messaging.EventIngestionCompleted, RunID: "r1", Tenant: "acme", Count: 3, OccurredAt: occurredAt})
+	assert.NoError(t, err)
+	reader, _ = goavro.NewCodec(messaging.RunSchema)
+	native, _, err = reader.NativeFromSingle(data)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), native.(map[string]interface{})["count"])
+}
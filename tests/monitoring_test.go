@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/monitoring"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelineExportsMetrics(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), storage.NewMemoryStorage(), logger)
+
+	runs := testutil.ToFloat64(monitoring.ETLRuns.WithLabelValues("success"))
+	saved := testutil.ToFloat64(monitoring.ETLRecords.WithLabelValues("metrics", "saved"))
+	extracted := testutil.ToFloat64(monitoring.ETLRecords.WithLabelValues(etl.SourceAds, "extracted"))
+
+	_, err := pipeline.Run(context.Background(), time.Time{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, runs+1, testutil.ToFloat64(monitoring.ETLRuns.WithLabelValues("success")))
+	assert.Equal(t, saved+1, testutil.ToFloat64(monitoring.ETLRecords.WithLabelValues("metrics", "saved")))
+	assert.Equal(t, extracted+1, testutil.ToFloat64(monitoring.ETLRecords.WithLabelValues(etl.SourceAds, "extracted")))
+	assert.Greater(t, testutil.ToFloat64(monitoring.LastSuccessfulIngestion), 0.0)
+
+	recorder := httptest.NewRecorder()
+	monitoring.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/internal/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, string(body), `admira_etl_stage_duration_seconds_count{stage="transform"}`)
+	assert.Contains(t, string(body), `admira_etl_stage_duration_seconds_count{stage="save"}`)
+}
+
+func TestRetryClientCountsRetries(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	retries := monitoring.HTTPClientRetries.WithLabelValues(req.URL.Host, "503")
+
+	client := utils.NewRetryableHTTPClientWithPolicy(logger, http.DefaultClient, utils.DefaultRetryPolicy(3, 1))
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(retries))
+}
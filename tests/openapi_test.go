@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/openapi"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func validationRouter(t *testing.T) *mux.Router {
+	doc, err := openapi.Load()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	validator := api.NewRequestValidator(doc)
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	router := mux.NewRouter()
+	v1 := router.PathPrefix(api.VersionPrefix).Subrouter()
+	legacy := router.NewRoute().Subrouter()
+	legacy.Use(api.DeprecatedMiddleware)
+	for _, routes := range []*mux.Router{v1, legacy} {
+		routes.Handle("/metrics/channel", validator.Validate(ok)).Methods("GET")
+		routes.Handle("/metrics/campaigns", validator.Validate(ok)).Methods("GET")
+		routes.Handle("/backfill", validator.Validate(ok)).Methods("POST")
+		routes.Handle("/campaigns/{id}", validator.Validate(ok)).Methods("PUT")
+	}
+	return router
+}
+
+func serve(router http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func problemOf(t *testing.T, recorder *httptest.ResponseRecorder) api.Problem {
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+
+	var problem api.Problem
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem))
+	return problem
+}
+
+func TestOpenAPIDescribesMetricModel(t *testing.T) {
+	doc, err := openapi.Load()
+	assert.NoError(t, err)
+
+	schema := doc.Components.Schemas["Metric"]
+	if !assert.NotNil(t, schema) {
+		return
+	}
+
+	metricType := reflect.TypeOf(models.Metric{})
+	for i := 0; i < metricType.NumField(); i++ {
+		name := strings.Split(metricType.Field(i).Tag.Get("json"), ",")[0]
+		assert.Contains(t, schema.Properties, name)
+	}
+
+	for _, path := range []string{"/ingest/run", "/metrics/channel", "/metrics/funnel", "/metrics/campaigns", "/backfill/{id}/resume", "/campaigns/{id}"} {
+		assert.NotEmpty(t, doc.Paths[path], path)
+	}
+}
+
+func TestRequestValidationProblem(t *testing.T) {
+	router := validationRouter(t)
+
+	recorder := serve(router, "GET", "/v1/metrics/channel?from=2024-13-01&limit=abc&offset=-1&tenant=ACME", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	problem := problemOf(t, recorder)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "/v1/metrics/channel", problem.Instance)
+
+	reasons := make(map[string]string)
+	for _, fieldErr := range problem.Errors {
+		assert.Equal(t, "query", fieldErr.In)
+		reasons[fieldErr.Name] = fieldErr.Reason
+	}
+	assert.Equal(t, "must be a date in YYYY-MM-DD format", reasons["from"])
+	assert.Equal(t, "must be an integer", reasons["limit"])
+	assert.Equal(t, "must be >= 0", reasons["offset"])
+	assert.Contains(t, reasons, "tenant")
+
+	assert.Equal(t, http.StatusOK, serve(router, "GET", "/v1/metrics/channel?from=2024-01-01&limit=10&channel=google", "").Code)
+
+	recorder = serve(router, "GET", "/v1/metrics/campaigns?group_by=color", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, problemOf(t, recorder).Errors[0].Reason, "must be one of")
+}
+
+func TestRequestValidationRequiredAndBody(t *testing.T) {
+	router := validationRouter(t)
+
+	recorder := serve(router, "POST", "/v1/backfill?to=2024-01-31", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, []openapi.FieldError{{In: "query", Name: "from", Reason: "is required"}}, problemOf(t, recorder).Errors)
+
+	recorder = serve(router, "PUT", "/v1/campaigns/c1", `{"name": "Spring", "tags": ["a", 3]}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, []openapi.FieldError{{In: "body", Name: "tags[1]", Reason: "must be a string"}}, problemOf(t, recorder).Errors)
+
+	recorder = serve(router, "PUT", "/v1/campaigns/c1", `{"name": `)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "is not valid JSON", problemOf(t, recorder).Errors[0].Reason)
+
+	assert.Equal(t, http.StatusOK, serve(router, "PUT", "/v1/campaigns/c1", `{"name": "Spring", "tags": ["a"]}`).Code)
+}
+
+func TestRequestValidationRejectsUnknownParamsAndLargeBodies(t *testing.T) {
+	router := validationRouter(t)
+
+	recorder := serve(router, "GET", "/v1/metrics/channel?chanel=google&limit=10&tenant=acme", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, []openapi.FieldError{{In: "query", Name: "chanel", Reason: "is not a known parameter"}}, problemOf(t, recorder).Errors)
+
+	// group_by sólo existe en /metrics/campaigns
+	assert.Equal(t, http.StatusBadRequest, serve(router, "GET", "/v1/metrics/channel?group_by=owner", "").Code)
+	assert.Equal(t, http.StatusOK, serve(router, "GET", "/v1/metrics/campaigns?group_by=owner&granularity=week", "").Code)
+
+	doc, err := openapi.Load()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	doc.SetMaxBodyBytes(64)
+	put := api.NewRequestValidator(doc).Validate(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	router = mux.NewRouter()
+	router.Handle("/v1/campaigns/{id}", put).Methods("PUT")
+
+	recorder = serve(router, "PUT", "/v1/campaigns/c1", `{"name": "`+strings.Repeat("x", 100)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	problem := problemOf(t, recorder)
+	if assert.Len(t, problem.Errors, 1) {
+		assert.Equal(t, "body", problem.Errors[0].In)
+		assert.Contains(t, problem.Errors[0].Reason, openapi.ReasonBodyTooLarge)
+	}
+	assert.Equal(t, http.StatusOK, serve(router, "PUT", "/v1/campaigns/c1", `{"name": "Spring"}`).Code)
+}
+
+func TestOpenAPIDeclaresTenantParameter(t *testing.T) {
+	doc, err := openapi.Load()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Todas las rutas con tenant lo aceptan por query; sin declararlo, la validación lo rechazaría
+	unscoped := map[string]bool{"/healthz": true, "/readyz": true, "/internal/metrics": true, "/openapi.json": true}
+	for path, item := range doc.Paths {
+		if unscoped[path] {
+			continue
+		}
+		for method, operation := range item {
+			declared := false
+			for _, param := range operation.Parameters {
+				declared = declared || (param.In == "query" && param.Name == "tenant")
+			}
+			assert.True(t, declared, "%s %s does not declare tenant", strings.ToUpper(method), path)
+		}
+	}
+}
+
+func TestLegacyRoutesAreDeprecated(t *testing.T) {
+	router := validationRouter(t)
+
+	recorder := serve(router, "GET", "/metrics/channel?limit=5", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "true", recorder.Header().Get("Deprecation"))
+	assert.Equal(t, `</v1/metrics/channel>; rel="successor-version"`, recorder.Header().Get("Link"))
+
+	// Las rutas sin prefijo se validan igual
+	assert.Equal(t, http.StatusBadRequest, serve(router, "GET", "/metrics/channel?limit=0", "").Code)
+
+	recorder = serve(router, "GET", "/v1/metrics/channel", "")
+	assert.Empty(t, recorder.Header().Get("Deprecation"))
+}
@@ -0,0 +1,401 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/queue"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// runnerStub sustituye al pipeline: devuelve err o, si block, espera a que se cancele el contexto.
+type runnerStub struct {
+	mu      sync.Mutex
+	err     error
+	block   bool
+	started chan struct{}
+	tenants []string
+	forced  []bool
+}
+
+func (r *runnerStub) RunRange(ctx context.Context, window etl.DateRange) (*etl.RunResult, error) {
+	return r.run(ctx, false)
+}
+
+func (r *runnerStub) ForceRunRange(ctx context.Context, window etl.DateRange) (*etl.RunResult, error) {
+	return r.run(ctx, true)
+}
+
+func (r *runnerStub) run(ctx context.Context, force bool) (*etl.RunResult, error) {
+	r.mu.Lock()
+	r.tenants = append(r.tenants, tenant.FromContext(ctx))
+	r.forced = append(r.forced, force)
+	r.mu.Unlock()
+
+	if r.started != nil {
+		r.started <- struct{}{}
+	}
+	if r.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &etl.RunResult{RunID: "run-1", Tenant: tenant.FromContext(ctx), Count: 3}, nil
+}
+
+func quietLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return logger
+}
+
+func TestQueuedIngestionThroughAPI(t *testing.T) {
+	logger := quietLogger()
+
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	store := storage.NewMemoryStorage()
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), store, logger)
+
+	ingestQueue := queue.NewMemoryQueue()
+	handler := api.NewHandler(pipeline, store, logger)
+	handler.SetQueue(ingestQueue)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/ingest/run", handler.IngestHandler).Methods("POST")
+	router.HandleFunc("/v1/ingest/jobs/{id}", handler.JobHandler).Methods("GET")
+
+	// La API sólo encola: sin worker no se llama a las fuentes
+	recorder := serve(router, "POST", "/v1/ingest/run?since=2023-01-01", "")
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	var job queue.Job
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&job))
+	assert.Equal(t, queue.StatusPending, job.Status)
+	assert.Equal(t, "2023-01-01", job.Since)
+	assert.Equal(t, "/v1/ingest/jobs/"+job.ID, recorder.Header().Get("Location"))
+	assert.Zero(t, adsCalls)
+
+	assert.Equal(t, http.StatusBadRequest, serve(router, "POST", "/v1/ingest/run?since=yesterday", "").Code)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	worker := queue.NewWorker(ingestQueue, pipeline, "worker-1", logger)
+	go worker.Run(ctx)
+
+	assert.Eventually(t, func() bool {
+		current, _ := ingestQueue.Get(job.ID)
+		return current.Status == queue.StatusCompleted
+	}, 2*time.Second, 5*time.Millisecond)
+
+	recorder = serve(router, "GET", "/v1/ingest/jobs/"+job.ID, "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var done queue.Job
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&done))
+	assert.Equal(t, "worker-1", done.Worker)
+	assert.Equal(t, 1, done.Attempts)
+	assert.NotEmpty(t, done.RunID)
+	assert.NotZero(t, done.Count)
+	assert.NotNil(t, done.FinishedAt)
+
+	metrics, err := store.GetMetricsByChannel(models.MetricsRequest{TenantID: tenant.Default})
+	assert.NoError(t, err)
+	assert.Len(t, metrics, done.Count)
+
+	// Otro tenant no ve el trabajo
+	assert.Equal(t, http.StatusNotFound, serve(router, "GET", "/v1/ingest/jobs/"+job.ID+"?tenant=acme", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(router, "GET", "/v1/ingest/jobs/ing-missing", "").Code)
+}
+
+func TestStandaloneWorkerSharesQueueAndStorage(t *testing.T) {
+	logger := quietLogger()
+	queueDir, storageDir := t.TempDir(), t.TempDir()
+
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	// Proceso de la API: sólo encola y lee del almacenamiento compartido
+	apiStore, err := storage.NewFileStorage(storageDir)
+	assert.NoError(t, err)
+	apiQueue, err := queue.NewFileQueue(queueDir, time.Minute, 3)
+	assert.NoError(t, err)
+	handler := api.NewHandler(nil, apiStore, logger)
+	handler.SetQueue(apiQueue)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/ingest/run", handler.IngestHandler).Methods("POST")
+	router.HandleFunc("/v1/ingest/jobs/{id}", handler.JobHandler).Methods("GET")
+
+	recorder := serve(router, "POST", "/v1/ingest/run?since=2023-01-01", "")
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	var job queue.Job
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&job))
+
+	// Proceso worker: su propia cola y su propio almacenamiento sobre los mismos directorios
+	workerStore, err := storage.NewFileStorage(storageDir)
+	assert.NoError(t, err)
+	workerQueue, err := queue.NewFileQueue(queueDir, time.Minute, 3)
+	assert.NoError(t, err)
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), workerStore, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.NewWorker(workerQueue, pipeline, "worker-1", logger).Run(ctx)
+
+	var done queue.Job
+	assert.Eventually(t, func() bool {
+		recorder := serve(router, "GET", "/v1/ingest/jobs/"+job.ID, "")
+		return json.NewDecoder(recorder.Body).Decode(&done) == nil && done.Status == queue.StatusCompleted
+	}, 2*time.Second, 5*time.Millisecond)
+	assert.NotZero(t, done.Count)
+
+	metrics, err := apiStore.GetMetricsByChannel(models.MetricsRequest{TenantID: tenant.Default})
+	assert.NoError(t, err)
+	assert.Len(t, metrics, done.Count)
+}
+
+func TestQueueWorkerReportsFailuresAndRequeuesOnShutdown(t *testing.T) {
+	logger := quietLogger()
+	ingestQueue := queue.NewMemoryQueue()
+
+	failing := &runnerStub{err: errors.New("ads source unavailable")}
+	job := &queue.Job{Tenant: "acme", Force: true}
+	assert.NoError(t, ingestQueue.Enqueue(job))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		queue.NewWorker(ingestQueue, failing, "worker-1", logger).Run(ctx)
+		close(stopped)
+	}()
+	assert.Eventually(t, func() bool {
+		current, _ := ingestQueue.Get(job.ID)
+		return current.Status == queue.StatusFailed
+	}, 2*time.Second, 5*time.Millisecond)
+	cancel()
+	<-stopped
+
+	failed, _ := ingestQueue.Get(job.ID)
+	assert.Equal(t, "ads source unavailable", failed.Error)
+	failing.mu.Lock()
+	assert.Equal(t, []string{"acme"}, failing.tenants)
+	assert.Equal(t, []bool{true}, failing.forced)
+	failing.mu.Unlock()
+
+	// Un worker que se para a mitad devuelve el trabajo a la cola
+	blocking := &runnerStub{block: true, started: make(chan struct{}, 1)}
+	interrupted := &queue.Job{Tenant: tenant.Default}
+	assert.NoError(t, ingestQueue.Enqueue(interrupted))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	stopped = make(chan struct{})
+	go func() {
+		queue.NewWorker(ingestQueue, blocking, "worker-2", logger).Run(ctx)
+		close(stopped)
+	}()
+	<-blocking.started
+	cancel()
+	<-stopped
+
+	requeued, _ := ingestQueue.Get(interrupted.ID)
+	assert.Equal(t, queue.StatusPending, requeued.Status)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	claimed, err := ingestQueue.Claim(ctx, "worker-3")
+	assert.NoError(t, err)
+	assert.Equal(t, interrupted.ID, claimed.ID)
+	assert.Equal(t, 2, claimed.Attempts)
+}
+
+func TestFileQueueSharedBetweenWorkers(t *testing.T) {
+	dir := t.TempDir()
+	producer, err := queue.NewFileQueue(dir, time.Minute, 3)
+	assert.NoError(t, err)
+
+	var ids []string
+	for i := 0; i < 20; i++ {
+		job := &queue.Job{Tenant: tenant.Default}
+		assert.NoError(t, producer.Enqueue(job))
+		ids = append(ids, job.ID)
+	}
+
+	// Cada "proceso" abre su propia cola sobre el mismo directorio; ningún trabajo se toma dos veces
+	var mu sync.Mutex
+	claimed := make(map[string]int)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		consumer, err := queue.NewFileQueue(dir, time.Minute, 3)
+		assert.NoError(t, err)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+				job, err := consumer.Claim(ctx, "worker")
+				cancel()
+				if err != nil {
+					return
+				}
+
+				mu.Lock()
+				claimed[job.ID]++
+				mu.Unlock()
+
+				job.Status = queue.StatusCompleted
+				consumer.Finish(job)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, claimed, len(ids))
+	for _, id := range ids {
+		assert.Equal(t, 1, claimed[id])
+		job, err := producer.Get(id)
+		assert.NoError(t, err)
+		assert.Equal(t, queue.StatusCompleted, job.Status)
+		assert.Nil(t, job.LeaseUntil)
+	}
+
+	_, err = producer.Get("../escape")
+	assert.ErrorIs(t, err, queue.ErrJobNotFound)
+}
+
+func TestFileQueueLeaseExpiry(t *testing.T) {
+	ingestQueue, err := queue.NewFileQueue(t.TempDir(), 50*time.Millisecond, 2)
+	assert.NoError(t, err)
+
+	job := &queue.Job{Tenant: tenant.Default}
+	assert.NoError(t, ingestQueue.Enqueue(job))
+
+	claim := func(worker string) (*queue.Job, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		return ingestQueue.Claim(ctx, worker)
+	}
+
+	// worker-1 desaparece sin renovar: worker-2 lo retoma
+	first, err := claim("worker-1")
+	assert.NoError(t, err)
+	assert.NotNil(t, first.LeaseUntil)
+
+	second, err := claim("worker-2")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, job.ID, second.ID)
+	assert.Equal(t, 2, second.Attempts)
+	assert.Error(t, ingestQueue.Renew(first))
+	assert.NoError(t, ingestQueue.Renew(second))
+
+	// Agotados los intentos, el trabajo se da por fallido
+	time.Sleep(100 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	_, err = ingestQueue.Claim(ctx, "worker-3")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	failed, err := ingestQueue.Get(job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, queue.StatusFailed, failed.Status)
+	assert.Contains(t, failed.Error, "lease expired")
+}
+
+func TestQueuesExpireFinishedJobs(t *testing.T) {
+	fileQueue, err := queue.NewFileQueue(t.TempDir(), time.Minute, 3)
+	assert.NoError(t, err)
+
+	for name, q := range map[string]interface {
+		queue.Queue
+		SetRetention(time.Duration)
+	}{
+		"memory": queue.NewMemoryQueue(),
+		"file":   fileQueue,
+	} {
+		t.Run(name, func(t *testing.T) {
+			q.SetRetention(100 * time.Millisecond)
+
+			waiting := &queue.Job{Tenant: tenant.Default}
+			assert.NoError(t, q.Enqueue(waiting))
+			finished := &queue.Job{Tenant: tenant.Default}
+			assert.NoError(t, q.Enqueue(finished))
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			claimed, err := q.Claim(ctx, "worker-1")
+			assert.NoError(t, err)
+			claimed, err = q.Claim(ctx, "worker-1")
+			assert.NoError(t, err)
+			assert.Equal(t, finished.ID, claimed.ID)
+			claimed.Status = queue.StatusCompleted
+			assert.NoError(t, q.Finish(claimed))
+
+			// Hasta que vence la retención el resultado se puede consultar
+			done, err := q.Get(finished.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, queue.StatusCompleted, done.Status)
+
+			time.Sleep(150 * time.Millisecond)
+			_, err = q.Get(finished.ID)
+			assert.ErrorIs(t, err, queue.ErrJobNotFound)
+
+			// Los trabajos sin terminar no caducan
+			running, err := q.Get(waiting.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, queue.StatusRunning, running.Status)
+		})
+	}
+}
+
+func TestFileQueuePrunesDoneJobsOnEnqueue(t *testing.T) {
+	dir := t.TempDir()
+	ingestQueue, err := queue.NewFileQueue(dir, time.Minute, 3)
+	assert.NoError(t, err)
+	ingestQueue.SetRetention(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, ingestQueue.Enqueue(&queue.Job{Tenant: tenant.Default}))
+		job, err := ingestQueue.Claim(ctx, "worker-1")
+		assert.NoError(t, err)
+		job.Status = queue.StatusFailed
+		assert.NoError(t, ingestQueue.Finish(job))
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "done"))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	// done/ no crece sin límite: encolar borra lo que ya ha caducado
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, ingestQueue.Enqueue(&queue.Job{Tenant: tenant.Default}))
+	entries, err = os.ReadDir(filepath.Join(dir, "done"))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
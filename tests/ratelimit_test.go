@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientRateLimitPerRoute(t *testing.T) {
+	limiter := api.NewClientLimiter(utils.RateLimit{RequestsPerSecond: 100, Burst: 100}, false)
+	limiter.SetRouteLimit("/metrics/channel", utils.RateLimit{RequestsPerSecond: 0.01, Burst: 2})
+
+	authn := auth.NewAuthenticator()
+	authn.AddAPIKey("dashboard", "dashboard", []string{auth.RoleViewer})
+	authn.AddAPIKey("ops", "ops", []string{auth.RoleViewer})
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router := mux.NewRouter()
+	router.Handle("/metrics/channel", authn.Identify(limiter.Limit(ok)))
+	router.Handle("/ingest/run", authn.Identify(limiter.Limit(ok)))
+
+	call := func(path, key, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":5555"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	first := call("/metrics/channel", "dashboard", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "2", first.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, http.StatusOK, call("/metrics/channel", "dashboard", "10.0.0.1").Code)
+
+	limited := call("/metrics/channel", "dashboard", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "0", limited.Header().Get("X-RateLimit-Remaining"))
+	assert.NotEmpty(t, limited.Header().Get("Retry-After"))
+
+	// Otra clave desde la misma IP y otra ruta de la misma clave no se ven afectadas
+	assert.Equal(t, http.StatusOK, call("/metrics/channel", "ops", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, call("/ingest/run", "dashboard", "10.0.0.1").Code)
+
+	// Sin API key se limita por IP
+	assert.Equal(t, http.StatusOK, call("/metrics/channel", "", "10.0.0.2").Code)
+	assert.Equal(t, http.StatusOK, call("/metrics/channel", "", "10.0.0.2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, call("/metrics/channel", "", "10.0.0.2").Code)
+	assert.Equal(t, http.StatusOK, call("/metrics/channel", "", "10.0.0.3").Code)
+
+	// Una API key que no valida no abre un bucket propio: cuenta para su IP
+	assert.Equal(t, http.StatusTooManyRequests, call("/metrics/channel", "forged-1", "10.0.0.2").Code)
+	assert.Equal(t, http.StatusOK, call("/metrics/channel", "forged-2", "10.0.0.4").Code)
+	assert.Equal(t, http.StatusOK, call("/metrics/channel", "forged-3", "10.0.0.4").Code)
+	assert.Equal(t, http.StatusTooManyRequests, call("/metrics/channel", "forged-4", "10.0.0.4").Code)
+}
+
+func TestDailyIngestionQuota(t *testing.T) {
+	var runs int
+	quota := api.NewDailyQuota(2, false)
+	authn := auth.NewAuthenticator()
+	authn.AddAPIKey("scheduler", "scheduler", []string{auth.RoleOperator})
+	authn.AddAPIKey("other", "other", []string{auth.RoleOperator})
+	handler := authn.Require(auth.RoleOperator, quota.Enforce(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	call := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/ingest/run", nil)
+		req.Header.Set("X-API-Key", key)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, "1", call("scheduler").Header().Get("X-Quota-Remaining"))
+	assert.Equal(t, http.StatusOK, call("scheduler").Code)
+
+	exceeded := call("scheduler")
+	assert.Equal(t, http.StatusTooManyRequests, exceeded.Code)
+	assert.Equal(t, "0", exceeded.Header().Get("X-Quota-Remaining"))
+	assert.NotEmpty(t, exceeded.Header().Get("Retry-After"))
+	assert.Equal(t, 2, runs)
+
+	assert.Equal(t, http.StatusOK, call("other").Code)
+}
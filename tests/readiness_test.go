@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/health"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type readyResponse struct {
+	Status string                        `json:"status"`
+	Checks map[string]health.CheckResult `json:"checks"`
+}
+
+func TestReadinessChecksDependenciesAndFreshness(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	var adsCalls, crmCalls int32
+	adsStub := newSourceStub(adsPayload, &adsCalls)
+	defer adsStub.Close()
+	crmStub := newSourceStub(crmPayload, &crmCalls)
+	defer crmStub.Close()
+
+	store := storage.NewMemoryStorage()
+	extractor := etl.NewExtractor(http.DefaultClient, adsStub.URL, crmStub.URL, logger)
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), store, logger)
+
+	readiness := health.NewReadiness(time.Second)
+	readiness.Add("storage", health.StorageCheck(store))
+	for source, sourceURL := range extractor.SourceURLs() {
+		readiness.Add("source_"+source, health.HTTPProbe(http.DefaultClient, sourceURL, time.Minute))
+	}
+	readiness.Add("ingestion", health.FreshnessCheck(pipeline.LastSuccess, time.Hour))
+
+	handler := api.NewHandler(pipeline, store, logger)
+	handler.SetReadiness(readiness)
+
+	ready := func() (int, readyResponse) {
+		recorder := httptest.NewRecorder()
+		handler.ReadyHandler(recorder, httptest.NewRequest("GET", "/readyz", nil))
+		var body readyResponse
+		json.NewDecoder(recorder.Body).Decode(&body)
+		return recorder.Code, body
+	}
+
+	// Sin ninguna ingesta todavía no está listo
+	code, body := ready()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not_ready", body.Status)
+	assert.Equal(t, health.StatusPass, body.Checks["storage"].Status)
+	assert.Equal(t, health.StatusPass, body.Checks["source_ads"].Status)
+	assert.Equal(t, health.StatusFail, body.Checks["ingestion"].Status)
+
+	_, err := pipeline.Run(context.Background(), time.Time{})
+	assert.NoError(t, err)
+
+	code, body = ready()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", body.Status)
+	assert.Equal(t, health.StatusPass, body.Checks["ingestion"].Status)
+
+	// Los sondeos a las fuentes se cachean: una petición de sondeo y otra de ingesta
+	assert.Equal(t, int32(2), adsCalls)
+}
+
+func TestReadinessFailsOnUnreachableSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	readiness := health.NewReadiness(time.Second)
+	readiness.Add("source_ads", health.HTTPProbe(http.DefaultClient, url, time.Minute))
+
+	report := readiness.Run(context.Background())
+	assert.False(t, report.Ready)
+	assert.Contains(t, report.Checks["source_ads"].Error, "unreachable")
+}
+
+func TestFreshnessCheckDetectsStaleData(t *testing.T) {
+	check := health.FreshnessCheck(func() time.Time { return time.Now().Add(-2 * time.Hour) }, time.Hour)
+	_, err := check(context.Background())
+	assert.ErrorContains(t, err, "stale")
+
+	check = health.FreshnessCheck(func() time.Time { return time.Now().Add(-2 * time.Hour) }, 0)
+	_, err = check(context.Background())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/retention"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// dailyMetrics genera, en orden de fecha, una métrica por día y canal entre from y to.
+func dailyMetrics(tenantID, from, to string) []models.Metric {
+	start, _ := time.Parse("2006-01-02", from)
+	end, _ := time.Parse("2006-01-02", to)
+
+	var metrics []models.Metric
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		for _, channel := range []string{"google", "facebook"} {
+			metric := models.Metric{
+				TenantID:    tenantID,
+				Date:        day.Format("2006-01-02"),
+				Channel:     channel,
+				CampaignID:  channel + "-1",
+				UtmCampaign: "spring",
+				Clicks:      10,
+				Impressions: 100,
+				Cost:        2.5,
+				Leads:       2,
+				Revenue:     20,
+			}
+			metric.ComputeDerived()
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+func TestRetentionRollsUpOldMetricsTransparently(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	assert.NoError(t, store.SaveMetrics(dailyMetrics("acme", "2024-01-01", "2024-03-31")))
+	assert.NoError(t, store.SaveMetrics(dailyMetrics("globex", "2024-03-01", "2024-03-31")))
+
+	query := func(granularity string) []models.Metric {
+		metrics, err := store.GetMetricsByChannel(models.MetricsRequest{
+			TenantID: "acme", From: "2024-01-10", Channel: "google", Granularity: granularity, Limit: 1000,
+		})
+		assert.NoError(t, err)
+		return metrics
+	}
+	weekly, monthly := query(storage.GranularityWeek), query(storage.GranularityMonth)
+	grouped, err := store.GetMetricsGrouped(models.MetricsRequest{TenantID: "acme", GroupBy: "channel", Granularity: storage.GranularityMonth})
+	assert.NoError(t, err)
+
+	// El rango se amplía a meses completos: enero entero aunque from sea el día 10
+	assert.Len(t, monthly, 3)
+	assert.Equal(t, "2024-01-01", monthly[0].Date)
+	assert.Equal(t, 31*10, monthly[0].Clicks)
+	assert.InDelta(t, 0.25, monthly[0].CPC, 0.0001)
+	// Las semanas empiezan en lunes: 2024-01-08 es la semana que contiene el 10
+	assert.Equal(t, "2024-01-08", weekly[0].Date)
+	assert.Equal(t, 70, weekly[0].Clicks)
+
+	manager := retention.NewManager(store, 30, []string{storage.GranularityWeek, storage.GranularityMonth}, quietLogger())
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, "2024-03-01", manager.Cutoff(now))
+
+	removed, err := manager.Apply(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 60*2, removed)
+
+	// El detalle diario anterior al corte ya no existe
+	daily, err := store.GetMetricsByChannel(models.MetricsRequest{TenantID: "acme", To: "2024-02-29", Limit: 1000})
+	assert.NoError(t, err)
+	assert.Empty(t, daily)
+	daily, err = store.GetMetricsByChannel(models.MetricsRequest{TenantID: "globex", Limit: 1000})
+	assert.NoError(t, err)
+	assert.Len(t, daily, 31*2)
+
+	// Los agregados devuelven lo mismo que antes de borrar
+	assert.Equal(t, weekly, query(storage.GranularityWeek))
+	assert.Equal(t, monthly, query(storage.GranularityMonth))
+	after, err := store.GetMetricsGrouped(models.MetricsRequest{TenantID: "acme", GroupBy: "channel", Granularity: storage.GranularityMonth})
+	assert.NoError(t, err)
+	assert.Equal(t, grouped, after)
+
+	// Aplicarla otra vez no cambia nada
+	removed, err = manager.Apply(now)
+	assert.NoError(t, err)
+	assert.Zero(t, removed)
+	assert.Equal(t, monthly, query(storage.GranularityMonth))
+
+	_, err = store.Compact("2024-03-01", []string{"year"})
+	assert.Error(t, err)
+}
+
+func TestMetricsEndpointsAcceptGranularity(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	assert.NoError(t, store.SaveMetrics(dailyMetrics("default", "2024-01-01", "2024-02-15")))
+	_, err := store.Compact("2024-02-01", []string{storage.GranularityMonth})
+	assert.NoError(t, err)
+
+	handler := api.NewHandler(nil, store, quietLogger())
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/metrics/channel", handler.MetricsChannelHandler).Methods("GET")
+	router.HandleFunc("/v1/metrics/funnel", handler.MetricsFunnelHandler).Methods("GET")
+	router.HandleFunc("/v1/metrics/campaigns", handler.MetricsCampaignsHandler).Methods("GET")
+
+	recorder := serve(router, "GET", "/v1/metrics/channel?granularity=month&channel=facebook", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var metrics []models.Metric
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&metrics))
+	if assert.Len(t, metrics, 2) {
+		assert.Equal(t, "2024-01-01", metrics[0].Date)
+		assert.Equal(t, 310, metrics[0].Clicks)
+		assert.Equal(t, "2024-02-01", metrics[1].Date)
+		assert.Equal(t, 150, metrics[1].Clicks)
+	}
+
+	// Sin granularidad sólo queda el detalle diario posterior al corte
+	recorder = serve(router, "GET", "/v1/metrics/funnel?utm_campaign=spring&limit=1000", "")
+	metrics = nil
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&metrics))
+	assert.Len(t, metrics, 15*2)
+
+	recorder = serve(router, "GET", "/v1/metrics/campaigns?group_by=channel&granularity=month", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var groups []models.MetricGroup
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&groups))
+	if assert.Len(t, groups, 2) {
+		assert.Equal(t, 460, groups[0].Clicks)
+	}
+
+	for _, target := range []string{
+		"/v1/metrics/channel?granularity=year",
+		"/v1/metrics/funnel?granularity=hour",
+		"/v1/metrics/campaigns?granularity=quarter",
+		"/v1/metrics/campaigns?group_by=color",
+	} {
+		assert.Equal(t, http.StatusBadRequest, serve(router, "GET", target, "").Code, target)
+	}
+
+	// Un fallo del almacenamiento es un 500 que no expone el error interno
+	dir := t.TempDir()
+	broken, err := storage.NewFileStorage(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "metrics.json"), []byte("{"), 0o644))
+	router = mux.NewRouter()
+	router.HandleFunc("/v1/metrics/campaigns", api.NewHandler(nil, broken, quietLogger()).MetricsCampaignsHandler).Methods("GET")
+
+	recorder = serve(router, "GET", "/v1/metrics/campaigns?group_by=channel", "")
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "snapshot")
+}
+
+func TestRetentionManagerRunsInBackground(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	assert.NoError(t, store.SaveMetrics(dailyMetrics("acme", "2020-01-01", "2020-01-31")))
+
+	manager := retention.NewManager(store, 90, []string{storage.GranularityMonth}, quietLogger())
+	manager.Start(time.Hour)
+	defer manager.Close()
+
+	// La primera pasada se hace al arrancar
+	assert.Eventually(t, func() bool {
+		daily, _ := store.GetMetricsInRange("", "")
+		return len(daily) == 0
+	}, 2*time.Second, 5*time.Millisecond)
+
+	monthly, err := store.GetMetricsByChannel(models.MetricsRequest{TenantID: "acme", Granularity: storage.GranularityMonth})
+	assert.NoError(t, err)
+	assert.Len(t, monthly, 2)
+}
+
+func TestReplaceRunKeepsFirstTimeMetricsBeforeCompaction(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	assert.NoError(t, store.SaveMetrics(dailyMetrics("acme", "2024-06-01", "2024-06-30")))
+	_, err := store.Compact("2024-07-01", []string{storage.GranularityWeek, storage.GranularityMonth})
+	assert.NoError(t, err)
+
+	// Un backfill anterior a la retención guarda sus métricas aunque el corte ya haya pasado
+	old := dailyMetrics("acme", "2023-01-05", "2023-01-05")
+	for i := range old {
+		old[i].RunID = "backfill-1"
+	}
+	_, err = store.ReplaceRun("acme", "backfill-1", old)
+	assert.NoError(t, err)
+
+	for _, granularity := range []string{storage.GranularityDay, storage.GranularityWeek, storage.GranularityMonth} {
+		metrics, err := store.GetMetricsByChannel(models.MetricsRequest{
+			TenantID: "acme", To: "2023-12-31", Channel: "google", Granularity: granularity,
+		})
+		assert.NoError(t, err)
+		if assert.Len(t, metrics, 1, granularity) {
+			assert.Equal(t, 10, metrics[0].Clicks)
+		}
+	}
+
+	// Tras el siguiente Compact pasa a los agregados y repetir el run no lo duplica
+	_, err = store.Compact("2024-07-01", []string{storage.GranularityWeek, storage.GranularityMonth})
+	assert.NoError(t, err)
+	_, err = store.ReplaceRun("acme", "backfill-1", old)
+	assert.NoError(t, err)
+
+	daily, err := store.GetMetricsInRange("2023-01-01", "2023-12-31")
+	assert.NoError(t, err)
+	assert.Empty(t, daily)
+	monthly, err := store.GetMetricsByChannel(models.MetricsRequest{
+		TenantID: "acme", To: "2023-12-31", Channel: "google", Granularity: storage.GranularityMonth,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, monthly, 1) {
+		assert.Equal(t, 10, monthly[0].Clicks)
+	}
+}
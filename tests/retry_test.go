@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRetryClient(attempts int, backoff time.Duration) *utils.RetryableHTTPClient {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	policy := utils.DefaultRetryPolicy(attempts, int(backoff/time.Millisecond))
+	return utils.NewRetryableHTTPClientWithPolicy(logger, http.DefaultClient, policy)
+}
+
+func TestRetryClassifiesStatusCodes(t *testing.T) {
+	cases := []struct {
+		status int
+		calls  int32
+	}{
+		{http.StatusTooManyRequests, 3},
+		{http.StatusRequestTimeout, 3},
+		{http.StatusServiceUnavailable, 3},
+		{http.StatusBadRequest, 1},
+		{http.StatusNotImplemented, 1},
+	}
+
+	for _, tc := range cases {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(tc.status)
+		}))
+
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := newTestRetryClient(3, time.Millisecond).Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		assert.Equal(t, tc.calls, calls, "status %d", tc.status)
+		server.Close()
+	}
+}
+
+func TestRetryHonorsRetryAfterAndRewindsBody(t *testing.T) {
+	var calls int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL, bytes.NewReader([]byte(`{"a":1}`)))
+
+	start := time.Now()
+	resp, err := newTestRetryClient(3, time.Millisecond).Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Equal(t, []string{`{"a":1}`, `{"a":1}`}, bodies)
+}
+
+func TestRetryAbortsOnContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+
+	start := time.Now()
+	_, err := newTestRetryClient(5, 10*time.Second).Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestRetryOnConnectionReset(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := newTestRetryClient(3, time.Millisecond).Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestRetryStopsAtMaxElapsedTime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	policy := utils.DefaultRetryPolicy(5, 1)
+	policy.MaxElapsedTime = time.Second
+	client := utils.NewRetryableHTTPClientWithPolicy(logger, http.DefaultClient, policy)
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	start := time.Now()
+	_, err := client.Do(req)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetryBackoffWithFullJitter(t *testing.T) {
+	policy := utils.DefaultRetryPolicy(5, 100)
+	policy.MaxBackoff = 250 * time.Millisecond
+
+	for attempt := 0; attempt < 5; attempt++ {
+		backoff := policy.Backoff(attempt)
+		assert.GreaterOrEqual(t, backoff, time.Duration(0))
+		assert.LessOrEqual(t, backoff, 250*time.Millisecond)
+	}
+
+	policy.FullJitter = false
+	assert.Equal(t, 200*time.Millisecond, policy.Backoff(1))
+	assert.Equal(t, 250*time.Millisecond, policy.Backoff(4))
+}
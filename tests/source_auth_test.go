@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceQueryTemplate(t *testing.T) {
+	source := etl.SourceConfig{
+		URL:       "http://ads.local/report?format=json",
+		AccountID: "acc-42",
+		Query: map[string]string{
+			"start_date": "{{from}}",
+			"end_date":   "{{to}}",
+			"account":    "act_{{account_id}}",
+		},
+	}
+
+	from, _ := time.Parse("2006-01-02", "2023-01-01")
+	assert.Equal(t,
+		"http://ads.local/report?account=act_acc-42&format=json&start_date=2023-01-01",
+		source.RequestURL(etl.DateRange{From: from}))
+}
+
+func TestSourceAuthenticators(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var tokensIssued int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		r.ParseForm()
+		if user != "client" || pass != "s3cret" || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(&tokensIssued, 1)
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":3600}`, n)
+	}))
+	defer tokenServer.Close()
+
+	cases := []struct {
+		name  string
+		auth  utils.Authenticator
+		check func(r *http.Request) bool
+	}{
+		{"bearer", utils.BearerAuth{Token: "abc"}, func(r *http.Request) bool {
+			return r.Header.Get("Authorization") == "Bearer abc"
+		}},
+		{"api_key", utils.APIKeyAuth{Header: "X-Ads-Key", Key: "k1"}, func(r *http.Request) bool {
+			return r.Header.Get("X-Ads-Key") == "k1"
+		}},
+		{"basic", utils.BasicAuth{Username: "u", Password: "p"}, func(r *http.Request) bool {
+			user, pass, ok := r.BasicAuth()
+			return ok && user == "u" && pass == "p"
+		}},
+		{"oauth2", utils.NewOAuth2ClientCredentials(http.DefaultClient, tokenServer.URL, "client", "s3cret", nil), func(r *http.Request) bool {
+			// El primer token se rechaza para forzar la renovación
+			return r.Header.Get("Authorization") == "Bearer token-2"
+		}},
+	}
+
+	for _, tc := range cases {
+		source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !tc.check(r) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(adsPayload))
+		}))
+
+		extractor := etl.NewExtractorWithSources(http.DefaultClient,
+			etl.SourceConfig{URL: source.URL, Auth: tc.auth},
+			etl.SourceConfig{URL: source.URL}, logger)
+
+		data, err := extractor.ExtractAdsData(context.Background())
+		assert.NoError(t, err, tc.name)
+		if assert.NotNil(t, data, tc.name) {
+			assert.Len(t, data.External.Ads.Performance, 1, tc.name)
+		}
+		source.Close()
+	}
+
+	assert.Equal(t, int32(2), tokensIssued)
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// sampleMetrics genera métricas repartidas entre días, canales y campañas, sin ordenar por fecha.
+func sampleMetrics(tenantID string, count int) []models.Metric {
+	channels := []string{"google", "facebook", "tiktok"}
+	campaigns := []string{"spring", "summer", "black_friday", ""}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	metrics := make([]models.Metric, 0, count)
+	for i := 0; i < count; i++ {
+		metrics = append(metrics, models.Metric{
+			TenantID:    tenantID,
+			Date:        base.AddDate(0, 0, (i*7)%60).Format("2006-01-02"),
+			Channel:     channels[i%len(channels)],
+			CampaignID:  fmt.Sprintf("c%d", i),
+			UtmCampaign: campaigns[i%len(campaigns)],
+			Owner:       []string{"ana", "luis"}[i%2],
+			Clicks:      i,
+		})
+	}
+	return metrics
+}
+
+// matches es el filtro sin índices con el que se comparan las consultas.
+func matches(metric models.Metric, from, to, channel, utmCampaign, owner string) bool {
+	if _, err := time.Parse("2006-01-02", metric.Date); err != nil {
+		return false
+	}
+	return (from == "" || metric.Date >= from) && (to == "" || metric.Date <= to) &&
+		(channel == "" || metric.Channel == channel) &&
+		(utmCampaign == "" || metric.UtmCampaign == utmCampaign) &&
+		(owner == "" || metric.Owner == owner)
+}
+
+func TestMemoryStorageIndexedQueriesMatchFullScan(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	metrics := sampleMetrics("acme", 600)
+	// Una fecha inválida nunca aparece en las consultas
+	metrics = append(metrics, models.Metric{TenantID: "acme", Date: "not-a-date", Channel: "google"})
+	assert.NoError(t, store.SaveMetrics(metrics))
+	assert.NoError(t, store.SaveMetrics(sampleMetrics("globex", 50)))
+
+	cases := []struct{ from, to, channel, utm, owner string }{
+		{},
+		{from: "2024-01-15"},
+		{to: "2024-01-10"},
+		{from: "2024-01-10", to: "2024-01-20", channel: "google"},
+		{from: "2024-02-01", to: "2024-02-01"},
+		{channel: "tiktok", owner: "luis"},
+		{utm: "summer", from: "2024-01-05"},
+		{from: "2024-02-10", to: "2024-01-10"},
+		{from: "yesterday", channel: "facebook"},
+		{channel: "unknown"},
+	}
+
+	for _, c := range cases {
+		name := fmt.Sprintf("%+v", c)
+		from := c.from
+		if from == "yesterday" {
+			// Un límite que no es una fecha se ignora
+			from = ""
+		}
+
+		var want, wantFunnel []models.Metric
+		for _, metric := range metrics {
+			if matches(metric, from, c.to, c.channel, "", c.owner) {
+				want = append(want, metric)
+			}
+			if matches(metric, from, c.to, "", c.utm, c.owner) {
+				wantFunnel = append(wantFunnel, metric)
+			}
+		}
+
+		request := models.MetricsRequest{
+			TenantID: "acme", From: c.from, To: c.to, Channel: c.channel, UtmCampaign: c.utm, Owner: c.owner, Limit: 1000,
+		}
+		got, err := store.GetMetricsByChannel(request)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, name)
+
+		got, err = store.GetMetricsByFunnel(request)
+		assert.NoError(t, err)
+		assert.Equal(t, wantFunnel, got, name)
+	}
+
+	all, err := store.GetMetricsInRange("2024-01-01", "2024-01-08")
+	assert.NoError(t, err)
+	tenants := map[string]int{}
+	for _, metric := range all {
+		assert.True(t, metric.Date <= "2024-01-08")
+		tenants[metric.TenantID]++
+	}
+	assert.NotZero(t, tenants["acme"])
+	assert.NotZero(t, tenants["globex"])
+	// Los tenants salen en orden alfabético
+	assert.Equal(t, "acme", all[0].TenantID)
+	assert.Equal(t, "globex", all[len(all)-1].TenantID)
+
+	page, err := store.GetMetricsByChannel(models.MetricsRequest{TenantID: "acme", Channel: "google", Limit: 5, Offset: 10})
+	assert.NoError(t, err)
+	assert.Len(t, page, 5)
+	assert.Equal(t, "c30", page[0].CampaignID)
+}
+
+func TestMemoryStorageConcurrentReadsAndWrites(t *testing.T) {
+	store := storage.NewMemoryStorage()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				store.SaveMetrics(sampleMetrics(fmt.Sprintf("tenant-%d", w%2), 10))
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				store.GetMetricsByChannel(models.MetricsRequest{TenantID: "tenant-0", From: "2024-01-10", Channel: "google"})
+				store.GetMetricsGrouped(models.MetricsRequest{GroupBy: "channel"})
+				store.GetMetricsInRange("2024-01-01", "2024-02-01")
+			}
+		}()
+	}
+	wg.Wait()
+
+	all, err := store.GetMetricsInRange("", "")
+	assert.NoError(t, err)
+	assert.Len(t, all, 4*50*10)
+}
+
+func TestFileStorageSharedBetweenProcesses(t *testing.T) {
+	dir := t.TempDir()
+	api, err := storage.NewFileStorage(dir)
+	assert.NoError(t, err)
+	worker, err := storage.NewFileStorage(dir)
+	assert.NoError(t, err)
+
+	// Lo que guarda el worker aparece en las lecturas de la API
+	assert.NoError(t, worker.SaveMetrics(dailyMetrics("acme", "2024-01-01", "2024-01-31")))
+	metrics, err := api.GetMetricsByChannel(models.MetricsRequest{TenantID: "acme", Channel: "google", Limit: 1000})
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 31)
+
+	// Un replay sustituye las filas de su run en vez de duplicarlas
+	run := dailyMetrics("acme", "2024-02-01", "2024-02-03")
+	for i := range run {
+		run[i].RunID = "run-1"
+	}
+	_, err = api.ReplaceRun("acme", "run-1", run)
+	assert.NoError(t, err)
+	replaced, err := worker.ReplaceRun("acme", "run-1", run[:2])
+	assert.NoError(t, err)
+	assert.Len(t, replaced, len(run))
+
+	// Compactar en un proceso se ve en el otro y en una instancia nueva tras reiniciar
+	_, err = worker.Compact("2024-02-01", []string{storage.GranularityMonth})
+	assert.NoError(t, err)
+	restarted, err := storage.NewFileStorage(dir)
+	assert.NoError(t, err)
+	for _, store := range []*storage.FileStorage{api, restarted} {
+		daily, err := store.GetMetricsInRange("2024-01-01", "2024-02-28")
+		assert.NoError(t, err)
+		assert.Len(t, daily, 2)
+
+		monthly, err := store.GetMetricsByChannel(models.MetricsRequest{
+			TenantID: "acme", Channel: "google", Granularity: storage.GranularityMonth, Limit: 1000,
+		})
+		assert.NoError(t, err)
+		if assert.Len(t, monthly, 2) {
+			assert.Equal(t, 310, monthly[0].Clicks)
+		}
+	}
+}
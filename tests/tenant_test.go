@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/auth"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/landing"
+	"github.com/admira-project/backend/internal/models"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	var calls int32
+	defaultAds := newSourceStub(adsPayload, &calls)
+	defer defaultAds.Close()
+	defaultCrm := newSourceStub(crmPayload, &calls)
+	defer defaultCrm.Close()
+	globexAds := newSourceStub(strings.ReplaceAll(adsPayload, "google_ads", "meta_ads"), &calls)
+	defer globexAds.Close()
+
+	extractor := etl.NewExtractor(http.DefaultClient, defaultAds.URL, defaultCrm.URL, logger)
+	// Sin URL de CRM propia, globex comparte la de por defecto
+	extractor.AddTenant("globex", etl.SourceConfig{URL: globexAds.URL}, etl.SourceConfig{})
+	assert.Equal(t, []string{tenant.Default, "globex"}, extractor.Tenants())
+
+	landingStore, err := landing.NewStore(t.TempDir())
+	assert.NoError(t, err)
+	store := storage.NewMemoryStorage()
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), store, logger)
+	pipeline.SetLanding(landingStore)
+
+	defaultRun, err := pipeline.Run(context.Background(), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, tenant.Default, defaultRun.Tenant)
+
+	globexRun, err := pipeline.Run(tenant.WithID(context.Background(), "globex"), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "globex", globexRun.Metrics[0].TenantID)
+	assert.Equal(t, "meta_ads", globexRun.Metrics[0].Channel)
+
+	_, err = pipeline.Run(tenant.WithID(context.Background(), "initech"), time.Time{})
+	assert.ErrorIs(t, err, etl.ErrUnknownTenant)
+
+	authn := auth.NewAuthenticator()
+	authn.AddTenantAPIKey("globex-key", "globex-dashboard", "globex", []string{auth.RoleOperator})
+	authn.AddTenantAPIKey("platform-key", "platform", auth.AllTenants, []string{auth.RoleOperator})
+	authn.AddAPIKey("default-key", "dashboard", []string{auth.RoleViewer})
+
+	handler := api.NewHandler(pipeline, store, logger)
+	router := mux.NewRouter()
+	router.Handle("/metrics/channel", authn.Require(auth.RoleViewer, handler.MetricsChannelHandler))
+	router.Handle("/ingest/runs", authn.Require(auth.RoleViewer, handler.ListRunsHandler))
+	router.Handle("/ingest/runs/{id}/replay", authn.Require(auth.RoleOperator, handler.ReplayHandler))
+
+	call := func(method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-API-Key", key)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	var metrics []models.Metric
+	recorder := call("GET", "/metrics/channel", "globex-key")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	json.NewDecoder(recorder.Body).Decode(&metrics)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "meta_ads", metrics[0].Channel)
+
+	// Una credencial de tenant no puede pedir datos de otro
+	assert.Equal(t, http.StatusForbidden, call("GET", "/metrics/channel?tenant=default", "globex-key").Code)
+
+	recorder = call("GET", "/metrics/channel?tenant=default", "platform-key")
+	json.NewDecoder(recorder.Body).Decode(&metrics)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "google_ads", metrics[0].Channel)
+
+	recorder = call("GET", "/metrics/channel?tenant=globex", "platform-key")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	json.NewDecoder(recorder.Body).Decode(&metrics)
+	assert.Equal(t, "meta_ads", metrics[0].Channel)
+
+	// Una clave sin tenant explícito sólo ve el tenant por defecto
+	assert.Equal(t, http.StatusOK, call("GET", "/metrics/channel", "default-key").Code)
+	assert.Equal(t, http.StatusForbidden, call("GET", "/metrics/channel?tenant=globex", "default-key").Code)
+
+	var runs []landing.Manifest
+	json.NewDecoder(call("GET", "/ingest/runs", "globex-key").Body).Decode(&runs)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, globexRun.RunID, runs[0].RunID)
+
+	assert.Equal(t, http.StatusNotFound, call("POST", "/ingest/runs/"+defaultRun.RunID+"/replay", "globex-key").Code)
+	assert.Equal(t, http.StatusOK, call("POST", "/ingest/runs/"+globexRun.RunID+"/replay", "globex-key").Code)
+
+	// El replay sustituye las métricas de la ejecución original en lugar de duplicarlas
+	all, err := store.GetMetricsInRange("", "")
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/storage"
+	"github.com/admira-project/backend/internal/tracing"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRequestIDAndTracePropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(previous)
+
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetOutput(&logs)
+
+	var mu sync.Mutex
+	outbound := make(map[string]http.Header)
+	var adsCalls int32
+	adsStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		outbound[etl.SourceAds] = r.Header.Clone()
+		mu.Unlock()
+		// El primer intento falla para que quede un evento de reintento
+		if atomic.AddInt32(&adsCalls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(adsPayload))
+	}))
+	defer adsStub.Close()
+	crmStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		outbound[etl.SourceCrm] = r.Header.Clone()
+		mu.Unlock()
+		w.Write([]byte(crmPayload))
+	}))
+	defer crmStub.Close()
+
+	client := utils.NewRetryableHTTPClientWithPolicy(logger, http.DefaultClient, utils.DefaultRetryPolicy(3, 1))
+	extractor := etl.NewExtractor(client, adsStub.URL, crmStub.URL, logger)
+	store := storage.NewMemoryStorage()
+	pipeline := etl.NewPipeline(extractor, etl.NewTransformer(logger), store, logger)
+	handler := api.NewHandler(pipeline, store, logger)
+
+	router := mux.NewRouter()
+	router.Use(api.RequestIDMiddleware, api.TracingMiddleware)
+	router.HandleFunc("/ingest/run", handler.IngestHandler).Methods("POST")
+
+	req := httptest.NewRequest("POST", "/ingest/run", nil)
+	req.Header.Set(tracing.RequestIDHeader, "req-123")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "req-123", recorder.Header().Get(tracing.RequestIDHeader))
+	for _, source := range []string{etl.SourceAds, etl.SourceCrm} {
+		assert.Equal(t, "req-123", outbound[source].Get(tracing.RequestIDHeader))
+		assert.NotEmpty(t, outbound[source].Get("traceparent"))
+	}
+	assert.Contains(t, logs.String(), `"request_id":"req-123"`)
+	assert.Contains(t, logs.String(), `"trace_id"`)
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	for _, name := range []string{"POST /ingest/run", "pipeline run", "extract ads", "extract crm", "transform", "save"} {
+		assert.Contains(t, spans, name)
+	}
+
+	root := spans["POST /ingest/run"]
+	assert.Equal(t, root.SpanContext.TraceID(), spans["transform"].SpanContext.TraceID())
+	assert.Len(t, spans["extract ads"].Events, 1)
+	assert.Equal(t, "retry", spans["extract ads"].Events[0].Name)
+}
+
+func TestRequestIDGeneratedWhenMissing(t *testing.T) {
+	handler := api.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, w.Header().Get(tracing.RequestIDHeader), tracing.RequestID(r.Context()))
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Len(t, recorder.Header().Get(tracing.RequestIDHeader), 32)
+}
@@ -0,0 +1,252 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/admira-project/backend/internal/api"
+	"github.com/admira-project/backend/internal/etl"
+	"github.com/admira-project/backend/internal/tenant"
+	"github.com/admira-project/backend/internal/utils"
+	"github.com/admira-project/backend/internal/webhooks"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	statuses []int
+	calls    int32
+}
+
+// newWebhookReceiver responde con statuses en orden y después con 200.
+func newWebhookReceiver(statuses ...int) *webhookReceiver {
+	receiver := &webhookReceiver{statuses: statuses}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		call := int(atomic.AddInt32(&receiver.calls, 1))
+
+		receiver.mu.Lock()
+		receiver.bodies = append(receiver.bodies, body)
+		receiver.headers = append(receiver.headers, r.Header.Clone())
+		status := http.StatusOK
+		if call <= len(receiver.statuses) {
+			status = receiver.statuses[call-1]
+		}
+		receiver.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	return receiver
+}
+
+func newTestDispatcher(t *testing.T, stateDir string, maxAttempts int) *webhooks.Dispatcher {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	policy := utils.DefaultRetryPolicy(maxAttempts, 1)
+	policy.FullJitter = false
+	dispatcher, err := webhooks.NewDispatcher(http.DefaultClient, policy, stateDir, 100, logger)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(dispatcher.Close)
+	return dispatcher
+}
+
+func waitForDelivery(t *testing.T, dispatcher *webhooks.Dispatcher, tenantID, subscriptionID, status string) webhooks.Delivery {
+	var found webhooks.Delivery
+	assert.Eventually(t, func() bool {
+		deliveries, _ := dispatcher.Deliveries(tenantID, subscriptionID, status)
+		if len(deliveries) == 0 {
+			return false
+		}
+		found = deliveries[0]
+		return true
+	}, 2*time.Second, 5*time.Millisecond)
+	return found
+}
+
+func TestWebhookSignedDeliveryWithRetries(t *testing.T) {
+	receiver := newWebhookReceiver(http.StatusServiceUnavailable, http.StatusBadGateway)
+	defer receiver.Close()
+
+	dispatcher := newTestDispatcher(t, "", 5)
+	subscription, err := dispatcher.Create(tenant.Default, webhooks.Subscription{URL: receiver.URL, Secret: "0123456789abcdef", Active: true})
+	assert.NoError(t, err)
+	other, _ := dispatcher.Create("acme", webhooks.Subscription{URL: receiver.URL, Active: true})
+	assert.True(t, strings.HasPrefix(other.Secret, "whsec_"))
+
+	// Una ejecución sin cambios no avisa
+	dispatcher.RunFinished(context.Background(), &etl.RunResult{RunID: "r0", Tenant: tenant.Default, Unchanged: true})
+	dispatcher.RunFinished(context.Background(), &etl.RunResult{RunID: "r1", Tenant: tenant.Default, Count: 42, Since: "2024-01-01", Until: "2024-01-31"})
+
+	delivery := waitForDelivery(t, dispatcher, tenant.Default, subscription.ID, webhooks.StatusDelivered)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, http.StatusOK, delivery.ResponseStatus)
+	assert.NotNil(t, delivery.DeliveredAt)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&receiver.calls))
+
+	receiver.mu.Lock()
+	body, headers := receiver.bodies[2], receiver.headers[2]
+	receiver.mu.Unlock()
+
+	assert.True(t, webhooks.Verify("0123456789abcdef", headers.Get(webhooks.SignatureHeader), body, time.Minute))
+	assert.False(t, webhooks.Verify("wrong-secret-value", headers.Get(webhooks.SignatureHeader), body, time.Minute))
+	assert.Equal(t, delivery.ID, headers.Get(webhooks.DeliveryHeader))
+	assert.Equal(t, webhooks.EventIngestionCompleted, headers.Get(webhooks.EventHeader))
+
+	var payload webhooks.Payload
+	assert.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, "r1", payload.RunID)
+	assert.Equal(t, 42, payload.Count)
+	assert.Equal(t, "2024-01-01", payload.Since)
+	assert.Equal(t, "2024-01-31", payload.Until)
+
+	deliveries, _ := dispatcher.Deliveries("acme", other.ID, "")
+	assert.Empty(t, deliveries)
+}
+
+func TestWebhookFailedDeliveryReplay(t *testing.T) {
+	// 400 es definitivo; 503 se reintenta hasta agotar los intentos
+	receiver := newWebhookReceiver(http.StatusBadRequest, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	defer receiver.Close()
+
+	dispatcher := newTestDispatcher(t, "", 2)
+	subscription, _ := dispatcher.Create(tenant.Default, webhooks.Subscription{URL: receiver.URL, Active: true})
+
+	dispatcher.RunFinished(context.Background(), &etl.RunResult{RunID: "r1", Tenant: tenant.Default, Count: 1})
+	failed := waitForDelivery(t, dispatcher, tenant.Default, subscription.ID, webhooks.StatusFailed)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Equal(t, http.StatusBadRequest, failed.ResponseStatus)
+
+	_, err := dispatcher.Replay("acme", failed.ID)
+	assert.ErrorIs(t, err, webhooks.ErrDeliveryNotFound)
+
+	_, err = dispatcher.Replay(tenant.Default, failed.ID)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		delivery, _ := dispatcher.GetDelivery(tenant.Default, failed.ID)
+		return delivery.Status == webhooks.StatusFailed && delivery.Attempts == 2
+	}, 2*time.Second, 5*time.Millisecond)
+
+	replayed, err := dispatcher.ReplayFailed(tenant.Default, subscription.ID)
+	assert.NoError(t, err)
+	assert.Len(t, replayed, 1)
+	delivered := waitForDelivery(t, dispatcher, tenant.Default, subscription.ID, webhooks.StatusDelivered)
+	assert.Equal(t, failed.ID, delivered.ID)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&receiver.calls))
+}
+
+func TestWebhookStatePersistence(t *testing.T) {
+	stateDir := t.TempDir()
+
+	dispatcher := newTestDispatcher(t, stateDir, 1)
+	subscription, _ := dispatcher.Create(tenant.Default, webhooks.Subscription{URL: "http://127.0.0.1:1/hook", Secret: "0123456789abcdef", Active: true})
+	dispatcher.RunFinished(context.Background(), &etl.RunResult{RunID: "r1", Tenant: tenant.Default, Count: 1})
+	waitForDelivery(t, dispatcher, tenant.Default, subscription.ID, webhooks.StatusFailed)
+	dispatcher.Close()
+
+	reloaded := newTestDispatcher(t, stateDir, 1)
+	restored, err := reloaded.Get(tenant.Default, subscription.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, subscription.URL, restored.URL)
+	assert.Empty(t, restored.Secret)
+
+	deliveries, _ := reloaded.Deliveries(tenant.Default, subscription.ID, webhooks.StatusFailed)
+	assert.Len(t, deliveries, 1)
+}
+
+func TestWebhookAPI(t *testing.T) {
+	dispatcher := newTestDispatcher(t, "", 1)
+	handler := api.NewWebhookHandler(dispatcher, logrus.New())
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/webhooks", handler.CreateHandler).Methods("POST")
+	router.HandleFunc("/v1/webhooks", handler.ListHandler).Methods("GET")
+	router.HandleFunc("/v1/webhooks/{id}", handler.GetHandler).Methods("GET")
+	router.HandleFunc("/v1/webhooks/{id}", handler.UpdateHandler).Methods("PATCH")
+	router.HandleFunc("/v1/webhooks/{id}", handler.DeleteHandler).Methods("DELETE")
+	router.HandleFunc("/v1/webhooks/{id}/deliveries", handler.DeliveriesHandler).Methods("GET")
+	router.HandleFunc("/v1/webhooks/{id}/deliveries/{delivery}/replay", handler.ReplayHandler).Methods("POST")
+
+	assert.Equal(t, http.StatusBadRequest, serve(router, "POST", "/v1/webhooks", `{"url": "ftp://example.com"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(router, "POST", "/v1/webhooks", `{"url": "https://example.com", "secret": "short"}`).Code)
+
+	recorder := serve(router, "POST", "/v1/webhooks", `{"url": "https://bi.example.com/refresh", "description": "BI"}`)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var created webhooks.Subscription
+	json.NewDecoder(recorder.Body).Decode(&created)
+	assert.NotEmpty(t, created.Secret)
+	assert.True(t, created.Active)
+
+	recorder = serve(router, "GET", "/v1/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), created.Secret)
+
+	// Otro tenant no la ve
+	assert.Equal(t, http.StatusNotFound, serve(router, "GET", "/v1/webhooks/"+created.ID+"?tenant=acme", "").Code)
+	recorder = serve(router, "GET", "/v1/webhooks?tenant=acme", "")
+	assert.JSONEq(t, `[]`, recorder.Body.String())
+
+	recorder = serve(router, "PATCH", "/v1/webhooks/"+created.ID, `{"active": false}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var updated webhooks.Subscription
+	json.NewDecoder(recorder.Body).Decode(&updated)
+	assert.False(t, updated.Active)
+	assert.Empty(t, updated.Secret)
+
+	// Inactiva: no genera entregas
+	dispatcher.RunFinished(context.Background(), &etl.RunResult{RunID: "r1", Tenant: tenant.Default, Count: 1})
+	recorder = serve(router, "GET", "/v1/webhooks/"+created.ID+"/deliveries", "")
+	assert.JSONEq(t, `[]`, recorder.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, serve(router, "POST", "/v1/webhooks/"+created.ID+"/deliveries/whd-missing/replay", "").Code)
+
+	assert.Equal(t, http.StatusNoContent, serve(router, "DELETE", "/v1/webhooks/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(router, "GET", "/v1/webhooks/"+created.ID, "").Code)
+}
+
+func TestWebhookClientBlocksInternalAddresses(t *testing.T) {
+	var hits int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	// Loopback, link-local (metadatos del proveedor cloud) y redes privadas se rechazan al conectar
+	client := webhooks.NewHTTPClient(time.Second, false)
+	for _, url := range []string{target.URL, "http://localhost:" + strings.TrimPrefix(target.URL, "http://127.0.0.1:"), "http://169.254.169.254/latest/meta-data/", "http://10.0.0.1/hook"} {
+		_, err := client.Post(url, "application/json", strings.NewReader("{}"))
+		assert.ErrorIs(t, err, webhooks.ErrForbiddenAddress, url)
+		assert.False(t, utils.DefaultRetryPolicy(3, 1).ShouldRetryError(err), url)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&hits))
+
+	// Las redirecciones no se siguen, ni siquiera con las redes privadas permitidas
+	client = webhooks.NewHTTPClient(time.Second, true)
+	resp, err := client.Post(redirect.URL, "application/json", strings.NewReader("{}"))
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&hits))
+}